@@ -6,7 +6,4 @@ godebug gotypesalias=1
 
 require github.com/gorilla/websocket v1.5.3
 
-require (
-	github.com/lilybw/bsc-multiplayer-backend v0.0.0-20241221193214-218547caac33
-	github.com/joho/godotenv v1.5.1
-)
+require github.com/joho/godotenv v1.5.1
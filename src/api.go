@@ -9,20 +9,26 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/lilybw/bsc-multiplayer-backend/src/auth"
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
 	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
 	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
 	"github.com/lilybw/bsc-multiplayer-backend/src/middleware"
 	"github.com/lilybw/bsc-multiplayer-backend/src/util"
 )
 
-func applyPublicApi(mux *http.ServeMux, lobbyManager *internal.LobbyManager) error {
+func applyPublicApi(mux *http.ServeMux, lobbyManager *internal.LobbyManager, ticketIssuer *auth.TicketIssuer) error {
 	//This one is the one that is upgraded to a websocket connection
 	mux.HandleFunc("/connect", func(w http.ResponseWriter, r *http.Request) {
-		webSocketConnectionRequestHandler(lobbyManager, w, r)
+		webSocketConnectionRequestHandler(lobbyManager, ticketIssuer, w, r)
 	})
 
 	mux.HandleFunc("POST /create-lobby", func(w http.ResponseWriter, r *http.Request) {
-		createLobbyHandler(lobbyManager, w, r)
+		createLobbyHandler(lobbyManager, ticketIssuer, w, r)
+	})
+
+	mux.HandleFunc("POST /lobby/{id}/ticket", func(w http.ResponseWriter, r *http.Request) {
+		mintJoinTicketHandler(lobbyManager, ticketIssuer, w, r)
 	})
 
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
@@ -52,26 +58,54 @@ func gatherLobbyStateHandler(w http.ResponseWriter, r *http.Request, lobbyManage
 		return
 	}
 
-	var clients = make([]ClientResponseDTO, 0, lobby.ClientCount())
+	var clients = make([]ClientResponseDTO, 0, lobby.PlayerCount())
+	var spectators = make([]ClientResponseDTO, 0)
 	lobby.Clients.Range(func(key internal.ClientID, value *internal.Client) bool {
-		clients = append(clients, ClientResponseDTO{
-			ID:   key,
-			IGN:  value.IGN,
-			Type: value.Type,
+		dto := ClientResponseDTO{
+			ID:        key,
+			IGN:       value.IGN,
+			Type:      value.Type(),
+			Connected: value.Connected.Load(),
 			State: ClientStateResponseDTO{
 				LastKnownPosition: value.State.LastKnownPosition.Load(),
 				MSOfLastMessage:   value.State.MSOfLastMessage.Load(),
+				IdleMS:            value.IdleMS(),
+			},
+			SendQueue: ClientSendQueueResponseDTO{
+				Length:                  value.SendQueueLength(),
+				Capacity:                value.SendQueueCapacity(),
+				HighWaterMark:           value.SendMetrics.QueueHighWaterMark.Load(),
+				SentMessages:            value.SendMetrics.SentMessages.Load(),
+				DroppedMessages:         value.SendMetrics.DroppedMessages.Load(),
+				SlowConsumerDisconnects: value.SendMetrics.SlowConsumerDisconnects.Load(),
 			},
-		})
+			SpoofedSenderIDs: value.SpoofedSenderIDs.Load(),
+			RateLimit: ClientRateLimitResponseDTO{
+				ThrottledMessages: value.RateLimiter.Metrics.ThrottledMessages.Load(),
+				RecentOffences:    value.RateLimiter.RecentOffences(),
+			},
+		}
+		if value.IsSpectator() {
+			spectators = append(spectators, dto)
+		} else {
+			clients = append(clients, dto)
+		}
 		return true
 	})
 
 	var response = LobbyStateResponseDTO{
-		ColonyID: lobby.ColonyID,
-		Closing:  lobby.Closing.Load(),
-		Phase:    internal.LobbyPhase(lobby.GetPhase()),
-		Encoding: lobby.Encoding,
-		Clients:  clients,
+		ColonyID:       lobby.ColonyID,
+		OwnerID:        lobby.OwnerID.Load(),
+		Closing:        lobby.Closing.Load(),
+		Phase:          internal.LobbyPhase(lobby.GetPhase()),
+		Encoding:       lobby.Encoding,
+		PingIntervalMS: lobby.PingInterval.Milliseconds(),
+		PongTimeoutMS:  lobby.PongTimeout.Milliseconds(),
+		Clients:        clients,
+		Spectators:     spectators,
+		MaxPlayers:     lobby.MaxPlayers,
+		QueueWhenFull:  lobby.QueueWhenFull,
+		JoinQueue:      lobby.JoinQueue(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -88,9 +122,37 @@ func gatherLobbyStateHandler(w http.ResponseWriter, r *http.Request, lobbyManage
 
 func performHealthCheckHandler(w http.ResponseWriter, r *http.Request, lobbyManager *internal.LobbyManager) {
 	lobbyCount := lobbyManager.GetLobbyCount()
+	metrics := lobbyManager.Metrics
 	response := HealthCheckResponseDTO{
 		Status:     true,
 		LobbyCount: uint32(lobbyCount),
+		Lifecycle: LifecycleMetricsResponseDTO{
+			LobbiesCreated:   metrics.LobbiesCreated.Load(),
+			LobbiesClosed:    metrics.LobbiesClosed.Load(),
+			ClientsJoined:    metrics.ClientsJoined.Load(),
+			ClientsLeft:      metrics.ClientsLeft.Load(),
+			PhaseChanges:     metrics.PhaseChanges.Load(),
+			MinigamesStarted: metrics.MinigamesStarted.Load(),
+			MinigamesWon:     metrics.MinigamesWon.Load(),
+			MinigamesLost:    metrics.MinigamesLost.Load(),
+			MinigamesAborted: metrics.MinigamesAborted.Load(),
+			DroppedEvents:    lobbyManager.Lifecycle.DroppedEvents(),
+		},
+		ProtocolVersion: internal.ProtocolVersion(),
+	}
+	mainBackend := lobbyManager.MainBackend
+	if cache, ok := mainBackend.(*integrations.CachingMainBackend); ok {
+		mainBackend = cache.MainBackend
+	}
+	if integration, ok := mainBackend.(*integrations.MainBackendIntegration); ok {
+		response.MainBackendCircuit = string(integration.CircuitState())
+	}
+	if webhooks := lobbyManager.Webhooks; webhooks != nil {
+		response.Webhooks = &WebhookMetricsResponseDTO{
+			Delivered: webhooks.Metrics.Delivered.Load(),
+			Retried:   webhooks.Metrics.Retried.Load(),
+			Failed:    webhooks.Metrics.Failed.Load(),
+		}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	bytes, err := json.Marshal(response)
@@ -104,10 +166,27 @@ func performHealthCheckHandler(w http.ResponseWriter, r *http.Request, lobbyMana
 	middleware.LogResultOfRequest(w, r, http.StatusOK)
 }
 
-func createLobbyHandler(lobbyManager *internal.LobbyManager, w http.ResponseWriter, r *http.Request) {
+// Requires the ticket issuer api key, as the response holds a join ticket
+func createLobbyHandler(lobbyManager *internal.LobbyManager, ticketIssuer *auth.TicketIssuer, w http.ResponseWriter, r *http.Request) {
+	if !authorizeTicketIssuer(ticketIssuer, w, r) {
+		return
+	}
 	ownerID, ownerIDErr := getAsUint32(r, "ownerID")
 	colonyID, colonyIDErr := getAsUint32(r, "colonyID")
+	IGN := r.URL.Query().Get("IGN")
 	userSetEncodingStr := r.URL.Query().Get("encoding")
+	if IGN == "" {
+		w.Header().Set("Default-Debug-Header", "IGN query param missing")
+		http.Error(w, "IGN not provided", http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
+	if len(IGN) > internal.MAX_IGN_SIZE {
+		w.Header().Set("Default-Debug-Header", fmt.Sprintf("IGN exceeds %d bytes", internal.MAX_IGN_SIZE))
+		http.Error(w, "IGN too long", http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
 	if ownerIDErr != nil {
 		//log.Println("[] Error parsing ownerID: ", ownerIDErr)
 		w.Header().Set("Default-Debug-Header", "Error in ownerID query param: "+ownerIDErr.Error())
@@ -124,6 +203,44 @@ func createLobbyHandler(lobbyManager *internal.LobbyManager, w http.ResponseWrit
 		return
 	}
 
+	// Optional heartbeat overwrites, 0 or absent uses the configured defaults
+	var settings internal.LobbySettings
+	for key, dest := range map[string]*time.Duration{"pingIntervalMS": &settings.PingInterval, "pongTimeoutMS": &settings.PongTimeout} {
+		if r.URL.Query().Get(key) == "" {
+			continue
+		}
+		valueMS, err := getAsUint32(r, key)
+		if err != nil {
+			w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in %s query param: %s", key, err.Error()))
+			http.Error(w, "Error in "+key, http.StatusBadRequest)
+			middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+			return
+		}
+		*dest = time.Duration(valueMS) * time.Millisecond
+	}
+
+	// Optional capacity, 0 or absent for no limit. Includes the seat of the owner
+	if r.URL.Query().Get("maxPlayers") != "" {
+		maxPlayers, err := getAsUint32(r, "maxPlayers")
+		if err != nil {
+			w.Header().Set("Default-Debug-Header", "Error in maxPlayers query param: "+err.Error())
+			http.Error(w, "Error in maxPlayers", http.StatusBadRequest)
+			middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+			return
+		}
+		settings.MaxPlayers = maxPlayers
+	}
+	if r.URL.Query().Get("queueWhenFull") != "" {
+		queueWhenFull, err := strconv.ParseBool(r.URL.Query().Get("queueWhenFull"))
+		if err != nil {
+			w.Header().Set("Default-Debug-Header", "Error in queueWhenFull query param: "+err.Error())
+			http.Error(w, "Error in queueWhenFull", http.StatusBadRequest)
+			middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+			return
+		}
+		settings.QueueWhenFull = queueWhenFull
+	}
+
 	var userSetEncoding meta.MessageEncoding
 	switch userSetEncodingStr {
 	case "base16":
@@ -134,7 +251,7 @@ func createLobbyHandler(lobbyManager *internal.LobbyManager, w http.ResponseWrit
 		userSetEncoding = meta.MESSAGE_ENCODING_BINARY
 	}
 
-	lobby, err := lobbyManager.CreateLobby(uint32(ownerID), uint32(colonyID), userSetEncoding)
+	lobby, created, err := lobbyManager.CreateLobby(uint32(ownerID), uint32(colonyID), userSetEncoding, settings)
 	if err != nil {
 		//log.Println("Error creating lobby: ", err)
 		w.Header().Set("Default-Debug-Header", "Error creating lobby: "+err.Error())
@@ -142,12 +259,141 @@ func createLobbyHandler(lobbyManager *internal.LobbyManager, w http.ResponseWrit
 		middleware.LogResultOfRequest(w, r, http.StatusInternalServerError)
 		return
 	}
+
+	// Owner tickets are only minted for a lobby just created. If the colony already had one, anyone but its owner joins as a guest,
+	// while the owner can only get back in by resuming their session
+	if !created && lobby.OwnerID.Load() == ownerID {
+		w.Header().Set("Default-Debug-Header", "Lobby already exists, the owner can only resume their session")
+		http.Error(w, "Lobby already exists", http.StatusConflict)
+		middleware.LogResultOfRequest(w, r, http.StatusConflict)
+		return
+	}
+	ticket, err := ticketIssuer.Mint(auth.JoinTicket{
+		ClientID:      ownerID,
+		IGN:           IGN,
+		LobbyID:       lobby.ID,
+		ColonyID:      lobby.ColonyID,
+		ColonyOwnerID: lobby.ColonyOwnerID,
+		Role:          util.Ternary(created, internal.ORIGIN_TYPE_OWNER, internal.ORIGIN_TYPE_GUEST),
+	})
+	if err != nil {
+		w.Header().Set("Default-Debug-Header", "Error minting join ticket: "+err.Error())
+		http.Error(w, "Error minting join ticket", http.StatusInternalServerError)
+		middleware.LogResultOfRequest(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(CreateLobbyResponseDTO{ID: lobby.ID, Ticket: ticket})
+	if err != nil {
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		middleware.LogResultOfRequest(w, r, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+	middleware.LogResultOfRequest(w, r, http.StatusOK)
+}
+
+// Mints a ticket for joining the lobby. Requires the ticket issuer api key. Owner tickets are only minted by /create-lobby
+//
+// The optional role query param defaults to guest
+func mintJoinTicketHandler(lobbyManager *internal.LobbyManager, ticketIssuer *auth.TicketIssuer, w http.ResponseWriter, r *http.Request) {
+	if !authorizeTicketIssuer(ticketIssuer, w, r) {
+		return
+	}
+	lobbyID, lobbyIDErr := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	clientID, clientIDErr := getAsUint32(r, "clientID")
+	IGN := r.URL.Query().Get("IGN")
+	role := r.URL.Query().Get("role")
+	if lobbyIDErr != nil {
+		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in lobbyID: %s", lobbyIDErr))
+		http.Error(w, fmt.Sprintf("Error in lobbyID: %s", lobbyIDErr.Error()), http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
+	if clientIDErr != nil {
+		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in clientID: %s", clientIDErr))
+		http.Error(w, fmt.Sprintf("Error in clientID: %s", clientIDErr.Error()), http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
+	if IGN == "" {
+		w.Header().Set("Default-Debug-Header", "IGN query param missing")
+		http.Error(w, "IGN not provided", http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
+	if len(IGN) > internal.MAX_IGN_SIZE {
+		w.Header().Set("Default-Debug-Header", fmt.Sprintf("IGN exceeds %d bytes", internal.MAX_IGN_SIZE))
+		http.Error(w, "IGN too long", http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
+
+	lobby, found := lobbyManager.Lobbies.Load(uint32(lobbyID))
+	if !found {
+		http.Error(w, "Lobby not found", http.StatusNotFound)
+		middleware.LogResultOfRequest(w, r, http.StatusNotFound)
+		return
+	}
+	switch role {
+	case "":
+		role = internal.ORIGIN_TYPE_GUEST
+	// Whoever holds the issuer api key decides who moderates, so moderator tickets need nothing more
+	case internal.ORIGIN_TYPE_GUEST, internal.ORIGIN_TYPE_SPECTATOR, internal.ORIGIN_TYPE_MODERATOR:
+	default:
+		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Invalid role: %s", role))
+		http.Error(w, fmt.Sprintf("Invalid role: %s", role), http.StatusBadRequest)
+		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+		return
+	}
+
+	if lobby.OwnerID.Load() == clientID {
+		w.Header().Set("Default-Debug-Header", "Owner tickets are only minted by /create-lobby, the owner can only resume their session")
+		http.Error(w, "Owner tickets are only minted by /create-lobby", http.StatusForbidden)
+		middleware.LogResultOfRequest(w, r, http.StatusForbidden)
+		return
+	}
+
+	ticket, err := ticketIssuer.Mint(auth.JoinTicket{
+		ClientID:      clientID,
+		IGN:           IGN,
+		LobbyID:       lobby.ID,
+		ColonyID:      lobby.ColonyID,
+		ColonyOwnerID: lobby.ColonyOwnerID,
+		Role:          role,
+	})
+	if err != nil {
+		w.Header().Set("Default-Debug-Header", "Error minting join ticket: "+err.Error())
+		http.Error(w, "Error minting join ticket", http.StatusInternalServerError)
+		middleware.LogResultOfRequest(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	bytes, err := json.Marshal(JoinTicketResponseDTO{Ticket: ticket})
+	if err != nil {
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		middleware.LogResultOfRequest(w, r, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	// Manual JSON encoding. Not ideal, better to use json.Marshal
-	w.Write([]byte(fmt.Sprintf("{\"id\": %s}", strconv.FormatUint(uint64(lobby.ID), 10))))
+	w.Write(bytes)
 	middleware.LogResultOfRequest(w, r, http.StatusOK)
 }
 
+// Whoever holds a ticket may join as whoever it names, so minting them is reserved for services holding the ticket issuer api key
+func authorizeTicketIssuer(ticketIssuer *auth.TicketIssuer, w http.ResponseWriter, r *http.Request) bool {
+	if ticketIssuer.IsAuthorizedIssuer(r.Header.Get("Authorization")) {
+		return true
+	}
+	w.Header().Set("Default-Debug-Header", "Minting join tickets requires the ticket issuer api key as bearer token")
+	http.Error(w, "Not allowed to mint join tickets", http.StatusUnauthorized)
+	middleware.LogResultOfRequest(w, r, http.StatusUnauthorized)
+	return false
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for simplicity
@@ -171,54 +417,66 @@ func getAsUint32(r *http.Request, key string) (uint32, error) {
 	return uint32(parsed), err
 }
 
-func webSocketConnectionRequestHandler(lobbyManager *internal.LobbyManager, w http.ResponseWriter, r *http.Request) {
-	lobbyID, lobbyIDErr := getAsInt(r, "lobbyID")
-	userID, userIDErr := getAsInt(r, "clientID")
-	IGN := r.URL.Query().Get("IGN")
-	colonyID, colonyIDErr := getAsUint32(r, "colonyID")
-	ownerID, ownerIDErr := getAsUint32(r, "ownerID")
+// Requires a join ticket as minted by /create-lobby or /lobby/{id}/ticket. The lobby, colony, client id, IGN and role are all derived from it
+//
+// When resuming a session, the ticket used to join may have expired since, as the resume token is what grants access.
+// Otherwise the owner could never get back in, as owner tickets are only minted for new lobbies
+func webSocketConnectionRequestHandler(lobbyManager *internal.LobbyManager, ticketIssuer *auth.TicketIssuer, w http.ResponseWriter, r *http.Request) {
+	signedTicket := r.URL.Query().Get("ticket")
+	// Clients that lost their connection provide the resume token issued on join to continue their session
+	resumeToken := r.URL.Query().Get("resumeToken")
 
-	if IGN == "" {
-		w.Header().Set("Default-Debug-Header", "IGN query param missing")
-		log.Println("IGN not provided")
-		http.Error(w, "IGN not provided", http.StatusBadRequest)
-		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+	if signedTicket == "" {
+		w.Header().Set("Default-Debug-Header", "ticket query param missing")
+		http.Error(w, "Join ticket not provided", http.StatusUnauthorized)
+		middleware.LogResultOfRequest(w, r, http.StatusUnauthorized)
 		return
 	}
 
-	if lobbyIDErr != nil {
-		log.Printf("Error in lobbyID: %s", lobbyIDErr)
-		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in lobbyID: %s", lobbyIDErr))
-		http.Error(w, fmt.Sprintf("Error in lobbyID: %s", lobbyIDErr.Error()), http.StatusBadRequest)
-		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
-		return
+	var ticket *auth.JoinTicket
+	var ticketErr error
+	if resumeToken != "" {
+		ticket, ticketErr = ticketIssuer.VerifyIgnoringExpiry(signedTicket)
+	} else {
+		ticket, ticketErr = ticketIssuer.Verify(signedTicket)
 	}
-
-	if userIDErr != nil {
-		log.Printf("Error in userID: %s", userIDErr.Error())
-		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in clientID: %s", userIDErr))
-		http.Error(w, fmt.Sprintf("Error in clientID: %s", userIDErr.Error()), http.StatusBadRequest)
-		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+	if ticketErr != nil {
+		log.Printf("Rejected join ticket: %s", ticketErr)
+		w.Header().Set("Default-Debug-Header", ticketErr.Error())
+		http.Error(w, "Invalid join ticket", http.StatusUnauthorized)
+		middleware.LogResultOfRequest(w, r, http.StatusUnauthorized)
 		return
 	}
 
-	if colonyIDErr != nil {
-		log.Printf("Error in colonyID: %s", colonyIDErr)
-		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in colonyID: %s", colonyIDErr))
-		http.Error(w, fmt.Sprintf("Error in colonyID: %s", colonyIDErr.Error()), http.StatusBadRequest)
-		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+	clientType, roleErr := internal.OriginTypeOfRole(ticket.Role)
+	if roleErr != nil {
+		log.Printf("Rejected join ticket: %s", roleErr)
+		w.Header().Set("Default-Debug-Header", roleErr.Error())
+		http.Error(w, "Invalid join ticket", http.StatusUnauthorized)
+		middleware.LogResultOfRequest(w, r, http.StatusUnauthorized)
 		return
 	}
 
-	if ownerIDErr != nil {
-		log.Printf("Error in ownerID: %s", ownerIDErr)
-		w.Header().Set("Default-Debug-Header", fmt.Sprintf("Error in ownerID: %s", ownerIDErr))
-		http.Error(w, fmt.Sprintf("Error in ownerID: %s", ownerIDErr.Error()), http.StatusBadRequest)
-		middleware.LogResultOfRequest(w, r, http.StatusBadRequest)
+	// Clients send the protocol version of the event specifications they were generated against
+	if versionErr := lobbyManager.CheckProtocolVersion(r.URL.Query().Get("protocolVersion")); versionErr != nil {
+		log.Printf("Rejected client: %s", versionErr)
+		w.Header().Set("Default-Debug-Header", versionErr.Error())
+		http.Error(w, "Unsupported protocol version, expected "+internal.ProtocolVersion(), http.StatusUpgradeRequired)
+		middleware.LogResultOfRequest(w, r, http.StatusUpgradeRequired)
 		return
 	}
 
-	if err := lobbyManager.IsJoinPossible(uint32(lobbyID), uint32(userID), colonyID, ownerID); err != nil {
+	lobbyID := ticket.LobbyID
+	userID := ticket.ClientID
+	IGN := ticket.IGN
+
+	var joinCheckErr *internal.LobbyJoinError
+	if resumeToken != "" {
+		joinCheckErr = lobbyManager.IsResumePossible(lobbyID, userID, resumeToken)
+	} else {
+		joinCheckErr = lobbyManager.IsJoinPossible(lobbyID, userID, clientType, ticket.ColonyID, ticket.ColonyOwnerID)
+	}
+	if err := joinCheckErr; err != nil {
 		log.Printf("Failed to join lobby: %v", err)
 		w.Header().Set("Default-Debug-Header", err.Error())
 		switch err.Type {
@@ -234,6 +492,22 @@ func webSocketConnectionRequestHandler(lobbyManager *internal.LobbyManager, w ht
 			http.Error(w, "Lobby is closing", http.StatusGone)
 			middleware.LogResultOfRequest(w, r, http.StatusGone)
 			return
+		case internal.JoinErrorResumeRejected:
+			http.Error(w, "Unable to resume session", http.StatusUnauthorized)
+			middleware.LogResultOfRequest(w, r, http.StatusUnauthorized)
+			return
+		case internal.JoinErrorBanned:
+			http.Error(w, "User is banned from lobby", http.StatusForbidden)
+			middleware.LogResultOfRequest(w, r, http.StatusForbidden)
+			return
+		case internal.JoinErrorFull:
+			http.Error(w, "Lobby is full", http.StatusTooManyRequests)
+			middleware.LogResultOfRequest(w, r, http.StatusTooManyRequests)
+			return
+		default:
+			http.Error(w, "Unable to join lobby", http.StatusInternalServerError)
+			middleware.LogResultOfRequest(w, r, http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -245,15 +519,14 @@ func webSocketConnectionRequestHandler(lobbyManager *internal.LobbyManager, w ht
 		return
 	}
 
-	if joinError := lobbyManager.JoinLobby(uint32(lobbyID), uint32(userID), IGN, conn); joinError != nil {
-		//Send as debug message over WS instead
-		msg := internal.DEBUG_EVENT.CopyIDBytes()
-		msg = append(msg, util.BytesOfUint32(500)...)
-		msg = append(msg, []byte(joinError.Error())...)
-		conn.WriteMessage(websocket.TextMessage, util.EncodeBase16(msg))
-		if err := conn.Close(); err != nil {
-			log.Printf("Failed to close connection: %v", err)
-		}
+	var joinError *internal.LobbyJoinError
+	if resumeToken != "" {
+		joinError = lobbyManager.ResumeSession(lobbyID, userID, resumeToken, conn)
+	} else {
+		joinError = lobbyManager.JoinLobby(lobbyID, userID, IGN, clientType, conn)
+	}
+	if joinError != nil {
+		rejectUpgradedJoin(conn, joinError)
 
 		//In case this works
 		log.Printf("Internal error user id %d joining lobby %d: %v", userID, lobbyID, err)
@@ -262,3 +535,25 @@ func webSocketConnectionRequestHandler(lobbyManager *internal.LobbyManager, w ht
 	}
 	middleware.LogResultOfRequest(w, r, http.StatusOK)
 }
+
+// Closes a connection that failed to join after being upgraded.
+//
+// A lobby that filled up since the join was checked is closed with 1013 (try again later), as the 429 can no longer be sent.
+// Anything else is sent as a debug message before closing
+func rejectUpgradedJoin(conn *websocket.Conn, joinError *internal.LobbyJoinError) {
+	if joinError.Type == internal.JoinErrorFull {
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, joinError.Reason)
+		if err := conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second)); err != nil {
+			log.Printf("Failed to send close message: %v", err)
+		}
+	} else {
+		//Send as debug message over WS instead
+		msg := internal.DEBUG_EVENT.CopyIDBytes()
+		msg = append(msg, util.BytesOfUint32(500)...)
+		msg = append(msg, []byte(joinError.Error())...)
+		conn.WriteMessage(websocket.TextMessage, util.EncodeBase16(msg))
+	}
+	if err := conn.Close(); err != nil {
+		log.Printf("Failed to close connection: %v", err)
+	}
+}
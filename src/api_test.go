@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lilybw/bsc-multiplayer-backend/src/auth"
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
+	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
+	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
+)
+
+const testIssuerAPIKey = "fedcba9876543210fedcba9876543210"
+
+var initTestEventsOnce sync.Once
+
+func newTestAPI(t *testing.T) (*http.ServeMux, *auth.TicketIssuer) {
+	return newTestAPIWith(t, time.Minute, nil)
+}
+
+// configure may change the runtime configuration before the lobby manager is created with it
+func newTestAPIWith(t *testing.T, ticketTTL time.Duration, configure func(*meta.RuntimeConfiguration)) (*http.ServeMux, *auth.TicketIssuer) {
+	initTestEventsOnce.Do(func() {
+		if err := internal.InitEventSpecifications(); err != nil {
+			panic(err)
+		}
+	})
+	fake, err := integrations.NewFakeMainBackend("../fakeMainBackendSettings.json")
+	if err != nil {
+		t.Fatalf("Error creating fake main backend: %v", err)
+	}
+	outbox, err := integrations.NewOutbox(fake, integrations.OutboxOptions{Path: filepath.Join(t.TempDir(), "outbox.json")})
+	if err != nil {
+		t.Fatalf("Error creating outbox: %v", err)
+	}
+	t.Cleanup(outbox.Close)
+	configuration := meta.NewRuntimeConfiguration(meta.RUNTIME_MODE_DEV, meta.MESSAGE_ENCODING_BINARY)
+	if configure != nil {
+		configure(configuration)
+	}
+	ticketIssuer, err := auth.NewTicketIssuer("0123456789abcdef0123456789abcdef", testIssuerAPIKey, ticketTTL)
+	if err != nil {
+		t.Fatalf("Error creating ticket issuer: %v", err)
+	}
+	mux := http.NewServeMux()
+	applyPublicApi(mux, internal.CreateLobbyManager(configuration, fake, outbox), ticketIssuer)
+	return mux, ticketIssuer
+}
+
+// Returns the status code, and the verified ticket of the response if any
+func requestTicket(t *testing.T, mux *http.ServeMux, ticketIssuer *auth.TicketIssuer, apiKey string, path string) (int, *auth.JoinTicket) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, path, nil)
+	if apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		return recorder.Code, nil
+	}
+
+	var response JoinTicketResponseDTO
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshalling response: %v", err)
+	}
+	ticket, err := ticketIssuer.Verify(response.Ticket)
+	if err != nil {
+		t.Fatalf("Expected a valid ticket, got %v", err)
+	}
+	return recorder.Code, ticket
+}
+
+func TestMintingTicketsRequiresIssuerAPIKey(t *testing.T) {
+	mux, ticketIssuer := newTestAPI(t)
+	for _, apiKey := range []string{"", "0123456789abcdef0123456789abcdef"} {
+		if code, _ := requestTicket(t, mux, ticketIssuer, apiKey, "/create-lobby?ownerID=1&colonyID=1&IGN=Owner"); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 creating a lobby with api key %q, got %d", apiKey, code)
+		}
+	}
+
+	code, ticket := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/create-lobby?ownerID=1&colonyID=1&IGN=Owner")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200 creating a lobby, got %d", code)
+	}
+	path := fmt.Sprintf("/lobby/%d/ticket?clientID=2&IGN=Guest", ticket.LobbyID)
+	if code, _ := requestTicket(t, mux, ticketIssuer, "", path); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 minting a ticket without api key, got %d", code)
+	}
+	if code, _ := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, path); code != http.StatusOK {
+		t.Errorf("Expected 200 minting a ticket, got %d", code)
+	}
+
+	// Nothing but the api key is needed for a moderator, and it isn't the signing secret
+	if code, _ := requestTicket(t, mux, ticketIssuer, "0123456789abcdef0123456789abcdef", path+"&role=moderator"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 minting a moderator ticket with the signing secret, got %d", code)
+	}
+	if code, moderatorTicket := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, path+"&role=moderator"); code != http.StatusOK || moderatorTicket.Role != internal.ORIGIN_TYPE_MODERATOR {
+		t.Errorf("Expected a moderator ticket, got %d, %+v", code, moderatorTicket)
+	}
+}
+
+func TestCreateLobbyOnlyMintsOwnerTicketsForNewLobbies(t *testing.T) {
+	mux, ticketIssuer := newTestAPI(t)
+	_, ticket := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/create-lobby?ownerID=1&colonyID=7&IGN=Owner")
+	if ticket == nil || ticket.Role != internal.ORIGIN_TYPE_OWNER || ticket.ColonyID != 7 || ticket.ColonyOwnerID != 1 {
+		t.Fatalf("Expected an owner ticket for colony 7 of user 1, got %+v", ticket)
+	}
+
+	if code, _ := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/create-lobby?ownerID=1&colonyID=7&IGN=Owner"); code != http.StatusConflict {
+		t.Errorf("Expected 409 for the owner of the existing lobby, got %d", code)
+	}
+	_, guestTicket := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/create-lobby?ownerID=2&colonyID=7&IGN=Guest")
+	if guestTicket == nil || guestTicket.Role != internal.ORIGIN_TYPE_GUEST || guestTicket.LobbyID != ticket.LobbyID {
+		t.Errorf("Expected a guest ticket for the existing lobby, got %+v", guestTicket)
+	}
+}
+
+func TestMintingTicketsRejectsLongIGN(t *testing.T) {
+	mux, ticketIssuer := newTestAPI(t)
+	IGN := strings.Repeat("x", internal.MAX_IGN_SIZE+1)
+	if code, _ := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/create-lobby?ownerID=1&colonyID=1&IGN="+IGN); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an IGN over %d bytes, got %d", internal.MAX_IGN_SIZE, code)
+	}
+	if code, _ := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/create-lobby?ownerID=1&colonyID=1&IGN="+IGN[1:]); code != http.StatusOK {
+		t.Errorf("Expected 200 for an IGN of %d bytes, got %d", internal.MAX_IGN_SIZE, code)
+	}
+	if code, _ := requestTicket(t, mux, ticketIssuer, testIssuerAPIKey, "/lobby/1/ticket?clientID=2&IGN="+IGN); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 minting a ticket for an IGN over %d bytes, got %d", internal.MAX_IGN_SIZE, code)
+	}
+}
+
+// Connects on /connect, and reads until the resume token arrives
+func connectForResumeToken(t *testing.T, server *httptest.Server, query string) (*websocket.Conn, string) {
+	t.Helper()
+	conn, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/connect?"+query, nil)
+	if err != nil {
+		t.Fatalf("Error connecting: %v, %+v", err, response)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Did not recieve a resume token: %v", err)
+		}
+		// Sender id, then message id
+		if len(data) < 8 || binary.BigEndian.Uint32(data[4:8]) != internal.RESUME_TOKEN_EVENT.ID {
+			continue
+		}
+		token, err := internal.Deserialize(internal.RESUME_TOKEN_EVENT, data[8:], true)
+		if err != nil {
+			t.Fatalf("Error deserializing resume token: %v", err)
+		}
+		return conn, token.Token
+	}
+}
+
+// No new owner ticket can be minted for an existing lobby, so the owner must be able to resume on the ticket they joined with
+func TestOwnerResumesAfterTicketExpired(t *testing.T) {
+	mux, ticketIssuer := newTestAPIWith(t, time.Second, func(configuration *meta.RuntimeConfiguration) {
+		configuration.ReconnectGracePeriod = 0
+		configuration.OwnerDisconnectPolicy = meta.OWNER_DISCONNECT_POLICY_WAIT
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	request := httptest.NewRequest(http.MethodPost, "/create-lobby?ownerID=1&colonyID=1&IGN=Owner", nil)
+	request.Header.Set("Authorization", "Bearer "+testIssuerAPIKey)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	var created CreateLobbyResponseDTO
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Error unmarshalling response: %v", err)
+	}
+	query := "protocolVersion=" + internal.ProtocolVersion() + "&ticket=" + created.Ticket
+	conn, resumeToken := connectForResumeToken(t, server, query)
+	conn.UnderlyingConn().Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for _, err := ticketIssuer.Verify(created.Ticket); err == nil; _, err = ticketIssuer.Verify(created.Ticket) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the ticket to expire in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/connect?"+query, nil); err == nil || response == nil || response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 joining on an expired ticket, got %v", err)
+	}
+	_, newResumeToken := connectForResumeToken(t, server, query+"&resumeToken="+resumeToken)
+	if newResumeToken == resumeToken {
+		t.Error("Expected a new resume token after resuming")
+	}
+}
+
+// A lobby may fill up after the join was checked, by when the response has been upgraded and the 429 can't be sent
+func TestJoinRejectedAsFullAfterUpgrade(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		rejectUpgradedJoin(conn, &internal.LobbyJoinError{Reason: "Lobby is full", Type: internal.JoinErrorFull, LobbyID: 1})
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Errorf("Expected close code %d, got %v", websocket.CloseTryAgainLater, err)
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTicketMalformed = errors.New("join ticket is malformed")
+	ErrTicketSignature = errors.New("join ticket signature is invalid")
+	ErrTicketExpired   = errors.New("join ticket has expired")
+)
+
+// The claims of a join ticket. Everything a client may do in a lobby is derived from this
+// rather than from anything the client states itself.
+type JoinTicket struct {
+	ClientID uint32 `json:"clientID"`
+	IGN      string `json:"IGN"`
+	LobbyID  uint32 `json:"lobbyID"`
+	// Of the lobby, so a lobby the main backend still thinks is open can be closed there if it no longer exists here
+	ColonyID      uint32 `json:"colonyID"`
+	ColonyOwnerID uint32 `json:"colonyOwnerID"`
+	// Same values as internal.OriginType
+	Role string `json:"role"`
+	// Seconds since epoch
+	Expiry int64 `json:"exp"`
+}
+
+// Mints and verifies join tickets using a shared secret.
+//
+// A ticket is the base64url encoded JSON claims and the base64url encoded HMAC-SHA256 of those, separated by a "."
+type TicketIssuer struct {
+	secret []byte
+	// Held by the services allowed to request tickets, fx. the main backend. Never the same as the secret
+	apiKey []byte
+	ttl    time.Duration
+	// Overwritten in tests
+	now func() time.Time
+}
+
+func NewTicketIssuer(secret string, apiKey string, ttl time.Duration) (*TicketIssuer, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("join ticket secret must be at least 32 characters, got %d", len(secret))
+	}
+	if len(apiKey) < 32 {
+		return nil, fmt.Errorf("ticket issuer api key must be at least 32 characters, got %d", len(apiKey))
+	}
+	if apiKey == secret {
+		return nil, fmt.Errorf("ticket issuer api key must differ from the join ticket secret")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("join ticket time to live must be positive, got %s", ttl)
+	}
+	return &TicketIssuer{secret: []byte(secret), apiKey: []byte(apiKey), ttl: ttl, now: time.Now}, nil
+}
+
+// Tickets are only minted for those presenting the api key as "Authorization: Bearer <key>"
+func (ti *TicketIssuer) IsAuthorizedIssuer(authorizationHeader string) bool {
+	key, found := strings.CutPrefix(authorizationHeader, "Bearer ")
+	return found && subtle.ConstantTimeCompare(ti.apiKey, []byte(key)) == 1
+}
+
+// Returns the signed ticket. Expiry is overwritten with now + time to live
+func (ti *TicketIssuer) Mint(ticket JoinTicket) (string, error) {
+	ticket.Expiry = ti.now().Add(ti.ttl).Unix()
+	claims, err := json.Marshal(ticket)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling join ticket: %s", err.Error())
+	}
+	encodedClaims := base64.RawURLEncoding.EncodeToString(claims)
+	return encodedClaims + "." + base64.RawURLEncoding.EncodeToString(ti.sign(encodedClaims)), nil
+}
+
+// Returns the claims of the ticket if the signature is valid and it hasn't expired yet
+func (ti *TicketIssuer) Verify(signed string) (*JoinTicket, error) {
+	ticket, err := ti.VerifyIgnoringExpiry(signed)
+	if err != nil {
+		return nil, err
+	}
+	if ti.now().Unix() >= ticket.Expiry {
+		return nil, ErrTicketExpired
+	}
+	return ticket, nil
+}
+
+// As Verify, but also accepts expired tickets.
+//
+// Only for resuming a session, where the resume token is what grants access and the ticket only tells whose session it is
+func (ti *TicketIssuer) VerifyIgnoringExpiry(signed string) (*JoinTicket, error) {
+	encodedClaims, encodedSignature, found := strings.Cut(signed, ".")
+	if !found {
+		return nil, ErrTicketMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, ErrTicketMalformed
+	}
+	if !hmac.Equal(signature, ti.sign(encodedClaims)) {
+		return nil, ErrTicketSignature
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(encodedClaims)
+	if err != nil {
+		return nil, ErrTicketMalformed
+	}
+	var ticket JoinTicket
+	if err := json.Unmarshal(claims, &ticket); err != nil {
+		return nil, ErrTicketMalformed
+	}
+	return &ticket, nil
+}
+
+func (ti *TicketIssuer) sign(encodedClaims string) []byte {
+	mac := hmac.New(sha256.New, ti.secret)
+	mac.Write([]byte(encodedClaims))
+	return mac.Sum(nil)
+}
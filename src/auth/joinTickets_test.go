@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+const testAPIKey = "fedcba9876543210fedcba9876543210"
+
+func TestMintAndVerifyTicket(t *testing.T) {
+	issuer, err := NewTicketIssuer(testSecret, testAPIKey, time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	signed, err := issuer.Mint(JoinTicket{ClientID: 42, IGN: "Hello", LobbyID: 7, Role: "guest"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ticket, err := issuer.Verify(signed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ticket.ClientID != 42 || ticket.IGN != "Hello" || ticket.LobbyID != 7 || ticket.Role != "guest" {
+		t.Errorf("Claims changed during round trip: %+v", ticket)
+	}
+}
+
+func TestVerifyRejectsTamperedTicket(t *testing.T) {
+	issuer, _ := NewTicketIssuer(testSecret, testAPIKey, time.Minute)
+	signed, _ := issuer.Mint(JoinTicket{ClientID: 42, Role: "guest"})
+	forged, _ := issuer.Mint(JoinTicket{ClientID: 42, Role: "owner"})
+
+	// Claims of one ticket with the signature of another
+	claims, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(signed, ".")
+	if _, err := issuer.Verify(claims + "." + signature); !errors.Is(err, ErrTicketSignature) {
+		t.Errorf("Expected ErrTicketSignature, got %v", err)
+	}
+
+	otherIssuer, _ := NewTicketIssuer(strings.Repeat("x", 32), testAPIKey, time.Minute)
+	if _, err := otherIssuer.Verify(signed); !errors.Is(err, ErrTicketSignature) {
+		t.Errorf("Expected ErrTicketSignature for different secret, got %v", err)
+	}
+
+	if _, err := issuer.Verify("not a ticket"); !errors.Is(err, ErrTicketMalformed) {
+		t.Errorf("Expected ErrTicketMalformed, got %v", err)
+	}
+}
+
+func TestVerifyRejectsExpiredTicket(t *testing.T) {
+	issuer, _ := NewTicketIssuer(testSecret, testAPIKey, time.Minute)
+	now := time.Now()
+	issuer.now = func() time.Time { return now }
+	signed, _ := issuer.Mint(JoinTicket{ClientID: 42, Role: "guest"})
+
+	now = now.Add(time.Minute - time.Second)
+	if _, err := issuer.Verify(signed); err != nil {
+		t.Errorf("Expected the ticket to be valid until it expires, got %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := issuer.Verify(signed); !errors.Is(err, ErrTicketExpired) {
+		t.Errorf("Expected ErrTicketExpired, got %v", err)
+	}
+	if ticket, err := issuer.VerifyIgnoringExpiry(signed); err != nil || ticket.ClientID != 42 {
+		t.Errorf("Expected the expired ticket to be accepted when ignoring expiry, got %+v, %v", ticket, err)
+	}
+}
+
+func TestNewTicketIssuerRejectsWeakKeys(t *testing.T) {
+	if _, err := NewTicketIssuer("short", testAPIKey, time.Minute); err == nil {
+		t.Error("Expected error for short secret")
+	}
+	if _, err := NewTicketIssuer(testSecret, "short", time.Minute); err == nil {
+		t.Error("Expected error for short api key")
+	}
+	if _, err := NewTicketIssuer(testSecret, testSecret, time.Minute); err == nil {
+		t.Error("Expected error for an api key that is the secret")
+	}
+}
+
+func TestIsAuthorizedIssuer(t *testing.T) {
+	issuer, err := NewTicketIssuer(testSecret, testAPIKey, time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !issuer.IsAuthorizedIssuer("Bearer " + testAPIKey) {
+		t.Error("Expected the api key to be accepted")
+	}
+	for _, header := range []string{"", testAPIKey, "Bearer " + testSecret, "Bearer " + testAPIKey[1:], "Basic " + testAPIKey} {
+		if issuer.IsAuthorizedIssuer(header) {
+			t.Errorf("Expected \"%s\" to be rejected", header)
+		}
+	}
+}
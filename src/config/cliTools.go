@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
 )
 
 func HandleToolRequest(args []string) error {
@@ -19,6 +21,14 @@ func HandleToolRequest(args []string) error {
 			log.Println("[config] --print-event-specs flag found, printing event specs")
 			return handleEventSpecRequest(args[1:])
 		}
+		if arg == "--diff-event-specs" {
+			log.Println("[config] --diff-event-specs flag found, comparing event specs")
+			return handleDiffEventSpecsRequest(args[1:])
+		}
+		if arg == "--generate-go-codecs" {
+			log.Println("[config] --generate-go-codecs flag found, generating go codecs")
+			return handleGoCodecRequest(args[1:])
+		}
 	}
 
 	return nil
@@ -62,3 +72,31 @@ func handleEventSpecRequest(args []string) error {
 
 	return nil
 }
+
+// Relative to the root of the repository
+const DEFAULT_GO_CODECS_PATH = "./src/internal/eventCodecs_gen.go"
+
+func handleGoCodecRequest(args []string) error {
+	var outputPath = DEFAULT_GO_CODECS_PATH
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--output=") {
+			var err error
+			outputPath, err = retrieveValueOfKVArg(arg)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	specs := getOrderedEventSpecs()
+	source, err := internal.GenerateGoCodecs(specs, "go run ./src --tools --generate-go-codecs")
+	if err != nil {
+		return err
+	}
+
+	if mkDirErr := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); mkDirErr != nil {
+		return fmt.Errorf("error creating directory for output file: %s", mkDirErr.Error())
+	}
+	return os.WriteFile(outputPath, source, 0o644)
+}
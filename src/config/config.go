@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
 	"github.com/joho/godotenv"
@@ -76,9 +77,222 @@ func ParseArgsAndApplyENV() (*meta.RuntimeConfiguration, error) {
 		}
 	}
 
+	if applyErr := applyENVToConfiguration(configuration); applyErr != nil {
+		return nil, applyErr
+	}
+
 	return configuration, nil
 }
 
+// Reads any optional settings from the loaded environment. Missing keys keeps the defaults.
+func applyENVToConfiguration(configuration *meta.RuntimeConfiguration) error {
+	gracePeriodS, err := GetIntOr("RECONNECT_GRACE_PERIOD_S", int(configuration.ReconnectGracePeriod.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.ReconnectGracePeriod = time.Duration(gracePeriodS) * time.Second
+
+	if configuration.ResumeBufferSize, err = GetIntOr("RESUME_BUFFER_SIZE", configuration.ResumeBufferSize); err != nil {
+		return err
+	}
+
+	if configuration.SendQueueSize, err = GetIntOr("CLIENT_SEND_QUEUE_SIZE", configuration.SendQueueSize); err != nil {
+		return err
+	}
+	if configuration.SendQueueSize <= 0 {
+		return fmt.Errorf("[config] CLIENT_SEND_QUEUE_SIZE must be positive, got %d", configuration.SendQueueSize)
+	}
+
+	writeTimeoutMS, err := GetIntOr("CLIENT_WRITE_TIMEOUT_MS", int(configuration.WriteTimeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.WriteTimeout = time.Duration(writeTimeoutMS) * time.Millisecond
+
+	pingIntervalMS, err := GetIntOr("PING_INTERVAL_MS", int(configuration.PingInterval.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.PingInterval = time.Duration(pingIntervalMS) * time.Millisecond
+
+	pongTimeoutMS, err := GetIntOr("PONG_TIMEOUT_MS", int(configuration.PongTimeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.PongTimeout = time.Duration(pongTimeoutMS) * time.Millisecond
+
+	configuration.JoinTicketSecret = GetOr("JOIN_TICKET_SECRET", "")
+	configuration.TicketIssuerAPIKey = GetOr("TICKET_ISSUER_API_KEY", "")
+	ticketTTLS, err := GetIntOr("JOIN_TICKET_TTL_S", int(configuration.JoinTicketTTL.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.JoinTicketTTL = time.Duration(ticketTTLS) * time.Second
+
+	if configuration.SpoofDisconnectThreshold, err = GetIntOr("SPOOF_DISCONNECT_THRESHOLD", configuration.SpoofDisconnectThreshold); err != nil {
+		return err
+	}
+
+	clientRateLimit, err := GetIntOr("CLIENT_RATE_LIMIT_PER_S", int(configuration.ClientRateLimit))
+	if err != nil {
+		return err
+	}
+	configuration.ClientRateLimit = float64(clientRateLimit)
+	if configuration.ClientRateBurst, err = GetIntOr("CLIENT_RATE_LIMIT_BURST", configuration.ClientRateBurst); err != nil {
+		return err
+	}
+	if configuration.RateLimitOffenceThreshold, err = GetIntOr("RATE_LIMIT_OFFENCE_THRESHOLD", configuration.RateLimitOffenceThreshold); err != nil {
+		return err
+	}
+	offenceWindowS, err := GetIntOr("RATE_LIMIT_OFFENCE_WINDOW_S", int(configuration.RateLimitOffenceWindow.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.RateLimitOffenceWindow = time.Duration(offenceWindowS) * time.Second
+
+	readLimit, err := GetIntOr("WS_READ_LIMIT_BYTES", int(configuration.WSReadLimit))
+	if err != nil {
+		return err
+	}
+	configuration.WSReadLimit = int64(readLimit)
+
+	switch policy := meta.OwnerDisconnectPolicy(GetOr("OWNER_DISCONNECT_POLICY", string(configuration.OwnerDisconnectPolicy))); policy {
+	case meta.OWNER_DISCONNECT_POLICY_CLOSE, meta.OWNER_DISCONNECT_POLICY_WAIT, meta.OWNER_DISCONNECT_POLICY_PROMOTE:
+		configuration.OwnerDisconnectPolicy = policy
+	default:
+		return fmt.Errorf("[config] Invalid OWNER_DISCONNECT_POLICY \"%s\", expected \"close|wait|promote\"", policy)
+	}
+	ownerWaitS, err := GetIntOr("OWNER_RECONNECT_WAIT_S", int(configuration.OwnerReconnectWait.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.OwnerReconnectWait = time.Duration(ownerWaitS) * time.Second
+
+	for _, url := range strings.Split(GetOr("WEBHOOK_URLS", ""), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			configuration.WebhookURLs = append(configuration.WebhookURLs, url)
+		}
+	}
+	configuration.WebhookSecret = GetOr("WEBHOOK_SECRET", "")
+	if len(configuration.WebhookURLs) > 0 && configuration.WebhookSecret == "" {
+		return fmt.Errorf("[config] WEBHOOK_SECRET is required when WEBHOOK_URLS is set")
+	}
+	webhookAttempts, err := GetIntOr("WEBHOOK_MAX_ATTEMPTS", configuration.WebhookMaxAttempts)
+	if err != nil {
+		return err
+	}
+	configuration.WebhookMaxAttempts = webhookAttempts
+	webhookBackoffMS, err := GetIntOr("WEBHOOK_INITIAL_BACKOFF_MS", int(configuration.WebhookInitialBackoff.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.WebhookInitialBackoff = time.Duration(webhookBackoffMS) * time.Millisecond
+	webhookMaxBackoffS, err := GetIntOr("WEBHOOK_MAX_BACKOFF_S", int(configuration.WebhookMaxBackoff.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.WebhookMaxBackoff = time.Duration(webhookMaxBackoffS) * time.Second
+
+	mbTimeoutMS, err := GetIntOr("MAIN_BACKEND_TIMEOUT_MS", int(configuration.MainBackendTimeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.MainBackendTimeout = time.Duration(mbTimeoutMS) * time.Millisecond
+	mbDialTimeoutMS, err := GetIntOr("MAIN_BACKEND_DIAL_TIMEOUT_MS", int(configuration.MainBackendDialTimeout.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.MainBackendDialTimeout = time.Duration(mbDialTimeoutMS) * time.Millisecond
+	if configuration.MainBackendMaxAttempts, err = GetIntOr("MAIN_BACKEND_MAX_ATTEMPTS", configuration.MainBackendMaxAttempts); err != nil {
+		return err
+	}
+	mbBackoffMS, err := GetIntOr("MAIN_BACKEND_RETRY_BACKOFF_MS", int(configuration.MainBackendRetryBackoff.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.MainBackendRetryBackoff = time.Duration(mbBackoffMS) * time.Millisecond
+	if configuration.MainBackendBreakerThreshold, err = GetIntOr("MAIN_BACKEND_BREAKER_THRESHOLD", configuration.MainBackendBreakerThreshold); err != nil {
+		return err
+	}
+	mbCooldownS, err := GetIntOr("MAIN_BACKEND_BREAKER_COOLDOWN_S", int(configuration.MainBackendBreakerCooldown.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.MainBackendBreakerCooldown = time.Duration(mbCooldownS) * time.Second
+
+	switch scheme := GetOr("MAIN_BACKEND_SCHEME", configuration.MainBackendScheme); scheme {
+	case "https", "http":
+		configuration.MainBackendScheme = scheme
+	default:
+		return fmt.Errorf("[config] Invalid MAIN_BACKEND_SCHEME \"%s\", expected \"https|http\"", scheme)
+	}
+	configuration.MainBackendCAFile = GetOr("MAIN_BACKEND_CA_FILE", "")
+	configuration.MainBackendClientCertFile = GetOr("MAIN_BACKEND_CLIENT_CERT_FILE", "")
+	configuration.MainBackendClientKeyFile = GetOr("MAIN_BACKEND_CLIENT_KEY_FILE", "")
+	if (configuration.MainBackendClientCertFile == "") != (configuration.MainBackendClientKeyFile == "") {
+		return fmt.Errorf("[config] MAIN_BACKEND_CLIENT_CERT_FILE and MAIN_BACKEND_CLIENT_KEY_FILE must be set together")
+	}
+	if configuration.MainBackendInsecureSkipVerify, err = GetBoolOr("MAIN_BACKEND_INSECURE_SKIP_VERIFY", configuration.MainBackendInsecureSkipVerify); err != nil {
+		return err
+	}
+	if configuration.MainBackendInsecureSkipVerify && configuration.Mode == meta.RUNTIME_MODE_PROD {
+		return fmt.Errorf("[config] MAIN_BACKEND_INSECURE_SKIP_VERIFY is not allowed in prod mode")
+	}
+	switch mode := meta.MainBackendMode(GetOr("MAIN_BACKEND_MODE", string(configuration.MainBackendMode))); mode {
+	case meta.MAIN_BACKEND_MODE_REMOTE, meta.MAIN_BACKEND_MODE_FAKE:
+		configuration.MainBackendMode = mode
+	default:
+		return fmt.Errorf("[config] Invalid MAIN_BACKEND_MODE \"%s\", expected \"remote|fake\"", mode)
+	}
+	if configuration.MainBackendMode == meta.MAIN_BACKEND_MODE_FAKE && configuration.Mode == meta.RUNTIME_MODE_PROD {
+		return fmt.Errorf("[config] MAIN_BACKEND_MODE=fake is not allowed in prod mode")
+	}
+	configuration.MainBackendFakeSettingsFile = GetOr("MAIN_BACKEND_FAKE_SETTINGS_FILE", "")
+	settingsTTLS, err := GetIntOr("MINIGAME_SETTINGS_CACHE_TTL_S", int(configuration.MinigameSettingsCacheTTL.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.MinigameSettingsCacheTTL = time.Duration(settingsTTLS) * time.Second
+	staleIfErrorS, err := GetIntOr("MINIGAME_SETTINGS_STALE_IF_ERROR_S", int(configuration.MinigameSettingsStaleIfError.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.MinigameSettingsStaleIfError = time.Duration(staleIfErrorS) * time.Second
+
+	configuration.OutboxFile = GetOr("OUTBOX_FILE", configuration.OutboxFile)
+	outboxBackoffMS, err := GetIntOr("OUTBOX_RETRY_BACKOFF_MS", int(configuration.OutboxRetryBackoff.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	configuration.OutboxRetryBackoff = time.Duration(outboxBackoffMS) * time.Millisecond
+	outboxMaxBackoffS, err := GetIntOr("OUTBOX_MAX_BACKOFF_S", int(configuration.OutboxMaxBackoff.Seconds()))
+	if err != nil {
+		return err
+	}
+	configuration.OutboxMaxBackoff = time.Duration(outboxMaxBackoffS) * time.Second
+	if configuration.OutboxMaxFailed, err = GetIntOr("OUTBOX_MAX_FAILED", configuration.OutboxMaxFailed); err != nil {
+		return err
+	}
+	if configuration.OutboxMaxFailed <= 0 {
+		return fmt.Errorf("[config] OUTBOX_MAX_FAILED must be positive, got %d", configuration.OutboxMaxFailed)
+	}
+
+	switch policy := meta.SlowConsumerPolicy(GetOr("SLOW_CONSUMER_POLICY", string(configuration.SlowConsumerPolicy))); policy {
+	case meta.SLOW_CONSUMER_POLICY_DROP, meta.SLOW_CONSUMER_POLICY_DISCONNECT:
+		configuration.SlowConsumerPolicy = policy
+	default:
+		return fmt.Errorf("[config] Invalid SLOW_CONSUMER_POLICY \"%s\", expected \"drop|disconnect\"", policy)
+	}
+
+	switch policy := meta.ProtocolVersionPolicy(GetOr("PROTOCOL_VERSION_POLICY", string(configuration.ProtocolVersionPolicy))); policy {
+	case meta.PROTOCOL_VERSION_POLICY_IGNORE, meta.PROTOCOL_VERSION_POLICY_WARN, meta.PROTOCOL_VERSION_POLICY_REJECT:
+		configuration.ProtocolVersionPolicy = policy
+	default:
+		return fmt.Errorf("[config] Invalid PROTOCOL_VERSION_POLICY \"%s\", expected \"ignore|warn|reject\"", policy)
+	}
+	return nil
+}
+
 // Overwrites any env variables currently set in environment
 func LoadDevConfig() error {
 	return LoadCustomConfig("dev.env")
@@ -125,6 +339,32 @@ func GetInt(key string) (int, error) {
 	return strconv.Atoi(val)
 }
 
+// Returns the default value if the key is not set, but errors if it is set and isn't an integer
+func GetIntOr(key string, defaultValue int) (int, error) {
+	val, err := LoudGet(key)
+	if err != nil {
+		return defaultValue, nil
+	}
+	parsed, parseErr := strconv.Atoi(val)
+	if parseErr != nil {
+		return defaultValue, fmt.Errorf("[config] Invalid integer value for %s: %s", key, parseErr.Error())
+	}
+	return parsed, nil
+}
+
+// Returns the default value if the key is not set, but errors if it is set and isn't a boolean
+func GetBoolOr(key string, defaultValue bool) (bool, error) {
+	val, err := LoudGet(key)
+	if err != nil {
+		return defaultValue, nil
+	}
+	parsed, parseErr := strconv.ParseBool(val)
+	if parseErr != nil {
+		return defaultValue, fmt.Errorf("[config] Invalid boolean value for %s: %s", key, parseErr.Error())
+	}
+	return parsed, nil
+}
+
 // Get func to get env value, will log on error but return the empty value
 // The value of the key will be trimmed/stripped/whitespace removed
 func Get(key string) string {
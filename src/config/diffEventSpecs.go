@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A difference between two exports of the event specifications
+type eventSpecChange struct {
+	ID   uint32
+	Name string
+	// Clients generated against the previous specifications would misparse or send messages the server rejects
+	Breaking    bool
+	Description string
+}
+
+func (change eventSpecChange) String() string {
+	severity := "compatible"
+	if change.Breaking {
+		severity = "BREAKING"
+	}
+	return fmt.Sprintf("%-10s %d %s: %s", severity, change.ID, change.Name, change.Description)
+}
+
+func handleDiffEventSpecsRequest(args []string) error {
+	var previousPath string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--against=") {
+			var err error
+			previousPath, err = retrieveValueOfKVArg(arg)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if previousPath == "" {
+		return fmt.Errorf("no previously exported event specifications given, expected --against=<path to json>")
+	}
+
+	previous, err := readJSONEventSpecs(previousPath)
+	if err != nil {
+		return err
+	}
+	changes := diffEventSpecs(previous, toJSONEventSpecs(getOrderedEventSpecs()).Events)
+
+	breaking := 0
+	for _, change := range changes {
+		fmt.Println(change.String())
+		if change.Breaking {
+			breaking++
+		}
+	}
+	fmt.Printf("%d change(s) to the event specifications since %s, %d breaking\n", len(changes), previousPath, breaking)
+	if breaking > 0 {
+		return fmt.Errorf("%d breaking change(s) to the event specifications", breaking)
+	}
+	return nil
+}
+
+// Reads an export of --print-event-specs in JSON. Exports from before the protocol version was added, which are only the array of events, are read as well
+func readJSONEventSpecs(path string) ([]jsonEventSpec, error) {
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading event specifications: %s", err.Error())
+	}
+	var specs jsonEventSpecs
+	if err := json.Unmarshal(fileBytes, &specs); err == nil {
+		return specs.Events, nil
+	}
+	if err := json.Unmarshal(fileBytes, &specs.Events); err != nil {
+		return nil, fmt.Errorf("error parsing event specifications %s: %s", path, err.Error())
+	}
+	return specs.Events, nil
+}
+
+// Lists every change from previous to current, ordered by event id.
+// Changes to the wire layout are breaking, except for raising the max size of a string or max length of a slice.
+// Renames and changed send permissions are listed as compatible, changed descriptions not at all
+func diffEventSpecs(previous []jsonEventSpec, current []jsonEventSpec) []eventSpecChange {
+	previousByID := make(map[uint32]jsonEventSpec, len(previous))
+	for _, spec := range previous {
+		previousByID[spec.ID] = spec
+	}
+	currentIDs := make(map[uint32]bool, len(current))
+
+	var changes []eventSpecChange
+	for _, spec := range current {
+		currentIDs[spec.ID] = true
+		before, existed := previousByID[spec.ID]
+		if !existed {
+			changes = append(changes, eventSpecChange{ID: spec.ID, Name: spec.Name, Description: "event added"})
+			continue
+		}
+		changes = append(changes, diffEventSpec(before, spec)...)
+	}
+	for _, spec := range previous {
+		if !currentIDs[spec.ID] {
+			changes = append(changes, eventSpecChange{ID: spec.ID, Name: spec.Name, Breaking: true, Description: "event removed"})
+		}
+	}
+
+	// Removed events are found last, but belong with the rest
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes
+}
+
+func diffEventSpec(before jsonEventSpec, after jsonEventSpec) []eventSpecChange {
+	var changes []eventSpecChange
+	add := func(breaking bool, format string, args ...interface{}) {
+		changes = append(changes, eventSpecChange{ID: after.ID, Name: after.Name, Breaking: breaking, Description: fmt.Sprintf(format, args...)})
+	}
+
+	if before.Name != after.Name {
+		add(false, "renamed from %s", before.Name)
+	}
+	if !maps.Equal(before.Permissions, after.Permissions) {
+		add(false, "send permissions changed from %v to %v", before.Permissions, after.Permissions)
+	}
+	if before.WireFormat != after.WireFormat {
+		add(true, "wire format changed from %d to %d", before.WireFormat, after.WireFormat)
+	}
+	for _, change := range diffStructure("", before.Structure, after.Structure) {
+		add(change.Breaking, "%s", change.Description)
+	}
+	return changes
+}
+
+// Elements are compared by position, as that is all the wire format knows of them
+func diffStructure(path string, before []jsonElementDescriptor, after []jsonElementDescriptor) []eventSpecChange {
+	var changes []eventSpecChange
+	add := func(breaking bool, format string, args ...interface{}) {
+		changes = append(changes, eventSpecChange{Breaking: breaking, Description: fmt.Sprintf(format, args...)})
+	}
+
+	for i := 0; i < max(len(before), len(after)); i++ {
+		if i >= len(after) {
+			add(true, "field %s%s removed", path, before[i].FieldName)
+			continue
+		}
+		if i >= len(before) {
+			add(true, "field %s%s added", path, after[i].FieldName)
+			continue
+		}
+		was, is := before[i], after[i]
+		name := path + is.FieldName
+
+		if was.Type != is.Type || was.ByteSize != is.ByteSize || was.LengthPrefixSize != is.LengthPrefixSize ||
+			was.ElemType != is.ElemType || was.ElemByteSize != is.ElemByteSize {
+			add(true, "field %s%s (%s) replaced by %s (%s)", path, was.FieldName, describeJSONElement(was), is.FieldName, describeJSONElement(is))
+			continue
+		}
+		if was.FieldName != is.FieldName {
+			add(false, "field %s%s renamed to %s", path, was.FieldName, is.FieldName)
+		}
+		// Exports from before max sizes were included have none to compare with
+		if was.MaxByteSize != 0 && was.MaxByteSize != is.MaxByteSize {
+			add(is.MaxByteSize < was.MaxByteSize, "max size of %s changed from %d to %d", name, was.MaxByteSize, is.MaxByteSize)
+		}
+		if was.MaxLength != is.MaxLength {
+			add(is.MaxLength < was.MaxLength, "max length of %s changed from %d to %d", name, was.MaxLength, is.MaxLength)
+		}
+		changes = append(changes, diffStructure(name+"[].", was.ElemStructure, is.ElemStructure)...)
+	}
+	return changes
+}
+
+func describeJSONElement(element jsonElementDescriptor) string {
+	description := element.Type
+	if element.ElemType != "" {
+		description = "[]" + element.ElemType
+	}
+	if element.LengthPrefixSize > 0 {
+		description += fmt.Sprintf(", %d byte length prefix", element.LengthPrefixSize)
+	}
+	return description
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"testing"
+)
+
+func testJSONEventSpec() jsonEventSpec {
+	return jsonEventSpec{
+		ID:          3000,
+		Name:        "AsteroidSpawn",
+		Permissions: map[string]bool{"server": true},
+		WireFormat:  1,
+		Structure: []jsonElementDescriptor{
+			{FieldName: "id", Type: "uint32", ByteSize: 4},
+			{FieldName: "hits", Type: "slice", LengthPrefixSize: 4, ElemType: "uint32", ElemByteSize: 4, MaxLength: 8},
+			{FieldName: "charCode", Type: "string", MaxByteSize: 32},
+		},
+	}
+}
+
+func TestDiffEventSpecs(t *testing.T) {
+	removed := jsonEventSpec{ID: 1, Name: "Removed"}
+	added := jsonEventSpec{ID: 2, Name: "Added"}
+	changed := testJSONEventSpec()
+	changed.Name = "AsteroidsAsteroidSpawn"
+	changed.Structure = []jsonElementDescriptor{
+		{FieldName: "asteroidID", Type: "uint32", ByteSize: 4},
+		{FieldName: "hits", Type: "slice", LengthPrefixSize: 4, ElemType: "uint32", ElemByteSize: 4, MaxLength: 4},
+		{FieldName: "charCode", Type: "string", MaxByteSize: 64},
+	}
+
+	changes := diffEventSpecs([]jsonEventSpec{removed, testJSONEventSpec()}, []jsonEventSpec{added, changed})
+	expected := []eventSpecChange{
+		{ID: 1, Name: "Removed", Breaking: true, Description: "event removed"},
+		{ID: 2, Name: "Added", Description: "event added"},
+		{ID: 3000, Name: "AsteroidsAsteroidSpawn", Description: "renamed from AsteroidSpawn"},
+		{ID: 3000, Name: "AsteroidsAsteroidSpawn", Description: "field id renamed to asteroidID"},
+		{ID: 3000, Name: "AsteroidsAsteroidSpawn", Breaking: true, Description: "max length of hits changed from 8 to 4"},
+		{ID: 3000, Name: "AsteroidsAsteroidSpawn", Description: "max size of charCode changed from 32 to 64"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected change %d to be %q, got %q", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestDiffEventSpecsBreakingLayout(t *testing.T) {
+	changed := testJSONEventSpec()
+	changed.Structure = []jsonElementDescriptor{
+		{FieldName: "id", Type: "uint16", ByteSize: 2},
+		changed.Structure[1],
+	}
+	changed.WireFormat = 2
+
+	changes := diffEventSpecs([]jsonEventSpec{testJSONEventSpec()}, []jsonEventSpec{changed})
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", changes)
+	}
+	for _, change := range changes {
+		if !change.Breaking {
+			t.Errorf("Expected %q to be breaking", change)
+		}
+	}
+	if unchanged := diffEventSpecs([]jsonEventSpec{testJSONEventSpec()}, []jsonEventSpec{testJSONEventSpec()}); len(unchanged) != 0 {
+		t.Errorf("Expected no changes, got %v", unchanged)
+	}
+}
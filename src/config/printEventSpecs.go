@@ -1,11 +1,13 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 	"unicode"
 
@@ -46,7 +48,9 @@ func writeEventSpecsToTSFile(file *os.File) error {
 	file.WriteString("export enum OriginType {\n")
 	file.WriteString("\tServer = \"server\",\n")
 	file.WriteString("\tOwner = \"owner\",\n")
-	file.WriteString("\tGuest = \"guest\"\n")
+	file.WriteString("\tGuest = \"guest\",\n")
+	file.WriteString("\tModerator = \"moderator\",\n")
+	file.WriteString("\tSpectator = \"spectator\"\n")
 	file.WriteString("};\n\n")
 
 	//Print type enum
@@ -65,7 +69,15 @@ func writeEventSpecsToTSFile(file *os.File) error {
 	file.WriteString("\toffset: number,\n")
 	file.WriteString("\tdescription: string,\n")
 	file.WriteString("\tfieldName: string,\n")
-	file.WriteString(fmt.Sprintf("\ttype: %s\n", nameOfTypeEnum))
+	file.WriteString(fmt.Sprintf("\ttype: %s,\n", nameOfTypeEnum))
+	file.WriteString("\t// Big endian, uint32 for slices and uint16 for strings in wire format 2, or uint32 for strings whose max size doesn't fit a uint16. 0 for everything else\n")
+	file.WriteString("\tlengthPrefixSize: number,\n")
+	file.WriteString("\t// Slices only. Sent as a big endian uint32 element count followed by the elements\n")
+	file.WriteString(fmt.Sprintf("\telemType?: %s,\n", nameOfTypeEnum))
+	file.WriteString("\telemByteSize?: number,\n")
+	file.WriteString("\tmaxLength?: number,\n")
+	file.WriteString("\t// Slices of structs only. Offsets are relative to the start of the element\n")
+	file.WriteString("\telemStructure?: MessageElementDescriptor[]\n")
 	file.WriteString("};\n\n")
 
 	//TS Types - EventSpecification
@@ -74,9 +86,20 @@ func writeEventSpecsToTSFile(file *os.File) error {
 	file.WriteString("\tname: string,\n")
 	file.WriteString("\tpermissions: SendPermissions,\n")
 	file.WriteString("\texpectedMinSize: number\n")
+	file.WriteString("\texpectedMaxSize: number\n")
+	file.WriteString("\t// In wire format 1 only the last element may be of variable size, and strings are not length prefixed.\n")
+	file.WriteString("\t// In wire format 2 offsets past the first variable size element are the least possible, the actual offsets depend on the length prefixes\n")
+	file.WriteString("\twireFormat: number\n")
+	file.WriteString("\t// Changes whenever the structure is serialized differently\n")
+	file.WriteString("\tschemaHash: string\n")
+	file.WriteString("\t// Human readable. Besides the permissions, messages are only handled if all of these hold\n")
+	file.WriteString("\tpolicies: string[]\n")
 	file.WriteString("\tstructure: MessageElementDescriptor[]\n")
 	file.WriteString("};\n\n")
 
+	file.WriteString("// To be sent as the protocolVersion query param when connecting\n")
+	file.WriteString(fmt.Sprintf("export const PROTOCOL_VERSION = \"%s\";\n\n", internal.ProtocolVersion()))
+
 	specs := getOrderedEventSpecs()
 	//Event Type Enum
 	nameOfEventEnum := "EventType"
@@ -115,22 +138,14 @@ func writeEventSpecsToTSFile(file *os.File) error {
 		file.WriteString(fmt.Sprintf("\tname: \"%s\",\n", spec.Name))
 		file.WriteString(fmt.Sprintf("\tpermissions: %s,\n", formatTSSendPermissions(spec.SendPermissions)))
 		file.WriteString(fmt.Sprintf("\texpectedMinSize: %d,\n", spec.ExpectedMinSize))
-		file.WriteString("\tstructure: [\n")
+		file.WriteString(fmt.Sprintf("\texpectedMaxSize: %d,\n", spec.ExpectedMaxSize))
+		file.WriteString(fmt.Sprintf("\twireFormat: %d,\n", spec.WireFormat))
+		file.WriteString(fmt.Sprintf("\tschemaHash: \"%s\",\n", spec.SchemaHash))
+		file.WriteString(fmt.Sprintf("\tpolicies: [%s],\n", formatTSPolicies(spec)))
+		file.WriteString("\tstructure: ")
 		// Message Structure
-		for i, element := range spec.Structure {
-			file.WriteString("\t\t{\n")
-			file.WriteString(fmt.Sprintf("\t\t\tbyteSize: %d,\n", element.ByteSize))
-			file.WriteString(fmt.Sprintf("\t\t\toffset: %d,\n", element.Offset))
-			file.WriteString(fmt.Sprintf("\t\t\tdescription: \"%s\",\n", element.Description))
-			file.WriteString(fmt.Sprintf("\t\t\tfieldName: \"%s\",\n", element.FieldName))
-			file.WriteString(fmt.Sprintf("\t\t\ttype: %s\n", fmt.Sprintf("%s.%s", nameOfTypeEnum, formatTSConstantName(element.Kind.String(), ""))))
-			if i == len(spec.Structure)-1 {
-				file.WriteString("\t\t}\n")
-			} else {
-				file.WriteString("\t\t},\n")
-			}
-		}
-		file.WriteString("\t]\n")
+		writeTSStructure(file, spec.Structure, "\t", nameOfTypeEnum)
+		file.WriteString("\n")
 		file.WriteString("}\n")
 	}
 	file.WriteString("\n")
@@ -147,6 +162,37 @@ func writeEventSpecsToTSFile(file *os.File) error {
 	return nil
 }
 
+// Writes the structure as an array of MessageElementDescriptor, without a trailing newline
+func writeTSStructure(file *os.File, structure internal.ComputedStructure, indent string, nameOfTypeEnum string) {
+	file.WriteString("[\n")
+	for i, element := range structure {
+		file.WriteString(indent + "\t{\n")
+		file.WriteString(fmt.Sprintf("%s\t\tbyteSize: %d,\n", indent, element.ByteSize))
+		file.WriteString(fmt.Sprintf("%s\t\toffset: %d,\n", indent, element.Offset))
+		file.WriteString(fmt.Sprintf("%s\t\tdescription: \"%s\",\n", indent, element.Description))
+		file.WriteString(fmt.Sprintf("%s\t\tfieldName: \"%s\",\n", indent, element.FieldName))
+		file.WriteString(fmt.Sprintf("%s\t\ttype: %s.%s,\n", indent, nameOfTypeEnum, formatTSConstantName(element.Kind.String(), "")))
+		file.WriteString(fmt.Sprintf("%s\t\tlengthPrefixSize: %d", indent, element.LengthPrefixSize))
+		if element.Kind == reflect.Slice {
+			file.WriteString(",\n")
+			file.WriteString(fmt.Sprintf("%s\t\telemType: %s.%s,\n", indent, nameOfTypeEnum, formatTSConstantName(element.ElemKind.String(), "")))
+			file.WriteString(fmt.Sprintf("%s\t\telemByteSize: %d,\n", indent, element.ElemByteSize))
+			file.WriteString(fmt.Sprintf("%s\t\tmaxLength: %d", indent, element.MaxLength))
+			if element.ElemKind == reflect.Struct {
+				file.WriteString(fmt.Sprintf(",\n%s\t\telemStructure: ", indent))
+				writeTSStructure(file, element.ElemStructure, indent+"\t\t", nameOfTypeEnum)
+			}
+		}
+		file.WriteString("\n")
+		if i == len(structure)-1 {
+			file.WriteString(indent + "\t}\n")
+		} else {
+			file.WriteString(indent + "\t},\n")
+		}
+	}
+	file.WriteString(indent + "]")
+}
+
 // Writes a TS type for the message structure of the event
 // Returns the formatted string and the generated type name
 func formatTSTypeForEvent(spec internal.EventSpecification[any], parents []string) (string, string) {
@@ -165,13 +211,7 @@ func formatTSTypeForEvent(spec internal.EventSpecification[any], parents []strin
 	typeName := fmt.Sprintf("%sMessageDTO", spec.Name) //allocated for easier modification
 	var toReturn = fmt.Sprintf("export interface %s %s {\n", typeName, formattedParentExtendsString)
 
-	for _, element := range spec.Structure {
-		tsType := TSTypeOf(element.Kind)
-		toReturn += fmt.Sprintf("\t/** %s\n\t*\n", element.Description)
-		toReturn += fmt.Sprintf("\t* go type: %s\n", element.Kind.String())
-		toReturn += "\t*/\n"
-		toReturn += fmt.Sprintf("\t%s: %s;\n", element.FieldName, tsType)
-	}
+	toReturn += formatTSFields(nestTSFields(spec.Structure), "\t")
 
 	toReturn += "}\n"
 	return toReturn, typeName
@@ -179,35 +219,117 @@ func formatTSTypeForEvent(spec internal.EventSpecification[any], parents []strin
 
 func insertJSDOCCommentDescribingStructure(file *os.File, spec internal.EventSpecification[any]) {
 	file.WriteString(fmt.Sprintf("/** %s Message Structure\n *\n", spec.Name))
+	// Offsets past a variable size element are only known at runtime, so are relative to the end of the previous element
+	pastVariable := false
 	for _, element := range spec.Structure {
 		isVariable := element.ByteSize == 0
+		var from, to = fmt.Sprintf("%db", element.Offset), fmt.Sprintf("%db", element.Offset+element.ByteSize)
+		if pastVariable {
+			from, to = "+0b", fmt.Sprintf("+%db", element.ByteSize)
+		}
 		if isVariable {
-			file.WriteString(fmt.Sprintf(" * *\t%db --> +%sb:\t%-10s:\t%s\n", element.Offset, "N", element.Kind, element.Description))
-		} else {
-			file.WriteString(fmt.Sprintf(" * *\t%db --> %db:\t%-10s:\t%s\n", element.Offset, element.Offset+element.ByteSize, element.Kind, element.Description))
+			to = "+Nb"
+		}
+		file.WriteString(fmt.Sprintf(" * *\t%s --> %s:\t%-10s:\t%s\n", from, to, goTypeNameOf(element), element.Description))
+		pastVariable = pastVariable || isVariable
+		if element.Kind == reflect.String && element.LengthPrefixSize > 0 {
+			file.WriteString(fmt.Sprintf(" * *\t\tuint%d byte count, then up to %d bytes\n", element.LengthPrefixSize*8, element.MaxByteSize-element.LengthPrefixSize))
+		}
+		if element.Kind == reflect.Slice {
+			file.WriteString(fmt.Sprintf(" * *\t\tuint32 element count, then up to %d elements of %db each\n", element.MaxLength, element.ElemByteSize))
+		}
+		for _, field := range element.ElemStructure {
+			file.WriteString(fmt.Sprintf(" * *\t\t+%db --> +%db:\t%-10s:\t%s\n", field.Offset, field.Offset+field.ByteSize, field.Kind, field.Description))
 		}
 	}
 	file.WriteString(" */\n")
 }
 
-func writeEventSpecsToJSONFile(file *os.File) error {
-	file.WriteString("[\n")
-	specs := getOrderedEventSpecs()
-	for index, spec := range specs {
-		file.WriteString("{\n")
-		file.WriteString(fmt.Sprintf("\t\"id\": %d,\n", spec.ID))
-		file.WriteString(fmt.Sprintf("\t\"name\": \"%s\",\n", spec.Name))
-		file.WriteString(fmt.Sprintf("\t\"permissions\": %s,\n", formatJSONSendPermissions(spec.SendPermissions)))
-		file.WriteString(fmt.Sprintf("\t\"expectedMinSize\": %d\n", spec.ExpectedMinSize))
-		if index == len(specs)-1 {
-			file.WriteString("}\n")
-		} else {
-			file.WriteString("},\n")
+type jsonElementDescriptor struct {
+	ByteSize         uint32 `json:"byteSize"`
+	Offset           uint32 `json:"offset"`
+	Description      string `json:"description"`
+	FieldName        string `json:"fieldName"`
+	Type             string `json:"type"`
+	LengthPrefixSize uint32 `json:"lengthPrefixSize"`
+	// Only for strings
+	MaxByteSize   uint32                  `json:"maxByteSize,omitempty"`
+	ElemType      string                  `json:"elemType,omitempty"`
+	ElemByteSize  uint32                  `json:"elemByteSize,omitempty"`
+	MaxLength     uint32                  `json:"maxLength,omitempty"`
+	ElemStructure []jsonElementDescriptor `json:"elemStructure,omitempty"`
+}
+
+type jsonEventSpec struct {
+	ID              uint32                  `json:"id"`
+	Name            string                  `json:"name"`
+	Permissions     map[string]bool         `json:"permissions"`
+	ExpectedMinSize uint32                  `json:"expectedMinSize"`
+	ExpectedMaxSize uint32                  `json:"expectedMaxSize"`
+	WireFormat      uint8                   `json:"wireFormat"`
+	SchemaHash      string                  `json:"schemaHash"`
+	Structure       []jsonElementDescriptor `json:"structure"`
+	// Human readable descriptions of the authorization policies. Not part of the wire layout
+	Policies []string `json:"policies,omitempty"`
+}
+
+type jsonEventSpecs struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Events          []jsonEventSpec `json:"events"`
+}
+
+func toJSONStructure(structure internal.ComputedStructure) []jsonElementDescriptor {
+	result := make([]jsonElementDescriptor, 0, len(structure))
+	for _, element := range structure {
+		descriptor := jsonElementDescriptor{
+			ByteSize:         element.ByteSize,
+			Offset:           element.Offset,
+			Description:      element.Description,
+			FieldName:        element.FieldName,
+			Type:             element.Kind.String(),
+			LengthPrefixSize: element.LengthPrefixSize,
+		}
+		if element.Kind == reflect.String {
+			descriptor.MaxByteSize = element.MaxByteSize
 		}
+		if element.Kind == reflect.Slice {
+			descriptor.ElemType = element.ElemKind.String()
+			descriptor.ElemByteSize = element.ElemByteSize
+			descriptor.MaxLength = element.MaxLength
+			if element.ElemKind == reflect.Struct {
+				descriptor.ElemStructure = toJSONStructure(element.ElemStructure)
+			}
+		}
+		result = append(result, descriptor)
 	}
-	file.WriteString("]\n")
+	return result
+}
 
-	return fmt.Errorf("not implemented")
+func writeEventSpecsToJSONFile(file *os.File) error {
+	asJSON, err := json.MarshalIndent(toJSONEventSpecs(getOrderedEventSpecs()), "", "\t")
+	if err != nil {
+		return fmt.Errorf("error marshalling event specifications: %s", err.Error())
+	}
+	_, err = file.Write(append(asJSON, '\n'))
+	return err
+}
+
+func toJSONEventSpecs(specs []internal.EventSpecification[any]) jsonEventSpecs {
+	result := jsonEventSpecs{ProtocolVersion: internal.ProtocolVersion(), Events: make([]jsonEventSpec, 0, len(specs))}
+	for _, spec := range specs {
+		result.Events = append(result.Events, jsonEventSpec{
+			ID:              spec.ID,
+			Name:            spec.Name,
+			Permissions:     spec.SendPermissions,
+			ExpectedMinSize: spec.ExpectedMinSize,
+			ExpectedMaxSize: spec.ExpectedMaxSize,
+			WireFormat:      uint8(spec.WireFormat),
+			SchemaHash:      spec.SchemaHash,
+			Policies:        describePolicies(spec),
+			Structure:       toJSONStructure(spec.Structure),
+		})
+	}
+	return result
 }
 
 func getOrderedEventSpecs() []internal.EventSpecification[any] {
@@ -225,6 +347,23 @@ func getOrderedEventSpecs() []internal.EventSpecification[any] {
 	return specs
 }
 
+// Nil if the spec has no policies
+func describePolicies(spec internal.EventSpecification[any]) []string {
+	var descriptions []string
+	for _, policy := range spec.Policies {
+		descriptions = append(descriptions, policy.Describe())
+	}
+	return descriptions
+}
+
+func formatTSPolicies(spec internal.EventSpecification[any]) string {
+	quoted := make([]string, 0, len(spec.Policies))
+	for _, description := range describePolicies(spec) {
+		quoted = append(quoted, fmt.Sprintf("%q", description))
+	}
+	return strings.Join(quoted, ", ")
+}
+
 func formatTSSendPermissions(permissions map[internal.OriginType]bool) string {
 	var result = "{"
 	count := 0
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
+)
+
+func TestJSONEventSpecsListPolicies(t *testing.T) {
+	specs := toJSONEventSpecs([]internal.EventSpecification[any]{
+		{ID: 1, Name: "Restricted", Policies: []internal.AuthorizationPolicy{
+			internal.RequireRoles(internal.ORIGIN_TYPE_OWNER),
+			internal.RequireMinigameParticipant(),
+		}},
+		{ID: 2, Name: "Unrestricted"},
+	})
+	expected := []string{"role is one of owner", "client is a minigame participant"}
+	if !reflect.DeepEqual(specs.Events[0].Policies, expected) {
+		t.Errorf("Expected policies %q, got %q", expected, specs.Events[0].Policies)
+	}
+	if specs.Events[1].Policies != nil {
+		t.Errorf("Expected no policies, got %q", specs.Events[1].Policies)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
 )
 
 func FormatTSEnum[T any](name string, data []T, formatter func(T) (string, string)) string {
@@ -32,6 +35,84 @@ func TSTypeOf(kind reflect.Kind) string {
 	}
 }
 
+// PANICS if the kind, or for slices the element kind, is not supported
+func TSTypeOfElement(element internal.MessageElementDescriptor) string {
+	if element.Kind != reflect.Slice {
+		return TSTypeOf(element.Kind)
+	}
+	if element.ElemKind != reflect.Struct {
+		return TSTypeOf(element.ElemKind) + "[]"
+	}
+	return formatTSInlineObject(nestTSFields(element.ElemStructure)) + "[]"
+}
+
+// Field of a generated TS type. Elements with dotted field names, fx. "position.x", are nested back into objects
+type tsField struct {
+	name string
+	// nil for objects
+	element *internal.MessageElementDescriptor
+	fields  []*tsField
+}
+
+func nestTSFields(structure internal.ComputedStructure) []*tsField {
+	var root []*tsField
+	for i := range structure {
+		fields := &root
+		path := strings.Split(structure[i].FieldName, ".")
+		for _, name := range path[:len(path)-1] {
+			// Fields of the same struct are always next to each other
+			if last := len(*fields) - 1; last < 0 || (*fields)[last].name != name || (*fields)[last].element != nil {
+				*fields = append(*fields, &tsField{name: name})
+			}
+			fields = &(*fields)[len(*fields)-1].fields
+		}
+		*fields = append(*fields, &tsField{name: path[len(path)-1], element: &structure[i]})
+	}
+	return root
+}
+
+// Fx. "{ id: number, position: { x: number, y: number } }"
+func formatTSInlineObject(fields []*tsField) string {
+	var formatted = ""
+	for i, field := range fields {
+		if i > 0 {
+			formatted += ", "
+		}
+		if field.element == nil {
+			formatted += fmt.Sprintf("%s: %s", field.name, formatTSInlineObject(field.fields))
+		} else {
+			formatted += fmt.Sprintf("%s: %s", field.name, TSTypeOfElement(*field.element))
+		}
+	}
+	return fmt.Sprintf("{ %s }", formatted)
+}
+
+// Formats the fields as the body of an interface, each with a JSDoc comment
+func formatTSFields(fields []*tsField, indent string) string {
+	var formatted = ""
+	for _, field := range fields {
+		if field.element == nil {
+			formatted += fmt.Sprintf("%s%s: {\n", indent, field.name)
+			formatted += formatTSFields(field.fields, indent+"\t")
+			formatted += fmt.Sprintf("%s};\n", indent)
+			continue
+		}
+		formatted += fmt.Sprintf("%s/** %s\n%s*\n", indent, field.element.Description, indent)
+		formatted += fmt.Sprintf("%s* go type: %s\n", indent, goTypeNameOf(*field.element))
+		formatted += fmt.Sprintf("%s*/\n", indent)
+		formatted += fmt.Sprintf("%s%s: %s;\n", indent, field.name, TSTypeOfElement(*field.element))
+	}
+	return formatted
+}
+
+// Fx. "uint32", "[]float32" or "[]struct"
+func goTypeNameOf(element internal.MessageElementDescriptor) string {
+	if element.Kind == reflect.Slice {
+		return "[]" + element.ElemKind.String()
+	}
+	return element.Kind.String()
+}
+
 func insertRawJSDOCComment(file *os.File, comment string) {
 	file.WriteString(fmt.Sprintf("/**\n * %s\n */\n", comment))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -24,7 +25,7 @@ func applyDevAPI(mux *http.ServeMux, lobbyManager *internal.LobbyManager) error
 			return
 		}
 
-		res := integrations.GetMainBackendIntegration().CloseColony(uint32(colonyIDUint), uint32(ownerIDUint))
+		res := lobbyManager.MainBackend.CloseColony(uint32(colonyIDUint), uint32(ownerIDUint))
 		w.Header().Set("Content-Type", "application/json")
 		if res != nil {
 			http.Error(w, res.Error(), http.StatusInternalServerError)
@@ -32,5 +33,52 @@ func applyDevAPI(mux *http.ServeMux, lobbyManager *internal.LobbyManager) error
 		}
 		w.WriteHeader(http.StatusOK)
 	})
+	mux.HandleFunc("GET "+devAPIRoot+"/outbox", func(w http.ResponseWriter, r *http.Request) {
+		bytes, err := json.Marshal(OutboxResponseDTO{
+			Pending: lobbyManager.Outbox.Pending(),
+			Failed:  lobbyManager.Outbox.Failed(),
+		})
+		if err != nil {
+			http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	})
+	// Only registered if the cache is enabled
+	if cache, ok := lobbyManager.MainBackend.(*integrations.CachingMainBackend); ok {
+		mux.HandleFunc("GET "+devAPIRoot+"/minigame-settings-cache", func(w http.ResponseWriter, r *http.Request) {
+			bytes, err := json.Marshal(cache.Entries())
+			if err != nil {
+				http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(bytes)
+		})
+		mux.HandleFunc("DELETE "+devAPIRoot+"/minigame-settings-cache", func(w http.ResponseWriter, r *http.Request) {
+			cache.InvalidateAll()
+			w.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("DELETE "+devAPIRoot+"/minigame-settings-cache/{minigameID}/{difficultyID}", func(w http.ResponseWriter, r *http.Request) {
+			minigameIDUint, err := strconv.ParseUint(r.PathValue("minigameID"), 10, 32)
+			if err != nil {
+				http.Error(w, "Invalid minigameID", http.StatusBadRequest)
+				return
+			}
+			difficultyIDUint, err := strconv.ParseUint(r.PathValue("difficultyID"), 10, 32)
+			if err != nil {
+				http.Error(w, "Invalid difficultyID", http.StatusBadRequest)
+				return
+			}
+			if !cache.Invalidate(uint32(minigameIDUint), uint32(difficultyIDUint)) {
+				http.Error(w, "Settings not cached", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
 	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
 	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
 )
@@ -8,24 +9,100 @@ import (
 type ClientStateResponseDTO struct {
 	LastKnownPosition uint32 `json:"lastKnownPosition"`
 	MSOfLastMessage   uint64 `json:"msOfLastMessage"`
+	// Milliseconds since the last message from the client, or since it joined if it hasn't sent any
+	IdleMS uint64 `json:"idleMS"`
+}
+
+type ClientSendQueueResponseDTO struct {
+	Length                  int    `json:"length"`
+	Capacity                int    `json:"capacity"`
+	HighWaterMark           uint32 `json:"highWaterMark"`
+	SentMessages            uint64 `json:"sentMessages"`
+	DroppedMessages         uint64 `json:"droppedMessages"`
+	SlowConsumerDisconnects uint32 `json:"slowConsumerDisconnects"`
 }
 
 type ClientResponseDTO struct {
-	ID    uint32                 `json:"id"`
-	IGN   string                 `json:"IGN"`
-	Type  internal.OriginType    `json:"type"`
-	State ClientStateResponseDTO `json:"state"`
+	ID        uint32                     `json:"id"`
+	IGN       string                     `json:"IGN"`
+	Type      internal.OriginType        `json:"type"`
+	Connected bool                       `json:"connected"`
+	State     ClientStateResponseDTO     `json:"state"`
+	SendQueue ClientSendQueueResponseDTO `json:"sendQueue"`
+	// Messages rejected for carrying a sender id other than the clients own
+	SpoofedSenderIDs uint32                     `json:"spoofedSenderIDs"`
+	RateLimit        ClientRateLimitResponseDTO `json:"rateLimit"`
+}
+
+type ClientRateLimitResponseDTO struct {
+	// Messages dropped for exceeding the rate limits
+	ThrottledMessages uint64 `json:"throttledMessages"`
+	// Throttled messages within the current offence window
+	RecentOffences uint32 `json:"recentOffences"`
 }
 
 type LobbyStateResponseDTO struct {
-	ColonyID uint32               `json:"colonyID"`
-	Closing  bool                 `json:"closing"`
-	Phase    internal.LobbyPhase  `json:"phase"`
-	Encoding meta.MessageEncoding `json:"encoding"`
-	Clients  []ClientResponseDTO  `json:"clients"`
+	ColonyID       uint32               `json:"colonyID"`
+	OwnerID        uint32               `json:"ownerID"`
+	Closing        bool                 `json:"closing"`
+	Phase          internal.LobbyPhase  `json:"phase"`
+	Encoding       meta.MessageEncoding `json:"encoding"`
+	PingIntervalMS int64                `json:"pingIntervalMS"`
+	PongTimeoutMS  int64                `json:"pongTimeoutMS"`
+	Clients        []ClientResponseDTO  `json:"clients"`
+	Spectators     []ClientResponseDTO  `json:"spectators"`
+	MaxPlayers     uint32               `json:"maxPlayers"`
+	QueueWhenFull  bool                 `json:"queueWhenFull"`
+	// IDs of the clients waiting to join, in the order they'll be admitted
+	JoinQueue []uint32 `json:"joinQueue"`
 }
 
 type HealthCheckResponseDTO struct {
-	Status     bool   `json:"status"`
-	LobbyCount uint32 `json:"lobbyCount"`
+	Status     bool                        `json:"status"`
+	LobbyCount uint32                      `json:"lobbyCount"`
+	Lifecycle  LifecycleMetricsResponseDTO `json:"lifecycle"`
+	// Of the event specifications, as clients are expected to send when connecting
+	ProtocolVersion string `json:"protocolVersion"`
+	// State of the circuit breaker guarding calls to the main backend: "closed", "open" or "half-open"
+	MainBackendCircuit string `json:"mainBackendCircuit"`
+	// Absent if no webhook endpoints are configured
+	Webhooks *WebhookMetricsResponseDTO `json:"webhooks,omitempty"`
+}
+
+type OutboxResponseDTO struct {
+	// Yet to succeed, oldest first
+	Pending []integrations.OutboxItem `json:"pending"`
+	// Given up on, as the main backend rejected them
+	Failed []integrations.OutboxItem `json:"failed"`
+}
+
+type WebhookMetricsResponseDTO struct {
+	Delivered uint64 `json:"delivered"`
+	Retried   uint64 `json:"retried"`
+	Failed    uint64 `json:"failed"`
+}
+
+// Counted since startup
+type LifecycleMetricsResponseDTO struct {
+	LobbiesCreated   uint64 `json:"lobbiesCreated"`
+	LobbiesClosed    uint64 `json:"lobbiesClosed"`
+	ClientsJoined    uint64 `json:"clientsJoined"`
+	ClientsLeft      uint64 `json:"clientsLeft"`
+	PhaseChanges     uint64 `json:"phaseChanges"`
+	MinigamesStarted uint64 `json:"minigamesStarted"`
+	MinigamesWon     uint64 `json:"minigamesWon"`
+	MinigamesLost    uint64 `json:"minigamesLost"`
+	MinigamesAborted uint64 `json:"minigamesAborted"`
+	// Per subscriber, events dropped because it couldn't keep up
+	DroppedEvents map[string]uint64 `json:"droppedEvents"`
+}
+
+type CreateLobbyResponseDTO struct {
+	ID internal.LobbyID `json:"id"`
+	// Join ticket for the lobby owner, to be passed to /connect
+	Ticket string `json:"ticket"`
+}
+
+type JoinTicketResponseDTO struct {
+	Ticket string `json:"ticket"`
 }
@@ -0,0 +1,97 @@
+package integrations
+
+import (
+	"sync"
+	"time"
+)
+
+type CircuitState string
+
+const (
+	// Calls go through
+	CIRCUIT_STATE_CLOSED CircuitState = "closed"
+	// Calls fail fast until the cooldown has passed
+	CIRCUIT_STATE_OPEN CircuitState = "open"
+	// A single call is let through to probe whether the remote has recovered
+	CIRCUIT_STATE_HALF_OPEN CircuitState = "half-open"
+)
+
+// Opens after a number of consecutive failures, then lets a single probe through once the cooldown has passed.
+// A successful probe closes it again, a failed one reopens it.
+//
+// Threadsafe
+type CircuitBreaker struct {
+	// Consecutive failures before opening. 0 disables the breaker
+	threshold int
+	cooldown  time.Duration
+	lock      sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CIRCUIT_STATE_CLOSED,
+		now:       time.Now,
+	}
+}
+
+// Returns false if the call should fail fast. Every allowed call must be followed by Success or Failure
+func (cb *CircuitBreaker) Allow() bool {
+	if cb.threshold <= 0 {
+		return true
+	}
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	switch cb.state {
+	case CIRCUIT_STATE_OPEN:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = CIRCUIT_STATE_HALF_OPEN
+		cb.probing = true
+		return true
+	case CIRCUIT_STATE_HALF_OPEN:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	}
+	return true
+}
+
+func (cb *CircuitBreaker) Success() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.state = CIRCUIT_STATE_CLOSED
+	cb.failures = 0
+	cb.probing = false
+}
+
+func (cb *CircuitBreaker) Failure() {
+	if cb.threshold <= 0 {
+		return
+	}
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	cb.failures++
+	if cb.state == CIRCUIT_STATE_HALF_OPEN || cb.failures >= cb.threshold {
+		cb.state = CIRCUIT_STATE_OPEN
+		cb.openedAt = cb.now()
+		cb.probing = false
+	}
+}
+
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	return cb.state
+}
@@ -0,0 +1,106 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// An entry of the settings file read by the fake main backend
+type FakeMinigameSettingsEntry struct {
+	MinigameID          uint32          `json:"minigameID"`
+	DifficultyID        uint32          `json:"difficultyID"`
+	Settings            json.RawMessage `json:"settings"`
+	OverwritingSettings json.RawMessage `json:"overwritingSettings"`
+}
+
+type fakeLocationKey struct {
+	colonyID         uint32
+	colonyLocationID uint32
+}
+
+// In memory stand in for the main backend, for tests and offline development.
+// Minigame settings are served from a JSON file, location levels and colony closures only live in memory.
+//
+// Threadsafe
+type FakeMainBackend struct {
+	settings map[minigameSettingsKey]*MBMinigameSettingsDTO
+	// Protects levels and closedColonies
+	lock           sync.Mutex
+	levels         map[fakeLocationKey]uint32
+	closedColonies map[uint32]bool
+}
+
+// The settings file must hold a JSON array of FakeMinigameSettingsEntry.
+// An empty path gives a fake without any minigame settings
+func NewFakeMainBackend(settingsFile string) (*FakeMainBackend, error) {
+	fake := &FakeMainBackend{
+		settings:       make(map[minigameSettingsKey]*MBMinigameSettingsDTO),
+		levels:         make(map[fakeLocationKey]uint32),
+		closedColonies: make(map[uint32]bool),
+	}
+	if settingsFile == "" {
+		return fake, nil
+	}
+
+	fileBytes, err := os.ReadFile(settingsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fake main backend settings file: %s", err.Error())
+	}
+	var entries []FakeMinigameSettingsEntry
+	if err := json.Unmarshal(fileBytes, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing fake main backend settings file %s: %s", settingsFile, err.Error())
+	}
+	for _, entry := range entries {
+		fake.SetMinigameSettings(entry.MinigameID, entry.DifficultyID, &MBMinigameSettingsDTO{
+			Settings:            entry.Settings,
+			OverwritingSettings: entry.OverwritingSettings,
+		})
+	}
+	log.Printf("[main backend] Using fake main backend with %d minigame settings from %s", len(entries), settingsFile)
+	return fake, nil
+}
+
+// Not threadsafe with calls to GetMinigameSettings, so only to be used while setting up
+func (f *FakeMainBackend) SetMinigameSettings(minigameID uint32, difficultyID uint32, settings *MBMinigameSettingsDTO) {
+	f.settings[minigameSettingsKey{minigameID, difficultyID}] = settings
+}
+
+func (f *FakeMainBackend) GetMinigameSettings(minigameID uint32, difficultyID uint32) (*MBMinigameSettingsDTO, error) {
+	settings, exists := f.settings[minigameSettingsKey{minigameID, difficultyID}]
+	if !exists {
+		return nil, fmt.Errorf("%w: no settings for minigame %d at difficulty %d", ErrMainBackendNotFound, minigameID, difficultyID)
+	}
+	return settings, nil
+}
+
+// Every upgrade raises the level of the location by one, starting from level 1
+func (f *FakeMainBackend) UpgradeLocation(colonyID uint32, colLocID uint32) (*UpgradeLocationResponseDTO, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := fakeLocationKey{colonyID, colLocID}
+	level, exists := f.levels[key]
+	if !exists {
+		level = 1
+	}
+	level++
+	f.levels[key] = level
+	return &UpgradeLocationResponseDTO{ColonyLocationID: colLocID, Level: level}, nil
+}
+
+func (f *FakeMainBackend) CloseColony(colonyID uint32, ownerID uint32) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.closedColonies[colonyID] = true
+	return nil
+}
+
+// Whether CloseColony has been called for the colony
+func (f *FakeMainBackend) IsColonyClosed(colonyID uint32) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.closedColonies[colonyID]
+}
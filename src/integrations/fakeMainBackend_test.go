@@ -0,0 +1,51 @@
+package integrations
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFakeMainBackendServesSettingsFile(t *testing.T) {
+	fake, err := NewFakeMainBackend("../../fakeMainBackendSettings.json")
+	if err != nil {
+		t.Fatalf("Error loading settings file: %v", err)
+	}
+
+	settings, err := fake.GetMinigameSettings(1, 1)
+	if err != nil {
+		t.Fatalf("Expected settings for minigame 1 at difficulty 1, got: %v", err)
+	}
+	if len(settings.Settings) == 0 {
+		t.Error("Expected non empty settings")
+	}
+	if _, err := fake.GetMinigameSettings(1, 999); !errors.Is(err, ErrMainBackendNotFound) {
+		t.Errorf("Expected not found error, got: %v", err)
+	}
+}
+
+func TestFakeMainBackendUpgradesAndCloses(t *testing.T) {
+	fake, err := NewFakeMainBackend("")
+	if err != nil {
+		t.Fatalf("Error creating fake: %v", err)
+	}
+
+	for expected := uint32(2); expected <= 3; expected++ {
+		resp, err := fake.UpgradeLocation(1, 7)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resp.ColonyLocationID != 7 || resp.Level != expected {
+			t.Errorf("Expected location 7 at level %d, got %+v", expected, resp)
+		}
+	}
+
+	if fake.IsColonyClosed(1) {
+		t.Error("Expected colony to be open")
+	}
+	if err := fake.CloseColony(1, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !fake.IsColonyClosed(1) {
+		t.Error("Expected colony to be closed")
+	}
+}
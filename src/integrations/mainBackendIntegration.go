@@ -3,27 +3,90 @@ package integrations
 import (
 	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"os"
 	"time"
 )
 
+var (
+	// The main backend responded, but doesn't know the requested resource
+	ErrMainBackendNotFound = errors.New("main backend resource not found")
+	// The main backend could not be reached, timed out or responded with a server error. Worth trying again later
+	ErrMainBackendUnavailable = errors.New("main backend unavailable")
+	// The main backend responded with something unexpected, or something that couldn't be decoded
+	ErrMainBackendBadResponse = errors.New("main backend bad response")
+	// Always wrapped together with ErrMainBackendUnavailable. No request was made
+	ErrCircuitOpen = errors.New("circuit breaker open")
+	// Always wrapped together with ErrMainBackendUnavailable. The request may have reached the main backend and been applied,
+	// fx. it timed out or the main backend responded with a 500
+	ErrMainBackendOutcomeUnknown = errors.New("main backend outcome unknown")
+)
+
+type MainBackendOptions struct {
+	// Of each attempt, from dialing to having read the response
+	Timeout     time.Duration
+	DialTimeout time.Duration
+	// Including the first. Only idempotent calls are retried
+	MaxAttempts int
+	// Wait before the first retry, doubled for each one after. Jittered
+	RetryBackoff time.Duration
+	// Consecutive failed attempts before calls fail fast. 0 disables the circuit breaker
+	BreakerThreshold int
+	// Time calls fail fast before a single one is let through to probe the main backend
+	BreakerCooldown time.Duration
+	// "https" or "http"
+	Scheme string
+	// PEM bundle of CAs trusted besides the system roots. Optional
+	CAFile string
+	// PEM certificate and key presented for mutual TLS. Optional, but both or neither
+	ClientCertFile string
+	ClientKeyFile  string
+	// Dev only. Skips verifying the certificate of the main backend
+	InsecureSkipVerify bool
+}
+
+func DefaultMainBackendOptions() MainBackendOptions {
+	return MainBackendOptions{
+		Timeout:          10 * time.Second,
+		DialTimeout:      5 * time.Second,
+		MaxAttempts:      3,
+		RetryBackoff:     200 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		Scheme:           "https",
+	}
+}
+
+// Calls made to the main backend. Implemented by MainBackendIntegration and FakeMainBackend
+type MainBackend interface {
+	CloseColony(colonyID uint32, ownerID uint32) error
+	UpgradeLocation(colonyID uint32, colLocID uint32) (*UpgradeLocationResponseDTO, error)
+	GetMinigameSettings(minigameID uint32, difficultyID uint32) (*MBMinigameSettingsDTO, error)
+}
+
 type MainBackendIntegration struct {
 	host    string
 	port    int
 	baseURL string
-}
-
-var singleton *MainBackendIntegration
-
-func GetMainBackendIntegration() *MainBackendIntegration {
-	return singleton
+	options MainBackendOptions
+	// Shared by every call so connections are reused
+	client  *http.Client
+	breaker *CircuitBreaker
 }
 
 type MBMinigameSettingsDTO struct {
 	Settings            json.RawMessage `json:"settings"`
 	OverwritingSettings json.RawMessage `json:"overwritingSettings"`
+	// From the ETag header of the response, if any
+	ETag string `json:"-"`
 }
 
 type CloseColonyRequest struct {
@@ -35,33 +98,18 @@ type UpgradeLocationResponseDTO struct {
 	Level            uint32 `json:"level"`
 }
 
+// Not retried, as retrying a request that did arrive would upgrade the location twice
 func (m *MainBackendIntegration) UpgradeLocation(colonyID uint32, colLocID uint32) (*UpgradeLocationResponseDTO, error) {
 	url := fmt.Sprintf(m.baseURL+"/colony/%d/location/%d/upgrade", colonyID, colLocID)
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %s", err.Error())
-	}
-
-	resp, err := getConfiguredClient().Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, headers: %s", resp.StatusCode, resp.Header)
-	}
-
 	var res UpgradeLocationResponseDTO
-	decodeErr := json.NewDecoder(resp.Body).Decode(&res)
-	if decodeErr != nil {
-		return nil, fmt.Errorf("error decoding response: %s", decodeErr.Error())
+	if _, err := m.do(&mainBackendCall{operation: "upgrade location", method: http.MethodPost, url: url, out: &res}); err != nil {
+		return nil, err
 	}
-
 	return &res, nil
 }
 
+// Closing an already closed colony changes nothing, so this is retried
 func (m *MainBackendIntegration) CloseColony(colonyID uint32, ownerID uint32) error {
 	url := fmt.Sprintf(m.baseURL+"/colony/%d/close", colonyID)
 
@@ -74,68 +122,234 @@ func (m *MainBackendIntegration) CloseColony(colonyID uint32, ownerID uint32) er
 		return fmt.Errorf("error marshalling request body: %s", err.Error())
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBodyBytes))
+	_, err = m.do(&mainBackendCall{operation: "close colony", method: http.MethodPost, url: url, body: reqBodyBytes, idempotent: true})
+	return err
+}
+
+func (m *MainBackendIntegration) GetMinigameSettings(minigameID uint32, difficultyID uint32) (*MBMinigameSettingsDTO, error) {
+	settings, _, err := m.GetMinigameSettingsIfChanged(minigameID, difficultyID, "")
+	return settings, err
+}
+
+// Like GetMinigameSettings, but returns notModified and no settings if the settings still match the given ETag.
+// An empty ETag always fetches the settings
+func (m *MainBackendIntegration) GetMinigameSettingsIfChanged(minigameID uint32, difficultyID uint32, etag string) (settings *MBMinigameSettingsDTO, notModified bool, err error) {
+	url := fmt.Sprintf(m.baseURL+"/minigame/minimized?minigame=%d&difficulty=%d", minigameID, difficultyID)
+
+	call := &mainBackendCall{operation: "get minigame settings", method: http.MethodGet, url: url, idempotent: true, header: http.Header{}}
+	if etag != "" {
+		call.header.Set("If-None-Match", etag)
+	}
+	var res MBMinigameSettingsDTO
+	call.out = &res
+	resp, err := m.do(call)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, true, nil
+	}
+	res.ETag = resp.Header.Get("ETag")
+	return &res, false, nil
+}
+
+// State of the circuit breaker guarding calls to the main backend
+func (m *MainBackendIntegration) CircuitState() CircuitState {
+	return m.breaker.State()
+}
+
+type mainBackendCall struct {
+	// Used in errors and logs
+	operation string
+	method    string
+	url       string
+	body      []byte
+	header    http.Header
+	// Only idempotent calls are retried
+	idempotent bool
+	// If not nil, successful responses with a body are decoded into it
+	out any
+}
+
+// Makes the call, retrying it with jittered exponential backoff if it is idempotent and the main backend was unavailable.
+// The returned response is only valid for its status code and headers, the body has been read and closed
+func (m *MainBackendIntegration) do(call *mainBackendCall) (*http.Response, error) {
+	attempts := 1
+	if call.idempotent {
+		attempts = m.options.MaxAttempts
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(m.retryBackoff(attempt - 1))
+		}
+		resp, err = m.attempt(call)
+		if err == nil || !errors.Is(err, ErrMainBackendUnavailable) || errors.Is(err, ErrCircuitOpen) {
+			break
+		}
+		if attempt < attempts {
+			log.Printf("[main backend] Attempt %d of %s failed, retrying: %v", attempt, call.operation, err)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("error creating request: %s", err.Error())
+		return nil, fmt.Errorf("error trying to %s: %w", call.operation, err)
 	}
+	return resp, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
+func (m *MainBackendIntegration) attempt(call *mainBackendCall) (*http.Response, error) {
+	var bodyReader io.Reader
+	if call.body != nil {
+		bodyReader = bytes.NewReader(call.body)
+	}
+	req, err := http.NewRequest(call.method, call.url, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %s", err.Error())
+	}
+	for key, values := range call.header {
+		req.Header[key] = values
+	}
+	if call.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if !m.breaker.Allow() {
+		return nil, fmt.Errorf("%w: %w", ErrMainBackendUnavailable, ErrCircuitOpen)
+	}
 
-	resp, err := getConfiguredClient().Do(req)
+	resp, err := m.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error sending request: %v", err)
+		m.breaker.Failure()
+		// Unless the connection couldn't even be made, the request may have been sent
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("%w: error sending request: %v", ErrMainBackendUnavailable, err)
+		}
+		return nil, fmt.Errorf("%w: %w: error sending request: %v", ErrMainBackendUnavailable, ErrMainBackendOutcomeUnknown, err)
 	}
 	defer resp.Body.Close()
+	// Reading any remainder lets the connection be reused
+	defer io.Copy(io.Discard, resp.Body)
+
+	switch {
+	// Refused before being handled
+	case resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		m.breaker.Failure()
+		return nil, fmt.Errorf("%w: unexpected status code: %d", ErrMainBackendUnavailable, resp.StatusCode)
+	case resp.StatusCode >= 500:
+		m.breaker.Failure()
+		return nil, fmt.Errorf("%w: %w: unexpected status code: %d", ErrMainBackendUnavailable, ErrMainBackendOutcomeUnknown, resp.StatusCode)
+	}
+	// Anything else means the main backend is up, even if the request wasn't to its liking
+	m.breaker.Success()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && call.header.Get("If-None-Match") != "":
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrMainBackendNotFound, req.URL.Path)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%w: unexpected status code: %d", ErrMainBackendBadResponse, resp.StatusCode)
+	}
+
+	if call.out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(call.out); err != nil {
+			return nil, fmt.Errorf("%w: error decoding response: %s", ErrMainBackendBadResponse, err.Error())
+		}
+	}
+	return resp, nil
+}
+
+// Wait before the given retry, randomized between half and all of the backoff
+// so callers failing at the same time don't retry at the same time too
+func (m *MainBackendIntegration) retryBackoff(retry int) time.Duration {
+	backoff := m.options.RetryBackoff << (retry - 1)
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d, headers: %s", resp.StatusCode, resp.Header)
+func newMainBackendTLSConfig(options MainBackendOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+	if options.InsecureSkipVerify {
+		log.Println("[main backend] WARNING: Certificate verification of the main backend is disabled")
+	}
+
+	if options.CAFile != "" {
+		pemBytes, err := os.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading main backend CA file: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Printf("[main backend] Error loading system cert pool, trusting only %s: %v", options.CAFile, err)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("no certificates found in main backend CA file %s", options.CAFile)
+		}
+		config.RootCAs = pool
 	}
 
-	return nil
+	if options.ClientCertFile != "" || options.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading main backend client certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
 }
 
-func getConfiguredClient() *http.Client {
+func newMainBackendClient(options MainBackendOptions) (*http.Client, error) {
+	tlsConfig, err := newMainBackendTLSConfig(options)
+	if err != nil {
+		return nil, err
+	}
 	return &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: options.Timeout,
 		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: options.DialTimeout}).DialContext,
+			TLSHandshakeTimeout: options.DialTimeout,
 			DisableKeepAlives:   false,
 			MaxIdleConns:        10,
 			MaxIdleConnsPerHost: 10,
 			IdleConnTimeout:     30 * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, // This skips certificate verification
-			},
+			TLSClientConfig:     tlsConfig,
 		},
-	}
+	}, nil
 }
 
-func (m *MainBackendIntegration) GetMinigameSettings(minigameID uint32, difficultyID uint32) (*MBMinigameSettingsDTO, error) {
-	url := fmt.Sprintf(m.baseURL+"/minigame/minimized?minigame=%d&difficulty=%d", minigameID, difficultyID)
-
-	resp, err := getConfiguredClient().Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("error getting minigame settings: %s", err.Error())
+func newMainBackendIntegration(baseURL string, options MainBackendOptions) (*MainBackendIntegration, error) {
+	if options.MaxAttempts < 1 {
+		return nil, fmt.Errorf("main backend max attempts must be at least 1, got %d", options.MaxAttempts)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	var res MBMinigameSettingsDTO
-	decodeErr := json.NewDecoder(resp.Body).Decode(&res)
-	if decodeErr != nil {
-		return nil, fmt.Errorf("error decoding response: %s", decodeErr.Error())
+	client, err := newMainBackendClient(options)
+	if err != nil {
+		return nil, err
 	}
-
-	return &res, nil
+	return &MainBackendIntegration{
+		baseURL: baseURL,
+		options: options,
+		client:  client,
+		breaker: NewCircuitBreaker(options.BreakerThreshold, options.BreakerCooldown),
+	}, nil
 }
 
-func InitializeMainBackendIntegration(mbHost string, mbPort int) (*MainBackendIntegration, error) {
-	singleton = &MainBackendIntegration{
-		host:    mbHost,
-		port:    mbPort,
-		baseURL: fmt.Sprintf("https://%s:%d/api/v1", mbHost, mbPort),
+func NewMainBackendIntegration(mbHost string, mbPort int, options MainBackendOptions) (*MainBackendIntegration, error) {
+	if options.Scheme != "https" && options.Scheme != "http" {
+		return nil, fmt.Errorf("invalid main backend scheme \"%s\", expected \"https|http\"", options.Scheme)
+	}
+	integration, err := newMainBackendIntegration(fmt.Sprintf("%s://%s:%d/api/v1", options.Scheme, mbHost, mbPort), options)
+	if err != nil {
+		return nil, err
 	}
-	return singleton, nil
+	integration.host = mbHost
+	integration.port = mbPort
+	return integration, nil
 }
@@ -0,0 +1,206 @@
+package integrations
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Responds with the given status codes in order, then 200 with an empty settings body
+func newMainBackendStub(t *testing.T, statusCodes ...int) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := int(requests.Add(1))
+		if count <= len(statusCodes) {
+			w.WriteHeader(statusCodes[count-1])
+			return
+		}
+		w.Write([]byte(`{"settings":{},"overwritingSettings":{}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestMainBackend(t *testing.T, url string, maxAttempts int, breakerThreshold int) *MainBackendIntegration {
+	options := DefaultMainBackendOptions()
+	options.MaxAttempts = maxAttempts
+	options.RetryBackoff = time.Millisecond
+	options.BreakerThreshold = breakerThreshold
+	options.BreakerCooldown = time.Hour
+	integration, err := newMainBackendIntegration(url, options)
+	if err != nil {
+		t.Fatalf("Error creating main backend integration: %v", err)
+	}
+	return integration
+}
+
+func TestMainBackendRetriesIdempotentCalls(t *testing.T) {
+	server, requests := newMainBackendStub(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+	mb := newTestMainBackend(t, server.URL, 3, 0)
+
+	if _, err := mb.GetMinigameSettings(1, 1); err != nil {
+		t.Fatalf("Expected settings after retrying, got: %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests.Load())
+	}
+}
+
+func TestMainBackendDoesNotRetryUpgrade(t *testing.T) {
+	server, requests := newMainBackendStub(t, http.StatusServiceUnavailable)
+	mb := newTestMainBackend(t, server.URL, 3, 0)
+
+	_, err := mb.UpgradeLocation(1, 1)
+	if !errors.Is(err, ErrMainBackendUnavailable) {
+		t.Errorf("Expected unavailable error, got: %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Expected 1 request, got %d", requests.Load())
+	}
+}
+
+func TestMainBackendOutcomeUnknown(t *testing.T) {
+	server, _ := newMainBackendStub(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusGatewayTimeout)
+	mb := newTestMainBackend(t, server.URL, 1, 0)
+
+	for _, outcomeUnknown := range []bool{false, false, true, true} {
+		_, err := mb.UpgradeLocation(1, 1)
+		if !errors.Is(err, ErrMainBackendUnavailable) || errors.Is(err, ErrMainBackendOutcomeUnknown) != outcomeUnknown {
+			t.Errorf("Expected unavailable error with outcome unknown %t, got: %v", outcomeUnknown, err)
+		}
+	}
+
+	// Nothing listens on the port once the server is closed, so the request is never sent
+	server.Close()
+	mb = newTestMainBackend(t, server.URL, 1, 0)
+	if _, err := mb.UpgradeLocation(1, 1); !errors.Is(err, ErrMainBackendUnavailable) || errors.Is(err, ErrMainBackendOutcomeUnknown) {
+		t.Errorf("Expected unavailable error with a known outcome, got: %v", err)
+	}
+}
+
+func TestMainBackendErrorTypes(t *testing.T) {
+	server, requests := newMainBackendStub(t, http.StatusNotFound, http.StatusBadRequest)
+	mb := newTestMainBackend(t, server.URL, 3, 0)
+
+	if _, err := mb.GetMinigameSettings(1, 1); !errors.Is(err, ErrMainBackendNotFound) {
+		t.Errorf("Expected not found error, got: %v", err)
+	}
+	if _, err := mb.GetMinigameSettings(1, 1); !errors.Is(err, ErrMainBackendBadResponse) {
+		t.Errorf("Expected bad response error, got: %v", err)
+	}
+	// Neither is retried
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+}
+
+func TestMainBackendCircuitBreakerFailsFast(t *testing.T) {
+	server, requests := newMainBackendStub(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	mb := newTestMainBackend(t, server.URL, 3, 2)
+
+	if _, err := mb.GetMinigameSettings(1, 1); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the breaker to open during retries, got: %v", err)
+	}
+	if _, err := mb.GetMinigameSettings(1, 1); !errors.Is(err, ErrMainBackendUnavailable) || !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected fast failure, got: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests.Load())
+	}
+	if mb.CircuitState() != CIRCUIT_STATE_OPEN {
+		t.Errorf("Expected open circuit, got %s", mb.CircuitState())
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Second)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatal("Expected an open breaker to refuse calls")
+	}
+
+	now = now.Add(time.Second)
+	if !breaker.Allow() {
+		t.Fatal("Expected a probe once the cooldown has passed")
+	}
+	if breaker.Allow() {
+		t.Error("Expected only a single probe at a time")
+	}
+	breaker.Failure()
+	if breaker.State() != CIRCUIT_STATE_OPEN || breaker.Allow() {
+		t.Error("Expected a failed probe to reopen the breaker")
+	}
+
+	now = now.Add(time.Second)
+	breaker.Allow()
+	breaker.Success()
+	if breaker.State() != CIRCUIT_STATE_CLOSED || !breaker.Allow() {
+		t.Error("Expected a successful probe to close the breaker")
+	}
+}
+
+func TestMainBackendVerifiesCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"settings":{},"overwritingSettings":{}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("Error writing CA file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(*MainBackendOptions)
+		expectErr bool
+	}{
+		{"untrusted certificate", func(o *MainBackendOptions) {}, true},
+		{"custom CA", func(o *MainBackendOptions) { o.CAFile = caFile }, false},
+		{"skipping verification", func(o *MainBackendOptions) { o.InsecureSkipVerify = true }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultMainBackendOptions()
+			options.MaxAttempts = 1
+			tt.configure(&options)
+			mb, err := newMainBackendIntegration(server.URL, options)
+			if err != nil {
+				t.Fatalf("Error creating main backend integration: %v", err)
+			}
+			_, err = mb.GetMinigameSettings(1, 1)
+			if tt.expectErr && err == nil {
+				t.Error("Expected an error")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMainBackendRejectsBadCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Error writing CA file: %v", err)
+	}
+	options := DefaultMainBackendOptions()
+	options.CAFile = caFile
+	if _, err := newMainBackendIntegration("https://localhost", options); err == nil {
+		t.Error("Expected an error for a CA file without certificates")
+	}
+	options.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := newMainBackendIntegration("https://localhost", options); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+}
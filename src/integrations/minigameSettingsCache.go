@@ -0,0 +1,178 @@
+package integrations
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Implemented by main backends able to tell whether minigame settings changed since they were last fetched
+type ConditionalSettingsSource interface {
+	GetMinigameSettingsIfChanged(minigameID uint32, difficultyID uint32, etag string) (settings *MBMinigameSettingsDTO, notModified bool, err error)
+}
+
+type MinigameSettingsCacheOptions struct {
+	// Time settings are served from the cache before they are revalidated
+	TTL time.Duration
+	// Time past the TTL expired settings are still served if the main backend is unavailable. 0 disables this
+	StaleIfError time.Duration
+}
+
+// Threadsafe counters of cache lookups
+type MinigameSettingsCacheMetrics struct {
+	Hits atomic.Uint64
+	// Not cached, or expired and changed
+	Misses atomic.Uint64
+	// Expired, but unchanged according to the main backend
+	Revalidated atomic.Uint64
+	// Expired and served anyway, as the main backend was unavailable
+	Stale atomic.Uint64
+}
+
+type minigameSettingsKey struct {
+	minigameID   uint32
+	difficultyID uint32
+}
+
+type minigameSettingsCacheEntry struct {
+	settings  *MBMinigameSettingsDTO
+	fetchedAt time.Time
+	// Last time the main backend confirmed the settings, either by sending them or by not modified
+	validatedAt time.Time
+}
+
+// Snapshot of a cache entry
+type MinigameSettingsCacheEntryInfo struct {
+	MinigameID   uint32    `json:"minigameID"`
+	DifficultyID uint32    `json:"difficultyID"`
+	ETag         string    `json:"etag"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	ValidatedAt  time.Time `json:"validatedAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// Wraps a MainBackend, caching minigame settings by minigame and difficulty. Every other call goes straight through.
+//
+// Threadsafe
+type CachingMainBackend struct {
+	MainBackend
+	options MinigameSettingsCacheOptions
+	// Protects entries
+	lock    sync.Mutex
+	entries map[minigameSettingsKey]*minigameSettingsCacheEntry
+	Metrics *MinigameSettingsCacheMetrics
+	now     func() time.Time
+}
+
+func NewCachingMainBackend(inner MainBackend, options MinigameSettingsCacheOptions) *CachingMainBackend {
+	return &CachingMainBackend{
+		MainBackend: inner,
+		options:     options,
+		entries:     make(map[minigameSettingsKey]*minigameSettingsCacheEntry),
+		Metrics:     &MinigameSettingsCacheMetrics{},
+		now:         time.Now,
+	}
+}
+
+// Served from the cache until the TTL expires. Expired settings are revalidated using their ETag if the main backend supports it.
+// If the main backend is unavailable, expired settings are served for up to StaleIfError longer
+func (c *CachingMainBackend) GetMinigameSettings(minigameID uint32, difficultyID uint32) (*MBMinigameSettingsDTO, error) {
+	key := minigameSettingsKey{minigameID, difficultyID}
+	c.lock.Lock()
+	entry, cached := c.entries[key]
+	c.lock.Unlock()
+
+	now := c.now()
+	if cached && now.Sub(entry.validatedAt) < c.options.TTL {
+		c.Metrics.Hits.Add(1)
+		return entry.settings, nil
+	}
+
+	settings, notModified, err := c.fetch(minigameID, difficultyID, entry)
+	switch {
+	case err == nil && notModified:
+		c.Metrics.Revalidated.Add(1)
+		c.store(key, &minigameSettingsCacheEntry{settings: entry.settings, fetchedAt: entry.fetchedAt, validatedAt: now})
+		return entry.settings, nil
+	case err == nil:
+		c.Metrics.Misses.Add(1)
+		c.store(key, &minigameSettingsCacheEntry{settings: settings, fetchedAt: now, validatedAt: now})
+		return settings, nil
+	case cached && errors.Is(err, ErrMainBackendUnavailable) && now.Sub(entry.validatedAt) < c.options.TTL+c.options.StaleIfError:
+		c.Metrics.Stale.Add(1)
+		log.Printf("[main backend] Serving stale settings for minigame %d at difficulty %d: %v", minigameID, difficultyID, err)
+		return entry.settings, nil
+	case errors.Is(err, ErrMainBackendNotFound):
+		// No longer there, so no longer worth serving if the main backend goes down later
+		c.Invalidate(minigameID, difficultyID)
+	}
+	return nil, err
+}
+
+func (c *CachingMainBackend) fetch(minigameID uint32, difficultyID uint32, entry *minigameSettingsCacheEntry) (*MBMinigameSettingsDTO, bool, error) {
+	conditional, ok := c.MainBackend.(ConditionalSettingsSource)
+	if !ok {
+		settings, err := c.MainBackend.GetMinigameSettings(minigameID, difficultyID)
+		return settings, false, err
+	}
+	etag := ""
+	if entry != nil {
+		etag = entry.settings.ETag
+	}
+	return conditional.GetMinigameSettingsIfChanged(minigameID, difficultyID, etag)
+}
+
+func (c *CachingMainBackend) store(key minigameSettingsKey, entry *minigameSettingsCacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = entry
+}
+
+// Returns true if the settings were cached
+func (c *CachingMainBackend) Invalidate(minigameID uint32, difficultyID uint32) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := minigameSettingsKey{minigameID, difficultyID}
+	_, cached := c.entries[key]
+	delete(c.entries, key)
+	return cached
+}
+
+// Returns the amount of settings that were cached
+func (c *CachingMainBackend) InvalidateAll() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	count := len(c.entries)
+	c.entries = make(map[minigameSettingsKey]*minigameSettingsCacheEntry)
+	return count
+}
+
+// Snapshot of every cached entry, ordered by minigame then difficulty
+func (c *CachingMainBackend) Entries() []MinigameSettingsCacheEntryInfo {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	infos := make([]MinigameSettingsCacheEntryInfo, 0, len(c.entries))
+	for key, entry := range c.entries {
+		infos = append(infos, MinigameSettingsCacheEntryInfo{
+			MinigameID:   key.minigameID,
+			DifficultyID: key.difficultyID,
+			ETag:         entry.settings.ETag,
+			FetchedAt:    entry.fetchedAt,
+			ValidatedAt:  entry.validatedAt,
+			ExpiresAt:    entry.validatedAt.Add(c.options.TTL),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].MinigameID != infos[j].MinigameID {
+			return infos[i].MinigameID < infos[j].MinigameID
+		}
+		return infos[i].DifficultyID < infos[j].DifficultyID
+	})
+	return infos
+}
@@ -0,0 +1,119 @@
+package integrations
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Serves settings with the ETag "v1", answering 304 to requests already holding it. Responds with failStatus instead while it is set
+func newETagStub(t *testing.T) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	var requests atomic.Int32
+	var failStatus atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if status := failStatus.Load(); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"settings":{},"overwritingSettings":{}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &failStatus
+}
+
+func newTestCache(t *testing.T, url string) (*CachingMainBackend, *time.Time) {
+	now := time.Now()
+	cache := NewCachingMainBackend(newTestMainBackend(t, url, 1, 0), MinigameSettingsCacheOptions{
+		TTL:          time.Minute,
+		StaleIfError: time.Hour,
+	})
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestMinigameSettingsCacheRevalidates(t *testing.T) {
+	server, requests, _ := newETagStub(t)
+	cache, now := newTestCache(t, server.URL)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetMinigameSettings(1, 1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if requests.Load() != 1 || cache.Metrics.Hits.Load() != 2 {
+		t.Errorf("Expected 1 request and 2 hits, got %d requests and %d hits", requests.Load(), cache.Metrics.Hits.Load())
+	}
+
+	*now = now.Add(2 * time.Minute)
+	settings, err := cache.GetMinigameSettings(1, 1)
+	if err != nil || settings == nil {
+		t.Fatalf("Expected revalidated settings, got %v, %v", settings, err)
+	}
+	if cache.Metrics.Revalidated.Load() != 1 {
+		t.Errorf("Expected 1 revalidation, got %d", cache.Metrics.Revalidated.Load())
+	}
+	if entries := cache.Entries(); len(entries) != 1 || entries[0].ETag != `"v1"` || !entries[0].ValidatedAt.Equal(*now) {
+		t.Errorf("Expected a single entry validated now, got %+v", entries)
+	}
+}
+
+func TestMinigameSettingsCacheServesStaleIfError(t *testing.T) {
+	server, _, failStatus := newETagStub(t)
+	cache, now := newTestCache(t, server.URL)
+
+	if _, err := cache.GetMinigameSettings(1, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	failStatus.Store(http.StatusServiceUnavailable)
+
+	*now = now.Add(30 * time.Minute)
+	if _, err := cache.GetMinigameSettings(1, 1); err != nil {
+		t.Errorf("Expected stale settings, got: %v", err)
+	}
+	if cache.Metrics.Stale.Load() != 1 {
+		t.Errorf("Expected 1 stale lookup, got %d", cache.Metrics.Stale.Load())
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if _, err := cache.GetMinigameSettings(1, 1); !errors.Is(err, ErrMainBackendUnavailable) {
+		t.Errorf("Expected unavailable error once past stale if error, got: %v", err)
+	}
+}
+
+func TestMinigameSettingsCacheInvalidates(t *testing.T) {
+	server, requests, failStatus := newETagStub(t)
+	cache, now := newTestCache(t, server.URL)
+
+	cache.GetMinigameSettings(1, 1)
+	cache.GetMinigameSettings(1, 2)
+	if !cache.Invalidate(1, 1) || cache.Invalidate(1, 1) {
+		t.Error("Expected only the first invalidation to find the settings")
+	}
+	cache.GetMinigameSettings(1, 1)
+	if requests.Load() != 3 {
+		t.Errorf("Expected invalidated settings to be fetched again, got %d requests", requests.Load())
+	}
+	if count := cache.InvalidateAll(); count != 2 {
+		t.Errorf("Expected 2 invalidated settings, got %d", count)
+	}
+
+	// Not found drops the settings rather than serving them stale
+	cache.GetMinigameSettings(1, 1)
+	failStatus.Store(http.StatusNotFound)
+	*now = now.Add(2 * time.Minute)
+	if _, err := cache.GetMinigameSettings(1, 1); !errors.Is(err, ErrMainBackendNotFound) {
+		t.Errorf("Expected not found error, got: %v", err)
+	}
+	if len(cache.Entries()) != 0 {
+		t.Errorf("Expected no entries, got %+v", cache.Entries())
+	}
+}
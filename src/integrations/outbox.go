@@ -0,0 +1,327 @@
+package integrations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type OutboxItemKind string
+
+const (
+	OUTBOX_ITEM_CLOSE_COLONY     OutboxItemKind = "closeColony"
+	OUTBOX_ITEM_UPGRADE_LOCATION OutboxItemKind = "upgradeLocation"
+)
+
+// Failed items kept unless OutboxOptions.MaxFailed says otherwise
+const DEFAULT_OUTBOX_MAX_FAILED = 100
+
+// A call to the main backend that is yet to succeed
+type OutboxItem struct {
+	ID               uint64         `json:"id"`
+	Kind             OutboxItemKind `json:"kind"`
+	ColonyID         uint32         `json:"colonyID"`
+	OwnerID          uint32         `json:"ownerID,omitempty"`
+	ColonyLocationID uint32         `json:"colonyLocationID,omitempty"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	Attempts         int            `json:"attempts"`
+	NextAttemptAt    time.Time      `json:"nextAttemptAt"`
+	LastError        string         `json:"lastError,omitempty"`
+}
+
+type OutboxOptions struct {
+	// File the outbox is kept in between restarts
+	Path string
+	// Wait before the first retry, doubled for each one after
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// Failed items kept for inspection. Past it the oldest are dropped. 0 for DEFAULT_OUTBOX_MAX_FAILED
+	MaxFailed int
+}
+
+// Contents of the outbox file
+type outboxState struct {
+	NextID  uint64        `json:"nextID"`
+	Pending []*OutboxItem `json:"pending"`
+	// Rejected by the main backend for reasons retrying won't fix. Kept for inspection, oldest first
+	Failed []*OutboxItem `json:"failed"`
+}
+
+// File backed queue of calls to the main backend that must not be lost, such as closing colonies and upgrading locations.
+// Calls are retried in the background for as long as the main backend is unavailable, also across restarts.
+// Closing a colony is delivered at least once. Upgrading a location isn't idempotent, so it is only retried if it certainly wasn't applied,
+// and given up on if it may have been, fx. it timed out after reaching the main backend.
+//
+// Threadsafe
+type Outbox struct {
+	mainBackend MainBackend
+	options     OutboxOptions
+	// Protects state and onUpgraded
+	lock  sync.Mutex
+	state outboxState
+	// Not persisted, so lost on restart
+	onUpgraded map[uint64]func(*UpgradeLocationResponseDTO)
+	wake       chan struct{}
+	closing    chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// Loads any items left in the outbox file and starts working through them
+func NewOutbox(mainBackend MainBackend, options OutboxOptions) (*Outbox, error) {
+	if options.Path == "" {
+		return nil, fmt.Errorf("outbox path is empty")
+	}
+	options.MaxBackoff = max(options.MaxBackoff, options.RetryBackoff)
+	if options.MaxFailed <= 0 {
+		options.MaxFailed = DEFAULT_OUTBOX_MAX_FAILED
+	}
+
+	outbox := &Outbox{
+		mainBackend: mainBackend,
+		options:     options,
+		state:       outboxState{NextID: 1, Pending: []*OutboxItem{}, Failed: []*OutboxItem{}},
+		onUpgraded:  make(map[uint64]func(*UpgradeLocationResponseDTO)),
+		wake:        make(chan struct{}, 1),
+		closing:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	fileBytes, err := os.ReadFile(options.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("error reading outbox file: %s", err.Error())
+	default:
+		if err := json.Unmarshal(fileBytes, &outbox.state); err != nil {
+			return nil, fmt.Errorf("error parsing outbox file %s: %s", options.Path, err.Error())
+		}
+		if len(outbox.state.Pending) > 0 {
+			log.Printf("[outbox] Resuming %d pending item(s) from %s", len(outbox.state.Pending), options.Path)
+		}
+		// In case MaxFailed was lowered since
+		outbox.trimFailedLocked()
+	}
+
+	go outbox.run()
+	return outbox, nil
+}
+
+// Queues closing the colony. Returns an error if it couldn't be persisted
+func (o *Outbox) CloseColony(colonyID uint32, ownerID uint32) error {
+	return o.enqueue(&OutboxItem{Kind: OUTBOX_ITEM_CLOSE_COLONY, ColonyID: colonyID, OwnerID: ownerID}, nil)
+}
+
+// Queues upgrading the location. onUpgraded is optional and called once the main backend has upgraded it,
+// unless the process restarted in the meantime. Returns an error if it couldn't be persisted
+func (o *Outbox) UpgradeLocation(colonyID uint32, colLocID uint32, onUpgraded func(*UpgradeLocationResponseDTO)) error {
+	return o.enqueue(&OutboxItem{Kind: OUTBOX_ITEM_UPGRADE_LOCATION, ColonyID: colonyID, ColonyLocationID: colLocID}, onUpgraded)
+}
+
+func (o *Outbox) enqueue(item *OutboxItem, onUpgraded func(*UpgradeLocationResponseDTO)) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	item.ID = o.state.NextID
+	item.CreatedAt = time.Now()
+	item.NextAttemptAt = item.CreatedAt
+	o.state.NextID++
+	o.state.Pending = append(o.state.Pending, item)
+	if err := o.persistLocked(); err != nil {
+		o.state.Pending = o.state.Pending[:len(o.state.Pending)-1]
+		return err
+	}
+	if onUpgraded != nil {
+		o.onUpgraded[item.ID] = onUpgraded
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Copies of the items yet to succeed, oldest first
+func (o *Outbox) Pending() []OutboxItem {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return copyOutboxItems(o.state.Pending)
+}
+
+// Copies of the items given up on, oldest first
+func (o *Outbox) Failed() []OutboxItem {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return copyOutboxItems(o.state.Failed)
+}
+
+// Stops working through the outbox after any current attempt. Pending items stay in the outbox file
+func (o *Outbox) Close() {
+	o.closeOnce.Do(func() { close(o.closing) })
+	<-o.done
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-o.closing:
+			return
+		case <-o.wake:
+		case <-timer.C:
+		}
+
+		for {
+			item := o.nextDue()
+			if item == nil {
+				break
+			}
+			o.attempt(item)
+			select {
+			case <-o.closing:
+				return
+			default:
+			}
+		}
+
+		timer.Stop()
+		if wait, ok := o.untilNextDue(); ok {
+			timer.Reset(wait)
+		}
+	}
+}
+
+// Oldest pending item due for an attempt, if any
+func (o *Outbox) nextDue() *OutboxItem {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	now := time.Now()
+	for _, item := range o.state.Pending {
+		if !item.NextAttemptAt.After(now) {
+			return item
+		}
+	}
+	return nil
+}
+
+func (o *Outbox) untilNextDue() (time.Duration, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if len(o.state.Pending) == 0 {
+		return 0, false
+	}
+	next := o.state.Pending[0].NextAttemptAt
+	for _, item := range o.state.Pending[1:] {
+		if item.NextAttemptAt.Before(next) {
+			next = item.NextAttemptAt
+		}
+	}
+	return max(time.Until(next), 0), true
+}
+
+func (o *Outbox) attempt(item *OutboxItem) {
+	var upgraded *UpgradeLocationResponseDTO
+	var err error
+	switch item.Kind {
+	case OUTBOX_ITEM_CLOSE_COLONY:
+		err = o.mainBackend.CloseColony(item.ColonyID, item.OwnerID)
+	case OUTBOX_ITEM_UPGRADE_LOCATION:
+		upgraded, err = o.mainBackend.UpgradeLocation(item.ColonyID, item.ColonyLocationID)
+	default:
+		err = fmt.Errorf("unknown outbox item kind \"%s\"", item.Kind)
+	}
+
+	o.lock.Lock()
+	item.Attempts++
+	var onUpgraded func(*UpgradeLocationResponseDTO)
+	switch {
+	case err == nil:
+		o.removePendingLocked(item)
+		onUpgraded = o.onUpgraded[item.ID]
+		delete(o.onUpgraded, item.ID)
+	case errors.Is(err, ErrMainBackendUnavailable) && (item.Kind != OUTBOX_ITEM_UPGRADE_LOCATION || !errors.Is(err, ErrMainBackendOutcomeUnknown)):
+		item.LastError = err.Error()
+		item.NextAttemptAt = time.Now().Add(o.backoff(item.Attempts))
+		log.Printf("[outbox] Attempt %d of %s %d failed, retrying at %s: %v", item.Attempts, item.Kind, item.ID, item.NextAttemptAt.Format(time.RFC3339), err)
+	default:
+		item.LastError = err.Error()
+		o.removePendingLocked(item)
+		o.state.Failed = append(o.state.Failed, item)
+		delete(o.onUpgraded, item.ID)
+		log.Printf("[outbox] Giving up on %s %d after %d attempt(s): %v", item.Kind, item.ID, item.Attempts, err)
+		o.trimFailedLocked()
+	}
+	if persistErr := o.persistLocked(); persistErr != nil {
+		log.Printf("[outbox] %v", persistErr)
+	}
+	o.lock.Unlock()
+
+	if onUpgraded != nil && upgraded != nil {
+		onUpgraded(upgraded)
+	}
+}
+
+func (o *Outbox) removePendingLocked(item *OutboxItem) {
+	o.state.Pending = slices.DeleteFunc(o.state.Pending, func(pending *OutboxItem) bool { return pending == item })
+}
+
+// Drops the oldest failed items past MaxFailed, so the outbox file doesn't grow for as long as the main backend keeps rejecting calls
+func (o *Outbox) trimFailedLocked() {
+	excess := len(o.state.Failed) - o.options.MaxFailed
+	if excess <= 0 {
+		return
+	}
+	for _, dropped := range o.state.Failed[:excess] {
+		log.Printf("[outbox] Dropping failed %s %d of colony %d, as only the last %d failed items are kept: %s", dropped.Kind, dropped.ID, dropped.ColonyID, o.options.MaxFailed, dropped.LastError)
+	}
+	o.state.Failed = slices.Delete(o.state.Failed, 0, excess)
+}
+
+// Wait before retrying after the given amount of attempts
+func (o *Outbox) backoff(attempts int) time.Duration {
+	backoff := o.options.RetryBackoff
+	for i := 1; i < attempts && backoff < o.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, o.options.MaxBackoff)
+}
+
+// Writes to a temporary file first, so a crash mid write leaves the previous outbox intact
+func (o *Outbox) persistLocked() error {
+	stateBytes, err := json.MarshalIndent(o.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling outbox: %s", err.Error())
+	}
+	if dir := filepath.Dir(o.options.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("error creating outbox directory: %s", err.Error())
+		}
+	}
+	tmpPath := o.options.Path + ".tmp"
+	if err := os.WriteFile(tmpPath, stateBytes, 0o600); err != nil {
+		return fmt.Errorf("error writing outbox: %s", err.Error())
+	}
+	if err := os.Rename(tmpPath, o.options.Path); err != nil {
+		return fmt.Errorf("error replacing outbox: %s", err.Error())
+	}
+	return nil
+}
+
+func copyOutboxItems(items []*OutboxItem) []OutboxItem {
+	copies := make([]OutboxItem, len(items))
+	for i, item := range items {
+		copies[i] = *item
+	}
+	return copies
+}
@@ -0,0 +1,155 @@
+package integrations
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestOutbox(t *testing.T, mainBackend MainBackend, path string) *Outbox {
+	outbox, err := NewOutbox(mainBackend, OutboxOptions{Path: path, RetryBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Error creating outbox: %v", err)
+	}
+	return outbox
+}
+
+func awaitOutbox(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !condition() {
+		t.Fatal("Timed out waiting for the outbox")
+	}
+}
+
+func TestOutboxDelivers(t *testing.T) {
+	fake, _ := NewFakeMainBackend("")
+	outbox := newTestOutbox(t, fake, filepath.Join(t.TempDir(), "outbox.json"))
+	defer outbox.Close()
+
+	upgraded := make(chan *UpgradeLocationResponseDTO, 1)
+	if err := outbox.UpgradeLocation(1, 7, func(resp *UpgradeLocationResponseDTO) { upgraded <- resp }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := outbox.CloseColony(1, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case resp := <-upgraded:
+		if resp.ColonyLocationID != 7 || resp.Level != 2 {
+			t.Errorf("Expected location 7 at level 2, got %+v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the upgrade")
+	}
+	awaitOutbox(t, func() bool { return fake.IsColonyClosed(1) && len(outbox.Pending()) == 0 })
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	server, requests := newMainBackendStub(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	mainBackend := newTestMainBackend(t, server.URL, 1, 0)
+	path := filepath.Join(t.TempDir(), "outbox.json")
+
+	outbox := newTestOutbox(t, mainBackend, path)
+	if err := outbox.CloseColony(1, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	awaitOutbox(t, func() bool { return requests.Load() >= 1 })
+	outbox.Close()
+
+	pending := outbox.Pending()
+	if len(pending) != 1 || pending[0].Attempts < 1 || pending[0].LastError == "" {
+		t.Fatalf("Expected a single pending item with a failed attempt, got %+v", pending)
+	}
+
+	restarted := newTestOutbox(t, mainBackend, path)
+	defer restarted.Close()
+	if len(restarted.Pending()) == 0 {
+		t.Fatal("Expected the pending item to be loaded from the outbox file")
+	}
+	awaitOutbox(t, func() bool { return len(restarted.Pending()) == 0 })
+	if len(restarted.Failed()) != 0 {
+		t.Errorf("Expected no failed items, got %+v", restarted.Failed())
+	}
+
+	// Ids keep counting from where they left off
+	restarted.CloseColony(2, 2)
+	awaitOutbox(t, func() bool { return len(restarted.Pending()) == 0 })
+	if restarted.state.NextID != 3 {
+		t.Errorf("Expected next id 3, got %d", restarted.state.NextID)
+	}
+}
+
+func TestOutboxGivesUpOnRejection(t *testing.T) {
+	server, _ := newMainBackendStub(t, http.StatusBadRequest)
+	outbox := newTestOutbox(t, newTestMainBackend(t, server.URL, 1, 0), filepath.Join(t.TempDir(), "outbox.json"))
+	defer outbox.Close()
+
+	if err := outbox.UpgradeLocation(1, 7, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	awaitOutbox(t, func() bool { return len(outbox.Failed()) == 1 })
+	if len(outbox.Pending()) != 0 {
+		t.Errorf("Expected no pending items, got %+v", outbox.Pending())
+	}
+}
+
+func TestOutboxCapsFailedItems(t *testing.T) {
+	server, _ := newMainBackendStub(t, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest)
+	mainBackend := newTestMainBackend(t, server.URL, 1, 0)
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	outbox, err := NewOutbox(mainBackend, OutboxOptions{Path: path, RetryBackoff: time.Millisecond, MaxFailed: 2})
+	if err != nil {
+		t.Fatalf("Error creating outbox: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := outbox.UpgradeLocation(1, uint32(i), nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		awaitOutbox(t, func() bool { return len(outbox.Pending()) == 0 })
+	}
+	outbox.Close()
+	failed := outbox.Failed()
+	if len(failed) != 2 || failed[0].ColonyLocationID != 2 || failed[1].ColonyLocationID != 3 {
+		t.Fatalf("Expected only the last 2 failed items to be kept, got %+v", failed)
+	}
+
+	// Also when loaded with a lower cap
+	restarted, err := NewOutbox(mainBackend, OutboxOptions{Path: path, MaxFailed: 1})
+	if err != nil {
+		t.Fatalf("Error creating outbox: %v", err)
+	}
+	defer restarted.Close()
+	if failed := restarted.Failed(); len(failed) != 1 || failed[0].ColonyLocationID != 3 {
+		t.Errorf("Expected only the last failed item to be kept, got %+v", failed)
+	}
+}
+
+// A failed upgrade is only retried if the main backend certainly didn't apply it
+func TestOutboxDoesNotRetryAmbiguousUpgrade(t *testing.T) {
+	server, requests := newMainBackendStub(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+	outbox := newTestOutbox(t, newTestMainBackend(t, server.URL, 1, 0), filepath.Join(t.TempDir(), "outbox.json"))
+	defer outbox.Close()
+
+	if err := outbox.UpgradeLocation(1, 7, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	awaitOutbox(t, func() bool { return len(outbox.Failed()) == 1 })
+	if requests.Load() != 2 {
+		t.Errorf("Expected the 503 to be retried and the 500 not, got %d requests", requests.Load())
+	}
+
+	// Closing a colony twice changes nothing, so it is retried regardless
+	if err := outbox.CloseColony(1, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	awaitOutbox(t, func() bool { return len(outbox.Pending()) == 0 })
+	if len(outbox.Failed()) != 1 {
+		t.Errorf("Expected only the upgrade to have failed, got %+v", outbox.Failed())
+	}
+}
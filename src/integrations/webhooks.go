@@ -0,0 +1,231 @@
+package integrations
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Hex encoded HMAC-SHA256 of the request body, prefixed with "sha256="
+	WEBHOOK_SIGNATURE_HEADER = "X-Webhook-Signature"
+	// Same for every attempt at delivering a payload to an endpoint, so receivers can ignore duplicates
+	WEBHOOK_DELIVERY_HEADER = "X-Webhook-Delivery"
+	WEBHOOK_EVENT_HEADER    = "X-Webhook-Event"
+)
+
+// Amount of deliveries attempted at the same time
+const webhookWorkers = 4
+
+type WebhookOptions struct {
+	URLs []string
+	// Payloads are signed with it. Never logged
+	Secret string
+	// Including the first. At least 1
+	MaxAttempts int
+	// Wait before the first retry, doubled for each one after
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Optional, a client with a 10 second timeout is used otherwise
+	Client *http.Client
+}
+
+// Body of every webhook request
+type WebhookEnvelope struct {
+	Type string `json:"type"`
+	// Unix milliseconds
+	Timestamp int64 `json:"timestamp"`
+	Data      any   `json:"data"`
+}
+
+// Threadsafe counters of webhook deliveries
+type WebhookMetrics struct {
+	Delivered atomic.Uint64
+	Retried   atomic.Uint64
+	// Given up on after a non retryable response or running out of attempts
+	Failed atomic.Uint64
+}
+
+type webhookDelivery struct {
+	id        string
+	url       string
+	eventType string
+	body      []byte
+	attempt   int
+}
+
+// Posts signed JSON payloads to every configured endpoint. Failed deliveries are retried with exponential backoff.
+//
+// Threadsafe
+type WebhookDispatcher struct {
+	options WebhookOptions
+	secret  []byte
+	client  *http.Client
+	queue   chan *webhookDelivery
+	// Protects closed and sending on queue
+	lock    sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+	Metrics *WebhookMetrics
+}
+
+func NewWebhookDispatcher(options WebhookOptions) (*WebhookDispatcher, error) {
+	if len(options.URLs) == 0 {
+		return nil, fmt.Errorf("no webhook urls given")
+	}
+	if options.Secret == "" {
+		return nil, fmt.Errorf("webhook secret is empty")
+	}
+	if options.MaxAttempts < 1 {
+		return nil, fmt.Errorf("webhook max attempts must be at least 1, got %d", options.MaxAttempts)
+	}
+
+	options.MaxBackoff = max(options.MaxBackoff, options.InitialBackoff)
+
+	dispatcher := &WebhookDispatcher{
+		options: options,
+		secret:  []byte(options.Secret),
+		client:  options.Client,
+		queue:   make(chan *webhookDelivery, 1024),
+		Metrics: &WebhookMetrics{},
+	}
+	if dispatcher.client == nil {
+		dispatcher.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		dispatcher.workers.Add(1)
+		go dispatcher.runWorker()
+	}
+	return dispatcher, nil
+}
+
+// Queues the payload for delivery to every endpoint. Never blocks
+func (d *WebhookDispatcher) Send(eventType string, data any) error {
+	body, err := json.Marshal(WebhookEnvelope{
+		Type:      eventType,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling webhook payload: %s", err.Error())
+	}
+
+	for _, url := range d.options.URLs {
+		id, err := newDeliveryID()
+		if err != nil {
+			return err
+		}
+		d.enqueue(&webhookDelivery{id: id, url: url, eventType: eventType, body: body, attempt: 1})
+	}
+	return nil
+}
+
+// Stops accepting payloads and blocks until everything queued has been attempted once more.
+// Retries scheduled for later are given up on
+func (d *WebhookDispatcher) Close() {
+	d.lock.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.lock.Unlock()
+
+	d.workers.Wait()
+}
+
+func (d *WebhookDispatcher) enqueue(delivery *webhookDelivery) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if d.closed {
+		d.Metrics.Failed.Add(1)
+		log.Printf("[webhooks] Dispatcher closed, dropped %s delivery %s to %s", delivery.eventType, delivery.id, delivery.url)
+		return
+	}
+
+	select {
+	case d.queue <- delivery:
+	default:
+		d.Metrics.Failed.Add(1)
+		log.Printf("[webhooks] Queue full, dropped %s delivery %s to %s", delivery.eventType, delivery.id, delivery.url)
+	}
+}
+
+func (d *WebhookDispatcher) runWorker() {
+	defer d.workers.Done()
+	for delivery := range d.queue {
+		retryable, err := d.attempt(delivery)
+		if err == nil {
+			d.Metrics.Delivered.Add(1)
+			continue
+		}
+		if !retryable || delivery.attempt >= d.options.MaxAttempts {
+			d.Metrics.Failed.Add(1)
+			log.Printf("[webhooks] Giving up on %s delivery %s to %s after %d attempt(s): %v", delivery.eventType, delivery.id, delivery.url, delivery.attempt, err)
+			continue
+		}
+
+		backoff := d.backoff(delivery.attempt)
+		log.Printf("[webhooks] Attempt %d of %s delivery %s to %s failed, retrying in %s: %v", delivery.attempt, delivery.eventType, delivery.id, delivery.url, backoff, err)
+		delivery.attempt++
+		d.Metrics.Retried.Add(1)
+		time.AfterFunc(backoff, func() { d.enqueue(delivery) })
+	}
+}
+
+// Returns whether a failed attempt is worth retrying
+func (d *WebhookDispatcher) attempt(delivery *webhookDelivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WEBHOOK_SIGNATURE_HEADER, "sha256="+SignWebhookBody(d.secret, delivery.body))
+	req.Header.Set(WEBHOOK_DELIVERY_HEADER, delivery.id)
+	req.Header.Set(WEBHOOK_EVENT_HEADER, delivery.eventType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+	// Other client errors won't go away by asking again
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// Wait before the given attempt is retried
+func (d *WebhookDispatcher) backoff(attempt int) time.Duration {
+	backoff := d.options.InitialBackoff
+	for i := 1; i < attempt && backoff < d.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.options.MaxBackoff)
+}
+
+// Hex encoded HMAC-SHA256 of the body. Receivers compute the same to verify a payload
+func SignWebhookBody(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryID() (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", fmt.Errorf("error generating delivery id: %s", err.Error())
+	}
+	return hex.EncodeToString(idBytes), nil
+}
@@ -0,0 +1,152 @@
+package integrations
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recievedWebhook struct {
+	headers http.Header
+	body    []byte
+}
+
+// Responds with the given status codes in order, then 200
+func newWebhookReceiver(t *testing.T, statusCodes ...int) (*httptest.Server, func() []recievedWebhook) {
+	var lock sync.Mutex
+	var recieved []recievedWebhook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Error reading webhook body: %v", err)
+		}
+		lock.Lock()
+		recieved = append(recieved, recievedWebhook{headers: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(recieved) <= len(statusCodes) {
+			status = statusCodes[len(recieved)-1]
+		}
+		lock.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []recievedWebhook {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]recievedWebhook{}, recieved...)
+	}
+}
+
+func newTestDispatcher(t *testing.T, url string, maxAttempts int) *WebhookDispatcher {
+	dispatcher, err := NewWebhookDispatcher(WebhookOptions{
+		URLs:           []string{url},
+		Secret:         "test secret",
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Error creating dispatcher: %v", err)
+	}
+	return dispatcher
+}
+
+func awaitWebhooks(t *testing.T, recieved func() []recievedWebhook, count int) []recievedWebhook {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if webhooks := recieved(); len(webhooks) >= count {
+			return webhooks
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d webhook request(s), got %d", count, len(recieved()))
+	return nil
+}
+
+func TestWebhookDeliversSignedPayload(t *testing.T) {
+	server, recieved := newWebhookReceiver(t)
+	dispatcher := newTestDispatcher(t, server.URL, 3)
+
+	if err := dispatcher.Send("lobby.opened", map[string]uint32{"lobbyID": 7}); err != nil {
+		t.Fatalf("Error sending webhook: %v", err)
+	}
+	webhook := awaitWebhooks(t, recieved, 1)[0]
+	dispatcher.Close()
+
+	expectedSignature := "sha256=" + SignWebhookBody([]byte("test secret"), webhook.body)
+	if webhook.headers.Get(WEBHOOK_SIGNATURE_HEADER) != expectedSignature {
+		t.Errorf("Expected signature %s, got %s", expectedSignature, webhook.headers.Get(WEBHOOK_SIGNATURE_HEADER))
+	}
+	if webhook.headers.Get(WEBHOOK_EVENT_HEADER) != "lobby.opened" {
+		t.Errorf("Expected event header lobby.opened, got %s", webhook.headers.Get(WEBHOOK_EVENT_HEADER))
+	}
+
+	var envelope struct {
+		Type string            `json:"type"`
+		Data map[string]uint32 `json:"data"`
+	}
+	if err := json.Unmarshal(webhook.body, &envelope); err != nil {
+		t.Fatalf("Error unmarshalling webhook body: %v", err)
+	}
+	if envelope.Type != "lobby.opened" || envelope.Data["lobbyID"] != 7 {
+		t.Errorf("Unexpected envelope: %+v", envelope)
+	}
+	if dispatcher.Metrics.Delivered.Load() != 1 {
+		t.Errorf("Expected 1 delivered, got %d", dispatcher.Metrics.Delivered.Load())
+	}
+}
+
+func TestWebhookRetriesServerErrors(t *testing.T) {
+	server, recieved := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	dispatcher := newTestDispatcher(t, server.URL, 5)
+
+	dispatcher.Send("lobby.closed", nil)
+	webhooks := awaitWebhooks(t, recieved, 3)
+	dispatcher.Close()
+
+	deliveryID := webhooks[0].headers.Get(WEBHOOK_DELIVERY_HEADER)
+	for i, webhook := range webhooks {
+		if webhook.headers.Get(WEBHOOK_DELIVERY_HEADER) != deliveryID {
+			t.Errorf("Expected attempt %d to carry the same delivery id", i+1)
+		}
+	}
+	if dispatcher.Metrics.Retried.Load() != 2 || dispatcher.Metrics.Delivered.Load() != 1 {
+		t.Errorf("Expected 2 retries and 1 delivery, got %d and %d", dispatcher.Metrics.Retried.Load(), dispatcher.Metrics.Delivered.Load())
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	server, recieved := newWebhookReceiver(t, http.StatusBadRequest)
+	dispatcher := newTestDispatcher(t, server.URL, 5)
+	dispatcher.Send("player.joined", nil)
+	awaitWebhooks(t, recieved, 1)
+	time.Sleep(50 * time.Millisecond)
+	dispatcher.Close()
+	if len(recieved()) != 1 || dispatcher.Metrics.Failed.Load() != 1 {
+		t.Errorf("Expected client errors not to be retried, got %d request(s)", len(recieved()))
+	}
+
+	server, recieved = newWebhookReceiver(t, 500, 500, 500, 500)
+	dispatcher = newTestDispatcher(t, server.URL, 2)
+	dispatcher.Send("player.left", nil)
+	awaitWebhooks(t, recieved, 2)
+	time.Sleep(50 * time.Millisecond)
+	dispatcher.Close()
+	if len(recieved()) != 2 || dispatcher.Metrics.Failed.Load() != 1 {
+		t.Errorf("Expected delivery to stop after max attempts, got %d request(s)", len(recieved()))
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	dispatcher := &WebhookDispatcher{options: WebhookOptions{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, backoff := range expected {
+		if actual := dispatcher.backoff(i + 1); actual != backoff {
+			t.Errorf("Expected backoff %s after attempt %d, got %s", backoff, i+1, actual)
+		}
+	}
+}
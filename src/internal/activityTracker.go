@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"fmt"
 	"log"
 	"sync/atomic"
 
@@ -29,6 +30,23 @@ const (
 	LOBBY_PHASE_IN_MINIGAME
 )
 
+func (phase LobbyPhase) String() string {
+	switch phase {
+	case LOBBY_PHASE_ROAMING_COLONY:
+		return "roaming colony"
+	case LOBBY_PHASE_AWAITING_PARTICIPANTS:
+		return "awaiting participants"
+	case LOBBY_PHASE_PLAYERS_DECLARE_INTENT:
+		return "players declare intent"
+	case LOBBY_PHASE_LOADING_MINIGAME:
+		return "loading minigame"
+	case LOBBY_PHASE_IN_MINIGAME:
+		return "in minigame"
+	default:
+		return fmt.Sprintf("unknown phase %d", uint32(phase))
+	}
+}
+
 // Struct for holding and updating information based on the lobby owners actions
 type ActivityTracker struct {
 	//	Same as MinigameID
@@ -51,6 +69,17 @@ type ActivityTracker struct {
 		participantSum      atomic.Uint32
 		playersToAccountFor util.ConcurrentTypedMap[ClientID, bool]
 	}
+	// Optional, called on every change of phase
+	onPhaseChange func(from LobbyPhase, to LobbyPhase)
+	// Controls of the locked in minigame, loading since lock in. Nil if not locked in
+	prefetch atomic.Pointer[minigamePrefetch]
+}
+
+func (ta *ActivityTracker) setPhase(phase LobbyPhase) {
+	from := LobbyPhase(ta.phase.Swap(uint32(phase)))
+	if from != phase && ta.onPhaseChange != nil {
+		ta.onPhaseChange(from, phase)
+	}
 }
 
 // Returns false if the activity isn't locked in yet, and thus participant registration is not to be done yet
@@ -100,7 +129,7 @@ func (ta *ActivityTracker) LockIn(numPlayersRightNow uint32) bool {
 		return false
 	}
 	ta.lockedIn.Store(true)
-	ta.phase.Store(uint32(LOBBY_PHASE_AWAITING_PARTICIPANTS))
+	ta.setPhase(LOBBY_PHASE_AWAITING_PARTICIPANTS)
 	ta.participantTracker.playersToAccountFor.Store(numPlayersRightNow)
 	return true
 }
@@ -110,7 +139,7 @@ func (ta *ActivityTracker) LockIn(numPlayersRightNow uint32) bool {
 // Returns true if the phase was advanced
 func (ta *ActivityTracker) AdvanceIfAllExpectedParticipantsAreAccountedFor() bool {
 	if ta.participantTracker.playersAccountedFor.Load() >= ta.participantTracker.playersToAccountFor.Load() {
-		ta.phase.Store(uint32(LOBBY_PHASE_PLAYERS_DECLARE_INTENT))
+		ta.setPhase(LOBBY_PHASE_PLAYERS_DECLARE_INTENT)
 		var participantCount uint32 = 0
 		ta.participantTracker.OptIn.Range(func(id ClientID, client *Client) bool {
 			ta.playerReadyTracker.playersToAccountFor.Store(id, false)
@@ -125,6 +154,16 @@ func (ta *ActivityTracker) AdvanceIfAllExpectedParticipantsAreAccountedFor() boo
 	return false
 }
 
+// IDs of the clients that opted in to the locked in activity
+func (ta *ActivityTracker) Participants() []ClientID {
+	participants := make([]ClientID, 0)
+	ta.participantTracker.OptIn.Range(func(id ClientID, client *Client) bool {
+		participants = append(participants, id)
+		return true
+	})
+	return participants
+}
+
 func (ta *ActivityTracker) MarkPlayerAsReady(client *Client) {
 	if prevVal, exists := ta.playerReadyTracker.playersToAccountFor.Swap(client.ID, true); exists && !prevVal {
 		ta.playerReadyTracker.playersAccountedFor.Add(1)
@@ -134,7 +173,7 @@ func (ta *ActivityTracker) MarkPlayerAsReady(client *Client) {
 // Returns true if the phase was advanced
 func (ta *ActivityTracker) AdvanceIfAllPlayersAreReady() bool {
 	if ta.playerReadyTracker.playersAccountedFor.Load() >= ta.playerReadyTracker.participantSum.Load() {
-		ta.phase.Store(uint32(LOBBY_PHASE_LOADING_MINIGAME))
+		ta.setPhase(LOBBY_PHASE_LOADING_MINIGAME)
 		log.Println("Going to in loading minigame phase")
 		return true
 	}
@@ -149,7 +188,7 @@ func (ta *ActivityTracker) MarkPlayerAsLoadComplete(client *Client) {
 
 func (ta *ActivityTracker) AdvanceIfAllPlayersHaveLoadedIn() bool {
 	if ta.playerLoadCompleteTracker.playersAccountedFor.Load() >= ta.playerLoadCompleteTracker.participantSum.Load() {
-		ta.phase.Store(uint32(LOBBY_PHASE_IN_MINIGAME))
+		ta.setPhase(LOBBY_PHASE_IN_MINIGAME)
 		log.Println("Going to in minigame phase")
 		return true
 	}
@@ -170,11 +209,12 @@ func (ta *ActivityTracker) ReleaseLock() error {
 // Reset all tracked fields
 func (ta *ActivityTracker) Reset() error {
 	ta.diffConfirmed.Set(nil)
+	ta.prefetch.Store(nil)
 	ta.participantTracker.OptIn.Clear()
 	ta.participantTracker.OptOut.Clear()
 	ta.participantTracker.playersAccountedFor.Store(0)
 	ta.participantTracker.playersToAccountFor.Store(0)
-	ta.phase.Store(uint32(LOBBY_PHASE_ROAMING_COLONY))
+	ta.setPhase(LOBBY_PHASE_ROAMING_COLONY)
 	ta.playerReadyTracker.playersAccountedFor.Store(0)
 	ta.playerReadyTracker.participantSum.Store(0)
 	ta.playerReadyTracker.playersToAccountFor.Clear()
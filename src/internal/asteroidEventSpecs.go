@@ -7,7 +7,7 @@ type AsteroidSpawnMessageDTO struct {
 	Health          uint8   `json:"health" comment:"Asteroid Health"`
 	TimeUntilImpact uint32  `json:"timeUntilImpact" comment:"Time until impact in milliseconds"`
 	Type            uint8   `json:"type" comment:"Asteroid Type (not in use)"`
-	CharCode        string  `json:"charCode" comment:"Sequence of Letters to be pressed to shoot at this asteroid"`
+	CharCode        string  `json:"charCode" comment:"Sequence of Letters to be pressed to shoot at this asteroid" maxSize:"32"`
 }
 
 var ASTEROID_SPAWN_EVENT = NewSpecification[AsteroidSpawnMessageDTO](3000, "AsteroidsAsteroidSpawn", "Sent when the server spawns a new asteroid",
@@ -18,7 +18,7 @@ type AssignPlayerDataMessageDTO struct {
 	X        float32 `json:"x" comment:"X Position, relative 0-1 value to be multiplied with viewport width"`
 	Y        float32 `json:"y" comment:"Y Position, relative 0-1 value to be multiplied with viewport height"`
 	TankType uint8   `json:"type" comment:"Tank Type (not in use)"`
-	CharCode string  `json:"code" comment:"Sequence of Letters to be pressed to accidentally shoot at this player"`
+	CharCode string  `json:"code" comment:"Sequence of Letters to be pressed to accidentally shoot at this player" maxSize:"32"`
 }
 
 //AssignPlayerDataEvent
@@ -36,12 +36,13 @@ var ASTEROID_IMPACT_EVENT = NewSpecification[AsteroidImpactOnColonyMessageDTO](3
 
 type PlayerShootAtCodeMessageDTO struct {
 	PlayerID uint32 `json:"id" comment:"Player ID"`
-	CharCode string `json:"code" comment:"What char combination the player shot at"`
+	CharCode string `json:"code" comment:"What char combination the player shot at" maxSize:"32"`
 }
 
 //PlayerShootAtCodeEvent
 var PLAYER_SHOOT_EVENT = NewSpecification[PlayerShootAtCodeMessageDTO](3003, "AsteroidsPlayerShootAtCode", "Sent when any player shoots at some char combination (code)",
-	OWNER_AND_GUESTS, Handlers_NoCheckReplicate)
+	OWNER_AND_GUESTS, Handlers_NoCheckReplicate).WithRateLimit(5, 10).
+	WithPolicies(AllOf(RequirePhase(LOBBY_PHASE_IN_MINIGAME), RequireMinigameParticipant()))
 
 type AsteroidsPenaltyType = string
 
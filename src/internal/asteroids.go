@@ -6,6 +6,7 @@ import (
 	"log"
 	"math"
 	"math/rand/v2"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -311,21 +312,23 @@ func (amc *AsteroidsMinigameControls) onPlayerShot(msg *PlayerShootAtCodeMessage
 func (amc *AsteroidsMinigameControls) onFallingEdge() error {
 	log.Println("Asteroids on falling edge for lobby id: ", amc.lobby.ID)
 	if (*amc.state).Load() == uint32(MINIGAME_STATE_VICTORY) {
-		//Ask main backend to upgrade location
-		resp, err := integrations.GetMainBackendIntegration().UpgradeLocation(amc.lobby.ColonyID, amc.difficultyInfo.ColonyLocationID)
-		if err != nil {
-			return fmt.Errorf("error upgrading location: %s", err.Error())
-		}
-		//Send location upgrade event
-		data := LocationUpgradeMessageDTO{
-			ColonyLocationID: amc.difficultyInfo.ColonyLocationID,
-			Level:            resp.Level,
-		}
-		serialized, err := Serialize(LOCATION_UPGRADE_EVENT, data)
+		//Ask main backend to upgrade location. Through the outbox, so the upgrade isn't lost if the main backend is down
+		err := amc.lobby.outbox.UpgradeLocation(amc.lobby.ColonyID, amc.difficultyInfo.ColonyLocationID, func(resp *integrations.UpgradeLocationResponseDTO) {
+			//Send location upgrade event
+			data := LocationUpgradeMessageDTO{
+				ColonyLocationID: amc.difficultyInfo.ColonyLocationID,
+				Level:            resp.Level,
+			}
+			serialized, err := Serialize(LOCATION_UPGRADE_EVENT, data)
+			if err != nil {
+				log.Printf("Error serializing location upgrade event: %s\n", err.Error())
+				return
+			}
+			amc.lobby.BroadcastMessage(SERVER_ID, serialized)
+		})
 		if err != nil {
-			return fmt.Errorf("error serializing location upgrade event: %s", err.Error())
+			return fmt.Errorf("error queueing location upgrade: %s", err.Error())
 		}
-		amc.lobby.BroadcastMessage(SERVER_ID, serialized)
 	}
 	return nil
 }
@@ -345,26 +348,16 @@ func (amc *AsteroidsMinigameControls) onMessage(msg *MessageEntry) error {
 }
 
 func GetAsteroidMinigameControls(diff *DifficultyConfirmedForMinigameMessageDTO, lobby *Lobby, onDismount func()) (*GenericMinigameControls, error) {
-	rawSettings, err := integrations.GetMainBackendIntegration().GetMinigameSettings(1, diff.DifficultyID)
+	rawSettings, err := lobby.mainBackend.GetMinigameSettings(1, diff.DifficultyID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get minigame settings: %s", err.Error())
-	}
-
-	// Parse base settings
-	var baseSettings AsteroidSettingsDTO
-	if err := json.Unmarshal(rawSettings.Settings, &baseSettings); err != nil {
-		return nil, fmt.Errorf("error unmarshaling base settings: %s", err.Error())
+		return nil, fmt.Errorf("failed to get minigame settings: %w", err)
 	}
-
-	// If there are overwriting settings, apply them
-	if len(rawSettings.OverwritingSettings) > 0 {
-		var overwriteSettings AsteroidSettingsDTO
-		if err := json.Unmarshal(rawSettings.OverwritingSettings, &overwriteSettings); err != nil {
-			return nil, fmt.Errorf("error unmarshaling overwriting settings: %s", err.Error())
-		}
-
-		mergeSettings(&baseSettings, &overwriteSettings)
+	settings, err := asteroidSettings.parse(diff.DifficultyID, rawSettings)
+	if err != nil {
+		return nil, err
 	}
+	// Copied, so no minigame shares its settings with the cache
+	baseSettings := *settings
 
 	// Todo update char set based on language from diff (diff also needs new field languageReferenceID)
 	generator, err := util.NewCharCodePool(100, baseSettings.CharCodeLength, util.SymbolSets.English.Lowercase)
@@ -397,6 +390,59 @@ func GetAsteroidMinigameControls(diff *DifficultyConfirmedForMinigameMessageDTO,
 	}, nil
 }
 
+// Asteroid settings as parsed and merged from what the main backend responded with, per difficulty.
+// Reused for as long as the main backend responds with the same ETag, so settings are only parsed again once they change.
+//
+// Threadsafe
+type asteroidSettingsCache struct {
+	lock sync.Mutex
+	// By difficulty id. Only the settings of the latest ETag are kept
+	entries map[uint32]asteroidSettingsCacheEntry
+}
+
+type asteroidSettingsCacheEntry struct {
+	etag     string
+	settings *AsteroidSettingsDTO
+}
+
+var asteroidSettings = &asteroidSettingsCache{entries: make(map[uint32]asteroidSettingsCacheEntry)}
+
+// Returns the merged settings. Never to be modified, as they may be shared.
+// Settings without an ETag can't be told apart from changed ones, so they are parsed every time
+func (c *asteroidSettingsCache) parse(difficultyID uint32, rawSettings *integrations.MBMinigameSettingsDTO) (*AsteroidSettingsDTO, error) {
+	if rawSettings.ETag != "" {
+		c.lock.Lock()
+		entry, cached := c.entries[difficultyID]
+		c.lock.Unlock()
+		if cached && entry.etag == rawSettings.ETag {
+			return entry.settings, nil
+		}
+	}
+
+	// Parse base settings
+	var baseSettings AsteroidSettingsDTO
+	if err := json.Unmarshal(rawSettings.Settings, &baseSettings); err != nil {
+		return nil, fmt.Errorf("error unmarshaling base settings: %s", err.Error())
+	}
+
+	// If there are overwriting settings, apply them
+	if len(rawSettings.OverwritingSettings) > 0 {
+		var overwriteSettings AsteroidSettingsDTO
+		if err := json.Unmarshal(rawSettings.OverwritingSettings, &overwriteSettings); err != nil {
+			return nil, fmt.Errorf("error unmarshaling overwriting settings: %s", err.Error())
+		}
+
+		mergeSettings(&baseSettings, &overwriteSettings)
+	}
+
+	if rawSettings.ETag != "" {
+		c.lock.Lock()
+		c.entries[difficultyID] = asteroidSettingsCacheEntry{etag: rawSettings.ETag, settings: &baseSettings}
+		c.lock.Unlock()
+	}
+	return &baseSettings, nil
+}
+
 // mergeSettings applies non-zero values from src to dst
 func mergeSettings(dst *AsteroidSettingsDTO, src *AsteroidSettingsDTO) {
 	if src.MinTimeTillImpactS != 0 {
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Everything an AuthorizationPolicy may base its decision on
+type AuthorizationContext struct {
+	Lobby  *Lobby
+	Client *Client
+	Phase  LobbyPhase
+}
+
+func NewAuthorizationContext(lobby *Lobby, client *Client) *AuthorizationContext {
+	return &AuthorizationContext{
+		Lobby:  lobby,
+		Client: client,
+		Phase:  LobbyPhase(lobby.GetPhase()),
+	}
+}
+
+// Whether the client has opted in to the currently locked in activity
+func (ctx *AuthorizationContext) IsMinigameParticipant() bool {
+	_, isParticipant := ctx.Lobby.activityTracker.participantTracker.OptIn.Load(ctx.Client.ID)
+	return isParticipant
+}
+
+// Evaluated for each message in the read loop, after the SendPermissions of the spec has been checked
+// and before the handler runs
+type AuthorizationPolicy interface {
+	// Returns nil if the client may send the message, otherwise an error describing why not
+	Authorize(ctx *AuthorizationContext) error
+	// Human readable, appears in generated specifications
+	Describe() string
+}
+
+// Attaches policies which must all be satisfied for a message to be handled
+func (eSpec *EventSpecification[T]) WithPolicies(policies ...AuthorizationPolicy) *EventSpecification[T] {
+	eSpec.Policies = append(eSpec.Policies, policies...)
+	return eSpec
+}
+
+// Returns the error of the first policy not satisfied, if any
+func (eSpec *EventSpecification[T]) Authorize(ctx *AuthorizationContext) error {
+	for _, policy := range eSpec.Policies {
+		if err := policy.Authorize(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type rolePolicy struct {
+	roles []OriginType
+}
+
+// Satisfied if the client has any of the roles
+func RequireRoles(roles ...OriginType) AuthorizationPolicy {
+	return &rolePolicy{roles: roles}
+}
+
+func (p *rolePolicy) Authorize(ctx *AuthorizationContext) error {
+	if slices.Contains(p.roles, ctx.Client.Type()) {
+		return nil
+	}
+	return fmt.Errorf("role %s is not one of %s", ctx.Client.Type(), strings.Join(p.roles, ", "))
+}
+
+func (p *rolePolicy) Describe() string {
+	return "role is one of " + strings.Join(p.roles, ", ")
+}
+
+type phasePolicy struct {
+	phases []LobbyPhase
+}
+
+// Satisfied if the lobby is in any of the phases
+func RequirePhase(phases ...LobbyPhase) AuthorizationPolicy {
+	return &phasePolicy{phases: phases}
+}
+
+func (p *phasePolicy) Authorize(ctx *AuthorizationContext) error {
+	if slices.Contains(p.phases, ctx.Phase) {
+		return nil
+	}
+	return fmt.Errorf("lobby phase %s is not one of %s", ctx.Phase, p.joinPhases())
+}
+
+func (p *phasePolicy) Describe() string {
+	return "lobby phase is one of " + p.joinPhases()
+}
+
+func (p *phasePolicy) joinPhases() string {
+	names := make([]string, len(p.phases))
+	for i, phase := range p.phases {
+		names[i] = phase.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+type minigameParticipantPolicy struct{}
+
+// Satisfied if the client has opted in to the currently locked in activity
+func RequireMinigameParticipant() AuthorizationPolicy {
+	return &minigameParticipantPolicy{}
+}
+
+func (p *minigameParticipantPolicy) Authorize(ctx *AuthorizationContext) error {
+	if ctx.IsMinigameParticipant() {
+		return nil
+	}
+	return fmt.Errorf("client %d is not a participant of the current minigame", ctx.Client.ID)
+}
+
+func (p *minigameParticipantPolicy) Describe() string {
+	return "client is a minigame participant"
+}
+
+type allOfPolicy struct {
+	policies []AuthorizationPolicy
+}
+
+// Satisfied if all the policies are
+func AllOf(policies ...AuthorizationPolicy) AuthorizationPolicy {
+	return &allOfPolicy{policies: policies}
+}
+
+func (p *allOfPolicy) Authorize(ctx *AuthorizationContext) error {
+	for _, policy := range p.policies {
+		if err := policy.Authorize(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *allOfPolicy) Describe() string {
+	descriptions := make([]string, len(p.policies))
+	for i, policy := range p.policies {
+		descriptions[i] = policy.Describe()
+	}
+	return "(" + strings.Join(descriptions, " and ") + ")"
+}
@@ -0,0 +1,64 @@
+package internal
+
+import "testing"
+
+func newTestAuthorizationContext(clientType OriginType, phase LobbyPhase) *AuthorizationContext {
+	lobby := &Lobby{activityTracker: NewActivityTracker()}
+	client := &Client{ID: 1}
+	client.setType(clientType)
+	return &AuthorizationContext{Lobby: lobby, Client: client, Phase: phase}
+}
+
+func TestRequireRoles(t *testing.T) {
+	policy := RequireRoles(ORIGIN_TYPE_OWNER, ORIGIN_TYPE_MODERATOR)
+
+	if err := policy.Authorize(newTestAuthorizationContext(ORIGIN_TYPE_MODERATOR, LOBBY_PHASE_ROAMING_COLONY)); err != nil {
+		t.Errorf("Expected moderator to be authorized, got %v", err)
+	}
+	if err := policy.Authorize(newTestAuthorizationContext(ORIGIN_TYPE_GUEST, LOBBY_PHASE_ROAMING_COLONY)); err == nil {
+		t.Error("Expected guest to be denied")
+	}
+}
+
+func TestRequirePhase(t *testing.T) {
+	policy := RequirePhase(LOBBY_PHASE_IN_MINIGAME)
+
+	if err := policy.Authorize(newTestAuthorizationContext(ORIGIN_TYPE_GUEST, LOBBY_PHASE_IN_MINIGAME)); err != nil {
+		t.Errorf("Expected message during minigame to be authorized, got %v", err)
+	}
+	if err := policy.Authorize(newTestAuthorizationContext(ORIGIN_TYPE_GUEST, LOBBY_PHASE_ROAMING_COLONY)); err == nil {
+		t.Error("Expected message while roaming colony to be denied")
+	}
+}
+
+func TestRequireMinigameParticipantAllOf(t *testing.T) {
+	policy := AllOf(RequirePhase(LOBBY_PHASE_IN_MINIGAME), RequireMinigameParticipant())
+	ctx := newTestAuthorizationContext(ORIGIN_TYPE_GUEST, LOBBY_PHASE_IN_MINIGAME)
+
+	if err := policy.Authorize(ctx); err == nil {
+		t.Error("Expected non participant to be denied")
+	}
+
+	ctx.Lobby.activityTracker.participantTracker.OptIn.Store(ctx.Client.ID, ctx.Client)
+	if err := policy.Authorize(ctx); err != nil {
+		t.Errorf("Expected participant to be authorized, got %v", err)
+	}
+
+	ctx.Phase = LOBBY_PHASE_LOADING_MINIGAME
+	if err := policy.Authorize(ctx); err == nil {
+		t.Error("Expected participant to be denied outside of the minigame phase")
+	}
+}
+
+func TestSpecificationAuthorize(t *testing.T) {
+	spec := &EventSpecification[any]{}
+	ctx := newTestAuthorizationContext(ORIGIN_TYPE_GUEST, LOBBY_PHASE_ROAMING_COLONY)
+	if err := spec.Authorize(ctx); err != nil {
+		t.Errorf("Expected spec without policies to authorize anyone, got %v", err)
+	}
+
+	spec.WithPolicies(RequireRoles(ORIGIN_TYPE_OWNER))
+	if err := spec.Authorize(ctx); err == nil {
+		t.Error("Expected guest to be denied by the attached policy")
+	}
+}
@@ -1,10 +1,17 @@
 package internal
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
 	"github.com/lilybw/bsc-multiplayer-backend/src/util"
@@ -46,20 +53,76 @@ func (dcs *GeneralDisclosedClientState) UpdateAny(messageID MessageID, remainder
 	dcs.MSOfLastMessage.Store(nowInMS)
 }
 
+var ErrClientDisconnected = errors.New("client is disconnected")
+var ErrSendQueueFull = errors.New("client send queue is full")
+
+// A message waiting to be written to the client, already encoded and prepended with the sender id
+type bufferedMessage struct {
+	messageType int
+	data        []byte
+}
+
+// Threadsafe counters describing the back-pressure on a clients send queue
+type ClientSendMetrics struct {
+	SentMessages atomic.Uint64
+	// Messages dropped as the send queue was full
+	DroppedMessages atomic.Uint64
+	// Highest queue length observed
+	QueueHighWaterMark atomic.Uint32
+	// Times the connection was closed for being too slow
+	SlowConsumerDisconnects atomic.Uint32
+}
+
 // Client represents a user connected to a lobby
 type Client struct {
 	ID      ClientID
 	IDBytes []byte
 	IGN     string
-	Type    OriginType
+	// Holds an OriginType. Changes if the client is promoted to owner
+	clientType atomic.Value
+	// When the client first joined the lobby. Unaffected by resuming
+	JoinedAt time.Time
 	//Updated in sync with processing of this clients messages
 	State    *GeneralDisclosedClientState
 	Encoding meta.MessageEncoding
-	Conn     *websocket.Conn
+	// Replaced when the session is resumed. Use only through the methods on Client
+	Conn *websocket.Conn
+	// Issued on join and replaced on each resume. Required to resume this session on a new connection
+	resumeToken string
+	// Threadsafe, false while the client is in its reconnect grace period
+	Connected atomic.Bool
+	// Threadsafe, true while the client waits in the join queue of a full lobby
+	Queued      atomic.Bool
+	SendMetrics *ClientSendMetrics
+	// Threadsafe, amount of messages rejected for carrying a sender id other than this clients own
+	SpoofedSenderIDs atomic.Uint32
+	RateLimiter      *ClientRateLimiter
+	// Drained by the writer routine of the current connection. The only route by which messages are written
+	sendQueue          chan bufferedMessage
+	writeTimeout       time.Duration
+	slowConsumerPolicy meta.SlowConsumerPolicy
+	// Incremented each time the connection is replaced
+	connectionGeneration uint32
+	// Of the current connection. Nil until started
+	writer *connectionWriter
+	// Protects Conn, connectionGeneration, writer, resumeToken, missedMessages and pendingResend
+	connLock sync.Mutex
+	// Broadcasts the client didn't recieve while disconnected, oldest first
+	missedMessages    []bufferedMessage
+	missedMessagesCap int
+	// Missed messages to be written by the writer of a resumed connection before anything else
+	pendingResend []bufferedMessage
+	// Whether the current connection has been closed as per the slow consumer policy
+	closedAsSlowConsumer bool
+}
+
+// Spectators recieve broadcasts, but don't count as players and aren't announced to other clients
+func (c *Client) IsSpectator() bool {
+	return c.Type() == ORIGIN_TYPE_SPECTATOR
 }
 
 func (c *Client) String() string {
-	return fmt.Sprintf("%d (%s) %s encoding: %s", c.ID, c.IGN, c.Type, c.Encoding)
+	return fmt.Sprintf("%d (%s) %s encoding: %s", c.ID, c.IGN, c.Type(), c.Encoding)
 }
 
 func NewDisclosedClientState() *GeneralDisclosedClientState {
@@ -69,14 +132,349 @@ func NewDisclosedClientState() *GeneralDisclosedClientState {
 	}
 }
 
-func NewClient(id ClientID, IGN string, clientType OriginType, conn *websocket.Conn, encoding meta.MessageEncoding) *Client {
-	return &Client{
-		ID:       id,
-		IDBytes:  util.BytesOfUint32(id),
-		IGN:      IGN,
-		Type:     clientType,
-		Conn:     conn,
-		Encoding: encoding,
-		State:    NewDisclosedClientState(),
+// Milliseconds since the last message was received from the client, or since it joined if it hasn't sent any
+func (c *Client) IdleMS() uint64 {
+	nowInMS := uint64(time.Now().UnixMilli())
+	last := max(c.State.MSOfLastMessage.Load(), uint64(c.JoinedAt.UnixMilli()))
+	if last > nowInMS {
+		return 0
+	}
+	return nowInMS - last
+}
+
+func NewClient(id ClientID, IGN string, clientType OriginType, conn *websocket.Conn, encoding meta.MessageEncoding,
+	configuration *meta.RuntimeConfiguration) *Client {
+	client := &Client{
+		ID:                 id,
+		IDBytes:            util.BytesOfUint32(id),
+		IGN:                IGN,
+		JoinedAt:           time.Now(),
+		Conn:               conn,
+		Encoding:           encoding,
+		State:              NewDisclosedClientState(),
+		resumeToken:        newResumeToken(),
+		SendMetrics:        &ClientSendMetrics{},
+		RateLimiter:        NewClientRateLimiter(configuration),
+		sendQueue:          make(chan bufferedMessage, configuration.SendQueueSize),
+		writeTimeout:       configuration.WriteTimeout,
+		slowConsumerPolicy: configuration.SlowConsumerPolicy,
+		missedMessagesCap:  configuration.ResumeBufferSize,
+	}
+	client.clientType.Store(clientType)
+	client.Connected.Store(true)
+	return client
+}
+
+// Threadsafe
+func (c *Client) Type() OriginType {
+	clientType, _ := c.clientType.Load().(OriginType)
+	return clientType
+}
+
+func (c *Client) setType(clientType OriginType) {
+	c.clientType.Store(clientType)
+}
+
+// Threadsafe
+func (c *Client) ResumeToken() string {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return c.resumeToken
+}
+
+// Threadsafe, constant time
+func (c *Client) hasResumeToken(token string) bool {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return subtle.ConstantTimeCompare([]byte(c.resumeToken), []byte(token)) == 1
+}
+
+// 128 bits of randomness, hex encoded
+func newResumeToken() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		// crypto/rand never returns an error on supported platforms
+		panic(fmt.Sprintf("unable to generate resume token: %s", err.Error()))
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// Queues the message for writing. Never blocks.
+//
+// Returns ErrClientDisconnected if the client is in its reconnect grace period,
+// and ErrSendQueueFull if the message was dropped as per the slow consumer policy
+func (c *Client) Send(messageType int, data []byte) error {
+	return c.enqueue(bufferedMessage{messageType: messageType, data: data}, false)
+}
+
+// Like Send, however if the client is disconnected, the message is retained
+// and delivered when (if) the client resumes its session.
+func (c *Client) deliverBroadcast(messageType int, data []byte) error {
+	return c.enqueue(bufferedMessage{messageType: messageType, data: data}, true)
+}
+
+func (c *Client) enqueue(msg bufferedMessage, retainIfDisconnected bool) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if !c.Connected.Load() {
+		if retainIfDisconnected {
+			return c.retainMissedLocked(msg)
+		}
+		return ErrClientDisconnected
+	}
+
+	select {
+	case c.sendQueue <- msg:
+		queueLength := uint32(len(c.sendQueue))
+		for {
+			highWaterMark := c.SendMetrics.QueueHighWaterMark.Load()
+			if queueLength <= highWaterMark || c.SendMetrics.QueueHighWaterMark.CompareAndSwap(highWaterMark, queueLength) {
+				break
+			}
+		}
+		return nil
+	default:
+	}
+
+	switch c.slowConsumerPolicy {
+	case meta.SLOW_CONSUMER_POLICY_DISCONNECT:
+		// The read loop exits on the closed connection, after which the client is handled as any other disconnect.
+		// The overflowing message is kept for a possible resume
+		if !c.closedAsSlowConsumer {
+			c.closedAsSlowConsumer = true
+			c.SendMetrics.SlowConsumerDisconnects.Add(1)
+			log.Printf("[client] Send queue full for client %d, closing connection", c.ID)
+			c.Conn.Close()
+		}
+		return c.retainMissedLocked(msg)
+	default:
+		c.SendMetrics.DroppedMessages.Add(1)
+		log.Printf("[client] Send queue full for client %d, dropping message", c.ID)
+		return ErrSendQueueFull
+	}
+}
+
+// Assumes connLock is held
+func (c *Client) retainMissedLocked(msg bufferedMessage) error {
+	if c.missedMessagesCap <= 0 {
+		return ErrClientDisconnected
+	}
+	if len(c.missedMessages) >= c.missedMessagesCap {
+		log.Printf("[client] Resume buffer full for client %d, dropping oldest message", c.ID)
+		c.missedMessages = c.missedMessages[1:]
+	}
+	c.missedMessages = append(c.missedMessages, msg)
+	return nil
+}
+
+// The writer routine of a single connection
+type connectionWriter struct {
+	done     chan struct{}
+	exited   chan struct{}
+	stopOnce sync.Once
+}
+
+// Idempotent. The writer exits after the write in progress, if any
+func (w *connectionWriter) stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+// Starts the writer routine of the connection of the given generation.
+// Returns nil if that connection has already been replaced or already has a writer
+func (c *Client) startWriter(conn *websocket.Conn, generation uint32, pingInterval time.Duration) *connectionWriter {
+	writer := &connectionWriter{done: make(chan struct{}), exited: make(chan struct{})}
+	c.connLock.Lock()
+	if c.connectionGeneration != generation || c.writer != nil {
+		c.connLock.Unlock()
+		return nil
+	}
+	c.writer = writer
+	c.connLock.Unlock()
+	go func() {
+		c.runWriter(conn, writer.done, pingInterval)
+		close(writer.exited)
+	}()
+	return writer
+}
+
+// Writes queued messages to the connection until done is closed or a write fails.
+// On failure the connection is closed, which ends the read loop as well.
+//
+// Any messages pending resend are written first. Pings the client every pingInterval, unless it is 0
+func (c *Client) runWriter(conn *websocket.Conn, done <-chan struct{}, pingInterval time.Duration) {
+	c.connLock.Lock()
+	resend := c.pendingResend
+	c.pendingResend = nil
+	c.connLock.Unlock()
+
+	for _, msg := range resend {
+		if err := c.write(conn, msg); err != nil {
+			return
+		}
+	}
+	if len(resend) > 0 {
+		log.Printf("[client] Resent %d missed messages to client %d", len(resend), c.ID)
+	}
+
+	// A nil channel blocks forever, so no pings are sent if disabled
+	var pingTicks <-chan time.Time
+	if pingInterval > 0 {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		pingTicks = ticker.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-pingTicks:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+				log.Printf("[client] Error pinging client %d, closing connection: %v", c.ID, err)
+				conn.Close()
+				return
+			}
+		case msg := <-c.sendQueue:
+			if err := c.write(conn, msg); err != nil {
+				return
+			}
+			if msg.messageType == websocket.CloseMessage {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) write(conn *websocket.Conn, msg bufferedMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if err := conn.WriteMessage(msg.messageType, msg.data); err != nil {
+		log.Printf("[client] Error writing to client %d, closing connection: %v", c.ID, err)
+		conn.Close()
+		return err
+	}
+	c.SendMetrics.SentMessages.Add(1)
+	return nil
+}
+
+// Approximate
+func (c *Client) SendQueueLength() int {
+	return len(c.sendQueue)
+}
+
+func (c *Client) SendQueueCapacity() int {
+	return cap(c.sendQueue)
+}
+
+// Returns the current connection and its generation
+func (c *Client) currentConnection() (*websocket.Conn, uint32) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return c.Conn, c.connectionGeneration
+}
+
+// Marks the client as disconnected, unless the connection of the given generation has already been replaced.
+// Anything still queued is retained, ahead of any broadcasts to come, for a possible resume.
+//
+// Returns true if the client was marked as disconnected
+func (c *Client) markDisconnected(generation uint32) bool {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	if c.connectionGeneration != generation {
+		return false
+	}
+	c.Connected.Store(false)
+	c.retainQueuedLocked()
+	return true
+}
+
+// Moves anything still queued into the resume buffer, ahead of what is already there.
+// Assumes connLock is held and the client is marked as disconnected, so nothing more is queued
+func (c *Client) retainQueuedLocked() {
+	var unsent []bufferedMessage
+	for len(c.sendQueue) > 0 {
+		unsent = append(unsent, <-c.sendQueue)
+	}
+	missed := c.missedMessages
+	c.missedMessages = nil
+	for _, msg := range append(unsent, missed...) {
+		c.retainMissedLocked(msg)
+	}
+}
+
+// Replaces the connection, closing the previous one, if the resume token matches. Any missed broadcasts are
+// written by the writer of the new connection before anything else.
+//
+// The writer of the previous connection is stopped and waited for first, as until it has exited it may still take
+// messages off the send queue and write them to the dead connection. Meanwhile the client counts as disconnected,
+// so anything queued or broadcast is kept for the new connection instead.
+//
+// The token is replaced, so it can only be used once. Returns the new token, and false if the given one didn't match
+func (c *Client) swapConnection(conn *websocket.Conn, resumeToken string) (string, bool) {
+	c.connLock.Lock()
+	if subtle.ConstantTimeCompare([]byte(c.resumeToken), []byte(resumeToken)) != 1 {
+		c.connLock.Unlock()
+		return "", false
+	}
+	c.resumeToken = newResumeToken()
+	// From here on the read loop of the previous connection exiting is not treated as a lost connection
+	c.connectionGeneration++
+	c.Connected.Store(false)
+	c.retainQueuedLocked()
+	previousConn, previousWriter := c.Conn, c.writer
+	c.writer = nil
+	c.connLock.Unlock()
+
+	if previousConn != nil && previousConn != conn {
+		// Fails any write in progress, so the writer exits right away
+		previousConn.Close()
+	}
+	if previousWriter != nil {
+		previousWriter.stop()
+		<-previousWriter.exited
+	}
+
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	c.Conn = conn
+	c.pendingResend = c.missedMessages
+	c.missedMessages = nil
+	c.closedAsSlowConsumer = false
+	c.Connected.Store(true)
+	return c.resumeToken, true
+}
+
+// Queues a close frame behind any messages already queued, so the client recieves those first.
+// The writer stops after writing it. If the queue is full, the close frame is written right away instead
+func (c *Client) queueClose(code int, reason string) {
+	msg := bufferedMessage{messageType: websocket.CloseMessage, data: websocket.FormatCloseMessage(code, truncateCloseReason(reason))}
+	select {
+	case c.sendQueue <- msg:
+	default:
+		conn, _ := c.currentConnection()
+		conn.WriteControl(websocket.CloseMessage, msg.data, time.Now().Add(c.writeTimeout))
+	}
+}
+
+// Control frames carry at most 125 bytes, 2 of which are the close code
+func truncateCloseReason(reason string) string {
+	return truncateUTF8(reason, 123)
+}
+
+// Cuts the string short to at most maxSize bytes, without splitting a multi-byte character
+func truncateUTF8(s string, maxSize int) string {
+	if len(s) <= maxSize {
+		return s
 	}
+	end := maxSize
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+// Closes the current connection. Any read loop on it will exit
+func (c *Client) closeConnection() error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return c.Conn.Close()
 }
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
+)
+
+// Returns the server and client side of a new websocket connection
+func newTestConnPair(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	serverSide := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading: %v", err)
+			return
+		}
+		serverSide <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	clientSide, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error connecting: %v", err)
+	}
+	serverConn := <-serverSide
+	t.Cleanup(func() {
+		clientSide.Close()
+		serverConn.Close()
+	})
+	return serverConn, clientSide
+}
+
+func newTestClient(t *testing.T, conn *websocket.Conn, queueSize int, policy meta.SlowConsumerPolicy) *Client {
+	configuration := meta.NewRuntimeConfiguration(meta.RUNTIME_MODE_DEV, meta.MESSAGE_ENCODING_BINARY)
+	configuration.SendQueueSize = queueSize
+	configuration.SlowConsumerPolicy = policy
+	return NewClient(1, "Test", ORIGIN_TYPE_GUEST, conn, meta.MESSAGE_ENCODING_BINARY, configuration)
+}
+
+func expectMessages(t *testing.T, conn *websocket.Conn, expected ...[]byte) {
+	t.Helper()
+	for _, want := range expected {
+		conn.SetReadDeadline(time.Now().Add(testTimeout))
+		_, got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Expected message %v, got error: %v", want, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Expected message %v, got %v", want, got)
+		}
+	}
+}
+
+func TestSendQueueOverflowDrops(t *testing.T) {
+	serverConn, clientConn := newTestConnPair(t)
+	client := newTestClient(t, serverConn, 2, meta.SLOW_CONSUMER_POLICY_DROP)
+
+	// Nothing is written before the writer is started, so the queue fills up
+	for i := byte(0); i < 2; i++ {
+		if err := client.Send(websocket.BinaryMessage, []byte{i}); err != nil {
+			t.Fatalf("Expected message %d to be queued, got %v", i, err)
+		}
+	}
+	if err := client.Send(websocket.BinaryMessage, []byte{2}); !errors.Is(err, ErrSendQueueFull) {
+		t.Errorf("Expected ErrSendQueueFull, got %v", err)
+	}
+	if dropped := client.SendMetrics.DroppedMessages.Load(); dropped != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", dropped)
+	}
+	if highWaterMark := client.SendMetrics.QueueHighWaterMark.Load(); highWaterMark != 2 {
+		t.Errorf("Expected a high water mark of 2, got %d", highWaterMark)
+	}
+
+	writer := client.startWriter(serverConn, 0, 0)
+	defer writer.stop()
+	expectMessages(t, clientConn, []byte{0}, []byte{1})
+	if err := client.Send(websocket.BinaryMessage, []byte{3}); err != nil {
+		t.Errorf("Expected room in the queue once drained, got %v", err)
+	}
+	expectMessages(t, clientConn, []byte{3})
+}
+
+func TestSendQueueOverflowDisconnects(t *testing.T) {
+	serverConn, clientConn := newTestConnPair(t)
+	client := newTestClient(t, serverConn, 2, meta.SLOW_CONSUMER_POLICY_DISCONNECT)
+
+	for i := byte(0); i < 3; i++ {
+		// The overflowing message is kept for a resume, rather than dropped
+		if err := client.Send(websocket.BinaryMessage, []byte{i}); err != nil {
+			t.Fatalf("Expected message %d to be accepted, got %v", i, err)
+		}
+	}
+	if disconnects := client.SendMetrics.SlowConsumerDisconnects.Load(); disconnects != 1 {
+		t.Errorf("Expected 1 slow consumer disconnect, got %d", disconnects)
+	}
+	clientConn.SetReadDeadline(time.Now().Add(testTimeout))
+	if _, _, err := clientConn.ReadMessage(); err == nil {
+		t.Error("Expected the connection to be closed")
+	}
+
+	// Everything not written is resent in order on a resumed connection
+	if !client.markDisconnected(0) {
+		t.Fatal("Expected the client to be marked disconnected")
+	}
+	newServerConn, newClientConn := newTestConnPair(t)
+	if _, resumed := client.swapConnection(newServerConn, client.ResumeToken()); !resumed {
+		t.Fatal("Expected the resume to succeed")
+	}
+	writer := client.startWriter(newServerConn, 1, 0)
+	defer writer.stop()
+	expectMessages(t, newClientConn, []byte{0}, []byte{1}, []byte{2})
+}
+
+func TestSwapConnectionStopsPreviousWriter(t *testing.T) {
+	serverConn, clientConn := newTestConnPair(t)
+	client := newTestClient(t, serverConn, 8, meta.SLOW_CONSUMER_POLICY_DROP)
+	previousWriter := client.startWriter(serverConn, 0, 0)
+
+	newServerConn, newClientConn := newTestConnPair(t)
+	if _, resumed := client.swapConnection(newServerConn, client.ResumeToken()); !resumed {
+		t.Fatal("Expected the resume to succeed")
+	}
+	select {
+	case <-previousWriter.exited:
+	default:
+		t.Fatal("Expected the previous writer to have exited before the connection was swapped")
+	}
+	if client.startWriter(serverConn, 0, 0) != nil {
+		t.Error("Expected no writer to be started for the replaced connection")
+	}
+
+	// Queued before the new writer starts, so the previous writer would have taken it were it still running
+	client.Send(websocket.BinaryMessage, []byte{1})
+	writer := client.startWriter(newServerConn, 1, 0)
+	defer writer.stop()
+	if client.startWriter(newServerConn, 1, 0) != nil {
+		t.Error("Expected no second writer for the same connection")
+	}
+	expectMessages(t, newClientConn, []byte{1})
+
+	clientConn.SetReadDeadline(time.Now().Add(testTimeout))
+	if _, msg, err := clientConn.ReadMessage(); err == nil {
+		t.Errorf("Expected nothing on the replaced connection, got %v", msg)
+	}
+}
+
+func TestSwapConnectionKeepsQueuedMessages(t *testing.T) {
+	serverConn, _ := newTestConnPair(t)
+	client := newTestClient(t, serverConn, 8, meta.SLOW_CONSUMER_POLICY_DROP)
+	// Queued for the previous connection, but never written
+	client.Send(websocket.BinaryMessage, []byte{1})
+	client.deliverBroadcast(websocket.BinaryMessage, []byte{2})
+
+	newServerConn, newClientConn := newTestConnPair(t)
+	if _, resumed := client.swapConnection(newServerConn, client.ResumeToken()); !resumed {
+		t.Fatal("Expected the resume to succeed")
+	}
+	if client.SendQueueLength() != 0 {
+		t.Errorf("Expected the queue to have been moved to the resume buffer, got %d queued", client.SendQueueLength())
+	}
+	client.deliverBroadcast(websocket.BinaryMessage, []byte{3})
+	writer := client.startWriter(newServerConn, 1, 0)
+	defer writer.stop()
+	expectMessages(t, newClientConn, []byte{1}, []byte{2}, []byte{3})
+}
+
+func TestIdleMS(t *testing.T) {
+	client := &Client{State: NewDisclosedClientState(), JoinedAt: time.Now().Add(-time.Minute)}
+	if idle := client.IdleMS(); idle < 60_000 || idle > 61_000 {
+		t.Errorf("Expected idle time to count from joining if nothing was sent, got %d", idle)
+	}
+	client.State.UpdateAny(0, nil)
+	if idle := client.IdleMS(); idle > 1_000 {
+		t.Errorf("Expected idle time to count from the last message, got %d", idle)
+	}
+}
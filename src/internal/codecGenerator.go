@@ -0,0 +1,509 @@
+package internal
+
+import (
+	"fmt"
+	"go/format"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var internalPackagePath = reflect.TypeOf(ComputedStructure{}).PkgPath()
+
+// Generates the source of a file in package internal, fx. eventCodecs_gen.go, with a typed AppendBinary, MarshalBinary and UnmarshalBinary
+// for the DTO of each spec. generatedBy is the command to regenerate the file with.
+// DTOs that can't have a codec, fx. as they are declared outside of package internal, are skipped with a warning
+func GenerateGoCodecs(specs []EventSpecification[any], generatedBy string) ([]byte, error) {
+	var dtoTypes []reflect.Type
+	structures := make(map[reflect.Type]ComputedStructure)
+	for _, spec := range specs {
+		if spec.DTOType == nil {
+			continue
+		}
+		if existing, seen := structures[spec.DTOType]; seen {
+			if existing.Layout() != spec.Structure.Layout() {
+				return nil, fmt.Errorf("%s is the DTO of events of differing layouts, fx. %s", spec.DTOType.Name(), spec.Name)
+			}
+			continue
+		}
+		structures[spec.DTOType] = spec.Structure
+		dtoTypes = append(dtoTypes, spec.DTOType)
+	}
+
+	imports := make(map[string]bool)
+	var body strings.Builder
+	for _, dtoType := range dtoTypes {
+		codec := &goCodecWriter{imports: make(map[string]bool)}
+		if err := codec.writeCodec(dtoType, structures[dtoType]); err != nil {
+			log.Printf("[codecs] Skipping codec of %s: %s", dtoType, err.Error())
+			continue
+		}
+		body.WriteString(codec.String())
+		for path := range codec.imports {
+			imports[path] = true
+		}
+	}
+
+	var source strings.Builder
+	source.WriteString(fmt.Sprintf("// Code generated by %s. DO NOT EDIT.\n\n", generatedBy))
+	source.WriteString("package internal\n\n")
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		source.WriteString("import (\n")
+		for _, path := range paths {
+			source.WriteString(fmt.Sprintf("\t%q\n", path))
+		}
+		source.WriteString(")\n")
+	}
+	source.WriteString(body.String())
+
+	formatted, err := format.Source([]byte(source.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated codecs: %s", err.Error())
+	}
+	return formatted, nil
+}
+
+type goCodecWriter struct {
+	strings.Builder
+	imports map[string]bool
+	// Only while writing UnmarshalBinary. Until the first length prefixed element, offsets are known and written as is
+	cursorDeclared bool
+	// Offset from the start of the data, or from the cursor once declared
+	offset uint32
+}
+
+func (w *goCodecWriter) line(format string, args ...interface{}) {
+	w.WriteString(fmt.Sprintf(format, args...))
+	w.WriteString("\n")
+}
+
+func (w *goCodecWriter) writeCodec(dtoType reflect.Type, structure ComputedStructure) error {
+	if dtoType.PkgPath() != internalPackagePath {
+		return fmt.Errorf("declared outside of package internal")
+	}
+	name := dtoType.Name()
+
+	w.line("")
+	w.line("// AppendBinary appends the body of a message, i.e. everything after the header, as described by its event specification")
+	w.line("func (m %s) AppendBinary(dst []byte) ([]byte, error) {", name)
+	if err := w.writeAppend(dtoType, structure); err != nil {
+		return err
+	}
+	w.line("return dst, nil")
+	w.line("}")
+
+	w.line("")
+	w.line("func (m %s) MarshalBinary() ([]byte, error) {", name)
+	w.line("return m.AppendBinary(nil)")
+	w.line("}")
+
+	w.line("")
+	w.line("// UnmarshalBinary reads the body of a message, i.e. everything after the header, as described by its event specification")
+	w.line("func (m *%s) UnmarshalBinary(data []byte) error {", name)
+	if err := w.writeUnmarshal(dtoType, structure); err != nil {
+		return err
+	}
+	w.line("return nil")
+	w.line("}")
+
+	w.line("")
+	w.line("func (%s) codecLayout() string {", name)
+	w.line("return %q", structure.Layout())
+	w.line("}")
+	return nil
+}
+
+func (w *goCodecWriter) writeAppend(dtoType reflect.Type, structure ComputedStructure) error {
+	var minSize uint32
+	var growth []string
+	for _, element := range structure {
+		minSize += element.ByteSize + element.LengthPrefixSize
+		goPath, _, err := goFieldPath(dtoType, element.FieldName)
+		if err != nil {
+			return err
+		}
+		switch {
+		case element.Kind == reflect.Slice:
+			w.imports["fmt"] = true
+			w.line("if len(m.%s) > %d {", goPath, element.MaxLength)
+			w.line("return nil, fmt.Errorf(\"field '%s': %%d elements exceeds max length %d\", len(m.%s))", element.FieldName, element.MaxLength, goPath)
+			w.line("}")
+			growth = append(growth, fmt.Sprintf("len(m.%s)*%d", goPath, element.ElemByteSize))
+		case element.Kind == reflect.String:
+			// Also for unprefixed strings, as whatever is sent to a client should be within what it's told to expect
+			maxSize := element.MaxByteSize - element.LengthPrefixSize
+			w.imports["fmt"] = true
+			w.line("if len(m.%s) > %d {", goPath, maxSize)
+			w.line("return nil, fmt.Errorf(\"field '%s': %%d bytes exceeds max size %d\", len(m.%s))", element.FieldName, maxSize, goPath)
+			w.line("}")
+			growth = append(growth, fmt.Sprintf("len(m.%s)", goPath))
+		}
+	}
+	if len(structure) == 0 {
+		return nil
+	}
+
+	w.imports["slices"] = true
+	w.line("dst = slices.Grow(dst, %s)", strings.Join(append([]string{fmt.Sprint(minSize)}, growth...), "+"))
+	for _, element := range structure {
+		goPath, fieldType, _ := goFieldPath(dtoType, element.FieldName)
+		switch {
+		case element.Kind == reflect.Slice:
+			w.imports["encoding/binary"] = true
+			w.line("dst = binary.BigEndian.AppendUint32(dst, uint32(len(m.%s)))", goPath)
+			w.line("for _, elem := range m.%s {", goPath)
+			if err := w.writeAppendElem(fieldType.Elem(), element); err != nil {
+				return err
+			}
+			w.line("}")
+		case element.Kind == reflect.String:
+			if element.LengthPrefixSize > 0 {
+				w.imports["encoding/binary"] = true
+				prefixType := stringLengthPrefixType(element)
+				w.line("dst = binary.BigEndian.Append%s(dst, %s(len(m.%s)))", prefixType, strings.ToLower(prefixType), goPath)
+			}
+			w.line("dst = append(dst, m.%s...)", goPath)
+		default:
+			if err := w.writeAppendScalar("m."+goPath, fieldType, element.Kind); err != nil {
+				return fmt.Errorf("field '%s': %s", element.FieldName, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// Of the byte count of a string in WIRE_FORMAT_V2, as named by encoding/binary
+func stringLengthPrefixType(element MessageElementDescriptor) string {
+	if element.LengthPrefixSize == LONG_STRING_LENGTH_PREFIX_SIZE {
+		return "Uint32"
+	}
+	return "Uint16"
+}
+
+func (w *goCodecWriter) writeAppendElem(elemType reflect.Type, element MessageElementDescriptor) error {
+	if element.ElemKind != reflect.Struct {
+		if err := w.writeAppendScalar("elem", elemType, element.ElemKind); err != nil {
+			return fmt.Errorf("elements of '%s': %s", element.FieldName, err.Error())
+		}
+		return nil
+	}
+	for _, field := range element.ElemStructure {
+		goPath, fieldType, err := goFieldPath(elemType, field.FieldName)
+		if err != nil {
+			return fmt.Errorf("elements of '%s': %s", element.FieldName, err.Error())
+		}
+		if err := w.writeAppendScalar("elem."+goPath, fieldType, field.Kind); err != nil {
+			return fmt.Errorf("elements of '%s', field '%s': %s", element.FieldName, field.FieldName, err.Error())
+		}
+	}
+	return nil
+}
+
+func (w *goCodecWriter) writeAppendScalar(expr string, t reflect.Type, kind reflect.Kind) error {
+	switch kind {
+	case reflect.Bool:
+		w.line("if %s {", expr)
+		w.line("dst = append(dst, 1)")
+		w.line("} else {")
+		w.line("dst = append(dst, 0)")
+		w.line("}")
+	case reflect.Uint8, reflect.Int8:
+		w.line("dst = append(dst, %s)", convertTo("uint8", t, expr))
+	case reflect.Uint16, reflect.Int16:
+		w.imports["encoding/binary"] = true
+		w.line("dst = binary.BigEndian.AppendUint16(dst, %s)", convertTo("uint16", t, expr))
+	case reflect.Uint32, reflect.Int32:
+		w.imports["encoding/binary"] = true
+		w.line("dst = binary.BigEndian.AppendUint32(dst, %s)", convertTo("uint32", t, expr))
+	case reflect.Uint64, reflect.Int64:
+		w.imports["encoding/binary"] = true
+		w.line("dst = binary.BigEndian.AppendUint64(dst, %s)", convertTo("uint64", t, expr))
+	case reflect.Float32:
+		w.imports["encoding/binary"] = true
+		w.imports["math"] = true
+		w.line("dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(%s))", convertTo("float32", t, expr))
+	case reflect.Float64:
+		w.imports["encoding/binary"] = true
+		w.imports["math"] = true
+		w.line("dst = binary.BigEndian.AppendUint64(dst, math.Float64bits(%s))", convertTo("float64", t, expr))
+	default:
+		return fmt.Errorf("unsupported kind %s", kind)
+	}
+	return nil
+}
+
+func (w *goCodecWriter) writeUnmarshal(dtoType reflect.Type, structure ComputedStructure) error {
+	var minSize uint32
+	hasVariableSizeElement := false
+	hasLengthPrefix := false
+	for _, element := range structure {
+		minSize += element.ByteSize + element.LengthPrefixSize
+		hasVariableSizeElement = hasVariableSizeElement || element.ByteSize == 0
+		hasLengthPrefix = hasLengthPrefix || element.LengthPrefixSize > 0
+	}
+	w.imports["fmt"] = true
+	if !hasVariableSizeElement {
+		w.line("if len(data) != %d {", minSize)
+		w.line("return fmt.Errorf(\"expected %d bytes, got %%d\", len(data))", minSize)
+		w.line("}")
+	} else if minSize > 0 {
+		w.line("if len(data) < %d {", minSize)
+		w.line("return fmt.Errorf(\"expected at least %d bytes, got %%d\", len(data))", minSize)
+		w.line("}")
+	}
+	if hasLengthPrefix {
+		w.line("var length int")
+	}
+
+	w.cursorDeclared = false
+	w.offset = 0
+	for index, element := range structure {
+		goPath, fieldType, err := goFieldPath(dtoType, element.FieldName)
+		if err != nil {
+			return err
+		}
+		if element.LengthPrefixSize > 0 {
+			if w.cursorDeclared {
+				w.flushCursor()
+				w.line("if len(data)-i < %d {", element.LengthPrefixSize)
+				w.line("return fmt.Errorf(\"not enough data to parse length of %s\")", element.FieldName)
+				w.line("}")
+			} else {
+				// The min size covers everything up to and including the first length prefix
+				w.line("i := %d", w.offset)
+				w.cursorDeclared = true
+				w.offset = 0
+			}
+		}
+
+		switch {
+		case element.Kind == reflect.Slice:
+			w.imports["encoding/binary"] = true
+			w.line("length = int(binary.BigEndian.Uint32(data[i:]))")
+			w.line("i += %d", element.LengthPrefixSize)
+			w.line("if length > %d {", element.MaxLength)
+			w.line("return fmt.Errorf(\"length %%d of %s exceeds max length %d\", length)", element.FieldName, element.MaxLength)
+			w.line("}")
+			w.line("if len(data)-i < length*%d {", element.ElemByteSize)
+			w.line("return fmt.Errorf(\"not enough data to parse %s, expected %%d bytes, got %%d\", length*%d, len(data)-i)", element.FieldName, element.ElemByteSize)
+			w.line("}")
+			sliceType, err := goTypeExpr(fieldType)
+			if err != nil {
+				return fmt.Errorf("field '%s': %s", element.FieldName, err.Error())
+			}
+			w.line("m.%s = make(%s, length)", goPath, sliceType)
+			w.line("for j := range m.%s {", goPath)
+			if err := w.writeParseElem(fmt.Sprintf("m.%s[j]", goPath), fieldType.Elem(), element); err != nil {
+				return err
+			}
+			w.line("i += %d", element.ElemByteSize)
+			w.line("}")
+		case element.Kind == reflect.String:
+			end := ""
+			if element.LengthPrefixSize > 0 {
+				w.imports["encoding/binary"] = true
+				maxSize := element.MaxByteSize - element.LengthPrefixSize
+				w.line("length = int(binary.BigEndian.%s(data[i:]))", stringLengthPrefixType(element))
+				w.line("i += %d", element.LengthPrefixSize)
+				w.line("if length > %d {", maxSize)
+				w.line("return fmt.Errorf(\"size %%d of %s exceeds max size %d\", length)", element.FieldName, maxSize)
+				w.line("}")
+				w.line("if len(data)-i < length {")
+				w.line("return fmt.Errorf(\"not enough data to parse %s, expected %%d bytes, got %%d\", length, len(data)-i)", element.FieldName)
+				w.line("}")
+				end = "i+length"
+			}
+			value := fmt.Sprintf("data[%s:%s]", w.position(0), end)
+			w.imports["unicode/utf8"] = true
+			w.line("if !utf8.Valid(%s) {", value)
+			w.line("return fmt.Errorf(\"invalid UTF-8 string\")")
+			w.line("}")
+			stringType, err := goTypeExpr(fieldType)
+			if err != nil {
+				return fmt.Errorf("field '%s': %s", element.FieldName, err.Error())
+			}
+			w.line("m.%s = %s(%s)", goPath, stringType, value)
+			if element.LengthPrefixSize > 0 {
+				w.line("i += length")
+			}
+		default:
+			// Any fixed size run following a variable size element has to be checked for itself
+			if w.cursorDeclared && w.offset == 0 {
+				var run uint32
+				for _, next := range structure[index:] {
+					if next.ByteSize == 0 {
+						break
+					}
+					run += next.ByteSize
+				}
+				w.line("if len(data)-i < %d {", run)
+				w.line("return fmt.Errorf(\"not enough data to parse %s, expected %d bytes, got %%d\", len(data)-i)", element.FieldName, run)
+				w.line("}")
+			}
+			expr, err := w.parseScalar(w.position(0), fieldType, element.Kind)
+			if err != nil {
+				return fmt.Errorf("field '%s': %s", element.FieldName, err.Error())
+			}
+			w.line("m.%s = %s", goPath, expr)
+			w.offset += element.ByteSize
+		}
+	}
+
+	// Unprefixed strings take up the rest of the data, so there is nothing left to check
+	last := len(structure) - 1
+	if w.cursorDeclared && !(structure[last].Kind == reflect.String && structure[last].LengthPrefixSize == 0) {
+		w.flushCursor()
+		w.line("if i != len(data) {")
+		w.line("return fmt.Errorf(\"expected %%d bytes, got %%d\", i, len(data))")
+		w.line("}")
+	}
+	return nil
+}
+
+func (w *goCodecWriter) writeParseElem(target string, elemType reflect.Type, element MessageElementDescriptor) error {
+	if element.ElemKind != reflect.Struct {
+		expr, err := w.parseScalar("i", elemType, element.ElemKind)
+		if err != nil {
+			return fmt.Errorf("elements of '%s': %s", element.FieldName, err.Error())
+		}
+		w.line("%s = %s", target, expr)
+		return nil
+	}
+	for _, field := range element.ElemStructure {
+		goPath, fieldType, err := goFieldPath(elemType, field.FieldName)
+		if err != nil {
+			return fmt.Errorf("elements of '%s': %s", element.FieldName, err.Error())
+		}
+		position := "i"
+		if field.Offset > 0 {
+			position = fmt.Sprintf("i+%d", field.Offset)
+		}
+		expr, err := w.parseScalar(position, fieldType, field.Kind)
+		if err != nil {
+			return fmt.Errorf("elements of '%s', field '%s': %s", element.FieldName, field.FieldName, err.Error())
+		}
+		w.line("%s.%s = %s", target, goPath, expr)
+	}
+	return nil
+}
+
+func (w *goCodecWriter) parseScalar(position string, t reflect.Type, kind reflect.Kind) (string, error) {
+	var expr string
+	switch kind {
+	case reflect.Bool:
+		expr = fmt.Sprintf("data[%s] != 0", position)
+	case reflect.Uint8:
+		expr = fmt.Sprintf("data[%s]", position)
+	case reflect.Int8:
+		expr = fmt.Sprintf("int8(data[%s])", position)
+	case reflect.Uint16, reflect.Int16:
+		expr = fmt.Sprintf("binary.BigEndian.Uint16(data[%s:])", position)
+	case reflect.Uint32, reflect.Int32:
+		expr = fmt.Sprintf("binary.BigEndian.Uint32(data[%s:])", position)
+	case reflect.Uint64, reflect.Int64:
+		expr = fmt.Sprintf("binary.BigEndian.Uint64(data[%s:])", position)
+	case reflect.Float32:
+		expr = fmt.Sprintf("math.Float32frombits(binary.BigEndian.Uint32(data[%s:]))", position)
+	case reflect.Float64:
+		expr = fmt.Sprintf("math.Float64frombits(binary.BigEndian.Uint64(data[%s:]))", position)
+	default:
+		return "", fmt.Errorf("unsupported kind %s", kind)
+	}
+	switch kind {
+	case reflect.Bool, reflect.Uint8, reflect.Int8:
+	case reflect.Float32, reflect.Float64:
+		w.imports["math"] = true
+		w.imports["encoding/binary"] = true
+	default:
+		w.imports["encoding/binary"] = true
+	}
+	switch kind {
+	case reflect.Int16, reflect.Int32, reflect.Int64:
+		expr = fmt.Sprintf("%s(%s)", kind, expr)
+	}
+
+	typeName, err := goTypeExpr(t)
+	if err != nil {
+		return "", err
+	}
+	if typeName != kind.String() {
+		expr = fmt.Sprintf("%s(%s)", typeName, expr)
+	}
+	return expr, nil
+}
+
+// Where the next element starts, given it is offset bytes further
+func (w *goCodecWriter) position(offset uint32) string {
+	switch {
+	case !w.cursorDeclared:
+		return fmt.Sprint(w.offset + offset)
+	case w.offset+offset == 0:
+		return "i"
+	default:
+		return fmt.Sprintf("i+%d", w.offset+offset)
+	}
+}
+
+// Moves the cursor past any fixed size elements read since it was last moved
+func (w *goCodecWriter) flushCursor() {
+	if w.offset > 0 {
+		w.line("i += %d", w.offset)
+		w.offset = 0
+	}
+}
+
+// Converts expr to the builtin type named typeName, unless t already is that type
+func convertTo(typeName string, t reflect.Type, expr string) string {
+	if t.PkgPath() == "" && t.Name() == typeName {
+		return expr
+	}
+	return fmt.Sprintf("%s(%s)", typeName, expr)
+}
+
+// How t is referred to from within package internal
+func goTypeExpr(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() != "" && t.PkgPath() != internalPackagePath {
+			return "", fmt.Errorf("%s is declared outside of package internal", t)
+		}
+		return t.Name(), nil
+	}
+	if t.Kind() == reflect.Slice {
+		elem, err := goTypeExpr(t.Elem())
+		return "[]" + elem, err
+	}
+	return "", fmt.Errorf("anonymous %s types are not supported", t.Kind())
+}
+
+// Resolves a dotted path of JSON tags, as the field names of flattened structures, to the Go field names
+func goFieldPath(t reflect.Type, jsonPath string) (string, reflect.Type, error) {
+	var names []string
+	for _, tag := range strings.Split(jsonPath, ".") {
+		field, found := fieldByJSONTag(t, tag)
+		if !found {
+			return "", nil, fmt.Errorf("field with JSON tag '%s' not found in %s", jsonPath, t)
+		}
+		names = append(names, field.Name)
+		t = field.Type
+	}
+	return strings.Join(names, "."), t, nil
+}
+
+func fieldByJSONTag(t reflect.Type, tag string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == tag {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
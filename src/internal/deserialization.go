@@ -25,7 +25,22 @@ func Deserialize[T any](spec *EventSpecification[T], data []byte, remainderOnly
 
 	var dest T // Allocation of new nil-value instantiated copy of T
 
-	t := reflect.TypeOf(dest)
+	start := MESSAGE_HEADER_SIZE - offsetAdjustment
+	if codec, ok := any(&dest).(generatedCodec); ok && spec.hasGeneratedCodec && uint32(len(data)) >= start {
+		if err := codec.UnmarshalBinary(data[start:]); err != nil {
+			return nil, err
+		}
+		return &dest, nil
+	}
+	if err := deserializeWithReflection(spec.Structure, data, start, reflect.ValueOf(&dest).Elem()); err != nil {
+		return nil, err
+	}
+	return &dest, nil
+}
+
+// Deserialize, but without any generated codec. Reads the body starting at start into the settable struct dest
+func deserializeWithReflection(structure ComputedStructure, data []byte, start uint32, dest reflect.Value) error {
+	t := dest.Type()
 
 	// If it's a pointer, get the underlying element
 	if t.Kind() == reflect.Ptr {
@@ -33,66 +48,156 @@ func Deserialize[T any](spec *EventSpecification[T], data []byte, remainderOnly
 	}
 
 	if t.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("expected a struct, got %s", t.Kind())
+		return fmt.Errorf("expected a struct, got %s", t.Kind())
 	}
 
-	if t.NumField() != len(spec.Structure) {
-		return nil, fmt.Errorf("expected %d fields, got %d", len(spec.Structure), t.NumField())
+	spans, err := locateElements(structure, data, start)
+	if err != nil {
+		return err
 	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		element := spec.Structure[i]
-		if field.Type.Kind() != element.Kind {
-			return nil, fmt.Errorf("expected field %d to be of kind %s, got %s", i, element.Kind, field.Type.Kind())
+	// Fields of nested structs are found by their dotted path
+	for i, element := range structure {
+		field, found := util.FindFieldByJSONTagPath(dest, element.FieldName)
+		if !found {
+			return fmt.Errorf("field with JSON tag '%s' not found in struct", element.FieldName)
+		}
+		if field.Kind() != element.Kind {
+			return fmt.Errorf("expected field %s to be of kind %s, got %s", element.FieldName, element.Kind, field.Kind())
 		}
 
-		value, err := parseGoTypeFromBytes(data, element.Offset-offsetAdjustment, element.Kind)
+		var value interface{}
+		if element.Kind == reflect.Slice {
+			value, err = parseSliceFromBytes(data, spans[i], element, field.Type())
+		} else {
+			// Cut off at the end of the element, as strings take up the rest of the data
+			value, err = parseGoTypeFromBytes(data[:spans[i].end], spans[i].start, element.Kind)
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if err := setStructField(&dest, field.Name, value); err != nil {
-			return nil, err
+		if err := setStructField(field, value); err != nil {
+			return fmt.Errorf("field %s: %s", element.FieldName, err.Error())
 		}
 	}
 
-	return &dest, nil
+	return nil
 }
 
 // Extremely unsafe. Use with caution
-func setStructField(strukt interface{}, fieldName string, value interface{}) error {
-	structValue := reflect.ValueOf(strukt)
-	if structValue.Kind() != reflect.Ptr || structValue.Elem().Kind() != reflect.Struct {
-		return fmt.Errorf("strukt must be a pointer to a struct")
-	}
-	structValue = structValue.Elem()
-
-	fieldValue := structValue.FieldByName(fieldName)
-	if !fieldValue.IsValid() {
-		return fmt.Errorf("no such field: %s in struct %v", fieldName, structValue.Type())
-	}
-
+func setStructField(fieldValue reflect.Value, value interface{}) error {
 	if !fieldValue.CanSet() {
-		return fmt.Errorf("cannot set field %s in struct %v", fieldName, structValue.Type())
+		return fmt.Errorf("cannot set field of type %v", fieldValue.Type())
 	}
 
-	fieldType := fieldValue.Type()
+	// Kinds are already known to match, so this only converts to named types
 	val := reflect.ValueOf(value)
-	if fieldType != val.Type() {
+	if !val.Type().ConvertibleTo(fieldValue.Type()) {
 		return fmt.Errorf("provided value type didn't match obj field type")
 	}
 
-	fieldValue.Set(val)
+	fieldValue.Set(val.Convert(fieldValue.Type()))
 	return nil
 }
 
+// Where the value of an element lies within a message
+type elementSpan struct {
+	// Past any length prefix
+	start uint32
+	end   uint32
+	// Only for slices. Amount of elements
+	length uint32
+}
+
+// Walks the structure from start, following any length prefixes, to find where the value of each element lies.
+// Errors if a prefix exceeds the max of its element, or the data doesn't end exactly with the last element
+func locateElements(structure ComputedStructure, data []byte, start uint32) ([]elementSpan, error) {
+	spans := make([]elementSpan, len(structure))
+	size := uint64(len(data))
+	cursor := uint64(start)
+	for i, element := range structure {
+		var span elementSpan
+		var byteLength uint64
+		switch {
+		case element.ByteSize > 0:
+			byteLength = uint64(element.ByteSize)
+		case element.LengthPrefixSize == 0:
+			// Unprefixed strings take up the rest of the data
+			byteLength = size - min(cursor, size)
+		default:
+			if cursor+uint64(element.LengthPrefixSize) > size {
+				return nil, fmt.Errorf("not enough data to parse length of %s", element.FieldName)
+			}
+			if element.Kind == reflect.Slice {
+				span.length = binary.BigEndian.Uint32(data[cursor:])
+				if span.length > element.MaxLength {
+					return nil, fmt.Errorf("length %d of %s exceeds max length %d", span.length, element.FieldName, element.MaxLength)
+				}
+				byteLength = uint64(span.length) * uint64(element.ElemByteSize)
+			} else {
+				if element.LengthPrefixSize == LONG_STRING_LENGTH_PREFIX_SIZE {
+					byteLength = uint64(binary.BigEndian.Uint32(data[cursor:]))
+				} else {
+					byteLength = uint64(binary.BigEndian.Uint16(data[cursor:]))
+				}
+				if maxSize := element.MaxByteSize - element.LengthPrefixSize; byteLength > uint64(maxSize) {
+					return nil, fmt.Errorf("size %d of %s exceeds max size %d", byteLength, element.FieldName, maxSize)
+				}
+			}
+			cursor += uint64(element.LengthPrefixSize)
+		}
+		if cursor+byteLength > size {
+			return nil, fmt.Errorf("not enough data to parse %s, expected %d bytes, got %d", element.FieldName, byteLength, size-min(cursor, size))
+		}
+		span.start = uint32(cursor)
+		cursor += byteLength
+		span.end = uint32(cursor)
+		spans[i] = span
+	}
+	if cursor != size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", cursor, size)
+	}
+	return spans, nil
+}
+
+func parseSliceFromBytes(data []byte, span elementSpan, element MessageElementDescriptor, sliceType reflect.Type) (interface{}, error) {
+	slice := reflect.MakeSlice(sliceType, int(span.length), int(span.length))
+	elemOffset := span.start
+	for i := 0; i < int(span.length); i++ {
+		elem := slice.Index(i)
+		if element.ElemKind != reflect.Struct {
+			value, err := parseGoTypeFromBytes(data, elemOffset, element.ElemKind)
+			if err != nil {
+				return nil, err
+			}
+			elem.Set(reflect.ValueOf(value).Convert(elem.Type()))
+		} else {
+			for _, field := range element.ElemStructure {
+				fieldValue, found := util.FindFieldByJSONTagPath(elem, field.FieldName)
+				if !found {
+					return nil, fmt.Errorf("field %s not found in elements of %s", field.FieldName, element.FieldName)
+				}
+				value, err := parseGoTypeFromBytes(data, elemOffset+field.Offset, field.Kind)
+				if err != nil {
+					return nil, err
+				}
+				fieldValue.Set(reflect.ValueOf(value).Convert(fieldValue.Type()))
+			}
+		}
+		elemOffset += element.ElemByteSize
+	}
+	return slice.Interface(), nil
+}
+
 // Extremely unsafe. Use with caution
 func parseGoTypeFromBytes(data []byte, offset uint32, kind reflect.Kind) (interface{}, error) {
 	if util.SizeOfSerializedKind(kind) > uint32(len(data))-offset {
 		return nil, fmt.Errorf("not enough data to parse %s", kind)
 	}
 	switch kind {
+	case reflect.Bool:
+		return data[offset] != 0, nil
 	case reflect.Uint8:
 		return uint8(data[offset]), nil
 	case reflect.Uint16:
@@ -348,3 +348,51 @@ func TestDeserializePlayerShootEvent(t *testing.T) {
 		t.Errorf("Deserialized result does not match expected.\nGot: %+v\nWant: %+v", result, expected)
 	}
 }
+
+func TestDeserializeSliceLengthMismatch(t *testing.T) {
+	spec := NewSpecification[testPositionsMessage](1_000_000_003, "TestPositions", "Test", SERVER_ONLY, nil)
+	position := []byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	cases := map[string][]byte{
+		"fewer elements than the prefix": append([]byte{1, 0, 0, 0, 2}, position...),
+		"more elements than the prefix":  append(append([]byte{1, 0, 0, 0, 1}, position...), position...),
+		"prefix over the max length":     {1, 0, 0, 0, 5},
+	}
+	for name, data := range cases {
+		if _, err := Deserialize(spec, data, true); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	empty, err := Deserialize(spec, []byte{1, 0, 0, 0, 0}, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if empty.Round != 1 || len(empty.Positions) != 0 {
+		t.Errorf("Expected round 1 with no positions, got %+v", empty)
+	}
+}
+
+func TestDeserializeV2Rejects(t *testing.T) {
+	spec := NewV2Specification[testLabelledShotMessage](1_000_000_005, "TestLabelledShot", "Test", SERVER_ONLY, nil)
+
+	cases := map[string][]byte{
+		"string past the end":      {0, 0, 0, 1, 0, 9, 'a', 'b', 0, 0, 0, 0, 0, 0, 3},
+		"string over its max size": {0, 0, 0, 1, 0, 9, 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 0, 0, 0, 0, 0, 0, 3},
+		"trailing bytes":           {0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 3, 3},
+		"invalid UTF-8":            {0, 0, 0, 1, 0, 1, 0xff, 0, 0, 0, 0, 0, 0, 3},
+	}
+	for name, data := range cases {
+		if _, err := Deserialize(spec, data, true); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	empty, err := Deserialize(spec, []byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 3}, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if empty.PlayerID != 1 || empty.CharCode != "" || empty.Label != "" || len(empty.Hits) != 0 || empty.Round != 3 {
+		t.Errorf("Expected player 1 in round 3 with nothing else, got %+v", empty)
+	}
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"log"
+	"reflect"
+)
+
+// Implemented by the DTOs in eventCodecs_gen.go, generated from their specifications with --tools --generate-go-codecs.
+// Serialize and Deserialize use these instead of reflection, as long as the codec matches the specification
+type generatedCodec interface {
+	binaryAppender
+	// Reads the message body, i.e. everything after the header
+	UnmarshalBinary(data []byte) error
+	// Layout of the structure the codec was generated from
+	codecLayout() string
+}
+
+// Implemented by the DTO itself rather than a pointer to it, so Serialize needn't take its address
+type binaryAppender interface {
+	// Appends the message body, i.e. everything after the header
+	AppendBinary(dst []byte) ([]byte, error)
+}
+
+// Whether dtoType has a generated codec of the same layout as the structure.
+// Codecs out of date are ignored with a warning, until regenerated
+func hasGeneratedCodec(dtoType reflect.Type, structure ComputedStructure) bool {
+	if dtoType == nil {
+		return false
+	}
+	codec, ok := reflect.New(dtoType).Interface().(generatedCodec)
+	if !ok {
+		return false
+	}
+	if codec.codecLayout() != structure.Layout() {
+		log.Printf("Warning: generated codec of %s is out of date, falling back to reflection. Regenerate with --tools --generate-go-codecs", dtoType.Name())
+		return false
+	}
+	return true
+}
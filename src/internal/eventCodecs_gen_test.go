@@ -0,0 +1,339 @@
+// Code generated by go test ./src/internal -run TestGeneratedCodecsAreUpToDate -update. DO NOT EDIT.
+
+package internal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"unicode/utf8"
+)
+
+// AppendBinary appends the body of a message, i.e. everything after the header, as described by its event specification
+func (m testPositionsMessage) AppendBinary(dst []byte) ([]byte, error) {
+	if len(m.Positions) > 4 {
+		return nil, fmt.Errorf("field 'positions': %d elements exceeds max length 4", len(m.Positions))
+	}
+	dst = slices.Grow(dst, 5+len(m.Positions)*12)
+	dst = append(dst, m.Round)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(m.Positions)))
+	for _, elem := range m.Positions {
+		dst = binary.BigEndian.AppendUint32(dst, elem.ID)
+		dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(elem.X))
+		dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(elem.Y))
+	}
+	return dst, nil
+}
+
+func (m testPositionsMessage) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// UnmarshalBinary reads the body of a message, i.e. everything after the header, as described by its event specification
+func (m *testPositionsMessage) UnmarshalBinary(data []byte) error {
+	if len(data) < 5 {
+		return fmt.Errorf("expected at least 5 bytes, got %d", len(data))
+	}
+	var length int
+	m.Round = data[0]
+	i := 1
+	length = int(binary.BigEndian.Uint32(data[i:]))
+	i += 4
+	if length > 4 {
+		return fmt.Errorf("length %d of positions exceeds max length 4", length)
+	}
+	if len(data)-i < length*12 {
+		return fmt.Errorf("not enough data to parse positions, expected %d bytes, got %d", length*12, len(data)-i)
+	}
+	m.Positions = make([]testPosition, length)
+	for j := range m.Positions {
+		m.Positions[j].ID = binary.BigEndian.Uint32(data[i:])
+		m.Positions[j].X = math.Float32frombits(binary.BigEndian.Uint32(data[i+4:]))
+		m.Positions[j].Y = math.Float32frombits(binary.BigEndian.Uint32(data[i+8:]))
+		i += 12
+	}
+	if i != len(data) {
+		return fmt.Errorf("expected %d bytes, got %d", i, len(data))
+	}
+	return nil
+}
+
+func (testPositionsMessage) codecLayout() string {
+	return "round:uint8;positions:[]{id:uint32,x:float32,y:float32}/4<=4"
+}
+
+// AppendBinary appends the body of a message, i.e. everything after the header, as described by its event specification
+func (m testFlagsMessage) AppendBinary(dst []byte) ([]byte, error) {
+	if len(m.Flags) > 1024 {
+		return nil, fmt.Errorf("field 'flags': %d elements exceeds max length 1024", len(m.Flags))
+	}
+	dst = slices.Grow(dst, 4+len(m.Flags)*1)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(m.Flags)))
+	for _, elem := range m.Flags {
+		if elem {
+			dst = append(dst, 1)
+		} else {
+			dst = append(dst, 0)
+		}
+	}
+	return dst, nil
+}
+
+func (m testFlagsMessage) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// UnmarshalBinary reads the body of a message, i.e. everything after the header, as described by its event specification
+func (m *testFlagsMessage) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("expected at least 4 bytes, got %d", len(data))
+	}
+	var length int
+	i := 0
+	length = int(binary.BigEndian.Uint32(data[i:]))
+	i += 4
+	if length > 1024 {
+		return fmt.Errorf("length %d of flags exceeds max length 1024", length)
+	}
+	if len(data)-i < length*1 {
+		return fmt.Errorf("not enough data to parse flags, expected %d bytes, got %d", length*1, len(data)-i)
+	}
+	m.Flags = make([]bool, length)
+	for j := range m.Flags {
+		m.Flags[j] = data[i] != 0
+		i += 1
+	}
+	if i != len(data) {
+		return fmt.Errorf("expected %d bytes, got %d", i, len(data))
+	}
+	return nil
+}
+
+func (testFlagsMessage) codecLayout() string {
+	return "flags:[]bool/4<=1024"
+}
+
+// AppendBinary appends the body of a message, i.e. everything after the header, as described by its event specification
+func (m testLabelledShotMessage) AppendBinary(dst []byte) ([]byte, error) {
+	if len(m.CharCode) > 8 {
+		return nil, fmt.Errorf("field 'code': %d bytes exceeds max size 8", len(m.CharCode))
+	}
+	if len(m.Label) > 1024 {
+		return nil, fmt.Errorf("field 'label': %d bytes exceeds max size 1024", len(m.Label))
+	}
+	if len(m.Hits) > 4 {
+		return nil, fmt.Errorf("field 'hits': %d elements exceeds max length 4", len(m.Hits))
+	}
+	dst = slices.Grow(dst, 13+len(m.CharCode)+len(m.Label)+len(m.Hits)*4)
+	dst = binary.BigEndian.AppendUint32(dst, m.PlayerID)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(m.CharCode)))
+	dst = append(dst, m.CharCode...)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(m.Label)))
+	dst = append(dst, m.Label...)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(m.Hits)))
+	for _, elem := range m.Hits {
+		dst = binary.BigEndian.AppendUint32(dst, elem)
+	}
+	dst = append(dst, m.Round)
+	return dst, nil
+}
+
+func (m testLabelledShotMessage) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// UnmarshalBinary reads the body of a message, i.e. everything after the header, as described by its event specification
+func (m *testLabelledShotMessage) UnmarshalBinary(data []byte) error {
+	if len(data) < 13 {
+		return fmt.Errorf("expected at least 13 bytes, got %d", len(data))
+	}
+	var length int
+	m.PlayerID = binary.BigEndian.Uint32(data[0:])
+	i := 4
+	length = int(binary.BigEndian.Uint16(data[i:]))
+	i += 2
+	if length > 8 {
+		return fmt.Errorf("size %d of code exceeds max size 8", length)
+	}
+	if len(data)-i < length {
+		return fmt.Errorf("not enough data to parse code, expected %d bytes, got %d", length, len(data)-i)
+	}
+	if !utf8.Valid(data[i : i+length]) {
+		return fmt.Errorf("invalid UTF-8 string")
+	}
+	m.CharCode = string(data[i : i+length])
+	i += length
+	if len(data)-i < 2 {
+		return fmt.Errorf("not enough data to parse length of label")
+	}
+	length = int(binary.BigEndian.Uint16(data[i:]))
+	i += 2
+	if length > 1024 {
+		return fmt.Errorf("size %d of label exceeds max size 1024", length)
+	}
+	if len(data)-i < length {
+		return fmt.Errorf("not enough data to parse label, expected %d bytes, got %d", length, len(data)-i)
+	}
+	if !utf8.Valid(data[i : i+length]) {
+		return fmt.Errorf("invalid UTF-8 string")
+	}
+	m.Label = string(data[i : i+length])
+	i += length
+	if len(data)-i < 4 {
+		return fmt.Errorf("not enough data to parse length of hits")
+	}
+	length = int(binary.BigEndian.Uint32(data[i:]))
+	i += 4
+	if length > 4 {
+		return fmt.Errorf("length %d of hits exceeds max length 4", length)
+	}
+	if len(data)-i < length*4 {
+		return fmt.Errorf("not enough data to parse hits, expected %d bytes, got %d", length*4, len(data)-i)
+	}
+	m.Hits = make([]uint32, length)
+	for j := range m.Hits {
+		m.Hits[j] = binary.BigEndian.Uint32(data[i:])
+		i += 4
+	}
+	if len(data)-i < 1 {
+		return fmt.Errorf("not enough data to parse round, expected 1 bytes, got %d", len(data)-i)
+	}
+	m.Round = data[i]
+	i += 1
+	if i != len(data) {
+		return fmt.Errorf("expected %d bytes, got %d", i, len(data))
+	}
+	return nil
+}
+
+func (testLabelledShotMessage) codecLayout() string {
+	return "id:uint32;code:string/2<=10;label:string/2<=1026;hits:[]uint32/4<=4;round:uint8"
+}
+
+// AppendBinary appends the body of a message, i.e. everything after the header, as described by its event specification
+func (m testPlacementsMessage) AppendBinary(dst []byte) ([]byte, error) {
+	if len(m.Players) > 4 {
+		return nil, fmt.Errorf("field 'players': %d elements exceeds max length 4", len(m.Players))
+	}
+	dst = slices.Grow(dst, 12+len(m.Players)*12)
+	dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(m.Origin.X))
+	dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(m.Origin.Y))
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(m.Players)))
+	for _, elem := range m.Players {
+		dst = binary.BigEndian.AppendUint32(dst, elem.ID)
+		dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(elem.Position.X))
+		dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(elem.Position.Y))
+	}
+	return dst, nil
+}
+
+func (m testPlacementsMessage) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// UnmarshalBinary reads the body of a message, i.e. everything after the header, as described by its event specification
+func (m *testPlacementsMessage) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("expected at least 12 bytes, got %d", len(data))
+	}
+	var length int
+	m.Origin.X = math.Float32frombits(binary.BigEndian.Uint32(data[0:]))
+	m.Origin.Y = math.Float32frombits(binary.BigEndian.Uint32(data[4:]))
+	i := 8
+	length = int(binary.BigEndian.Uint32(data[i:]))
+	i += 4
+	if length > 4 {
+		return fmt.Errorf("length %d of players exceeds max length 4", length)
+	}
+	if len(data)-i < length*12 {
+		return fmt.Errorf("not enough data to parse players, expected %d bytes, got %d", length*12, len(data)-i)
+	}
+	m.Players = make([]testPlayerPlacement, length)
+	for j := range m.Players {
+		m.Players[j].ID = binary.BigEndian.Uint32(data[i:])
+		m.Players[j].Position.X = math.Float32frombits(binary.BigEndian.Uint32(data[i+4:]))
+		m.Players[j].Position.Y = math.Float32frombits(binary.BigEndian.Uint32(data[i+8:]))
+		i += 12
+	}
+	if i != len(data) {
+		return fmt.Errorf("expected %d bytes, got %d", i, len(data))
+	}
+	return nil
+}
+
+func (testPlacementsMessage) codecLayout() string {
+	return "origin.x:float32;origin.y:float32;players:[]{id:uint32,position.x:float32,position.y:float32}/4<=4"
+}
+
+// AppendBinary appends the body of a message, i.e. everything after the header, as described by its event specification
+func (m testLongTextMessage) AppendBinary(dst []byte) ([]byte, error) {
+	if len(m.Title) > 16 {
+		return nil, fmt.Errorf("field 'title': %d bytes exceeds max size 16", len(m.Title))
+	}
+	if len(m.Text) > 100000 {
+		return nil, fmt.Errorf("field 'text': %d bytes exceeds max size 100000", len(m.Text))
+	}
+	dst = slices.Grow(dst, 7+len(m.Title)+len(m.Text))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(m.Title)))
+	dst = append(dst, m.Title...)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(m.Text)))
+	dst = append(dst, m.Text...)
+	dst = append(dst, m.Round)
+	return dst, nil
+}
+
+func (m testLongTextMessage) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// UnmarshalBinary reads the body of a message, i.e. everything after the header, as described by its event specification
+func (m *testLongTextMessage) UnmarshalBinary(data []byte) error {
+	if len(data) < 7 {
+		return fmt.Errorf("expected at least 7 bytes, got %d", len(data))
+	}
+	var length int
+	i := 0
+	length = int(binary.BigEndian.Uint16(data[i:]))
+	i += 2
+	if length > 16 {
+		return fmt.Errorf("size %d of title exceeds max size 16", length)
+	}
+	if len(data)-i < length {
+		return fmt.Errorf("not enough data to parse title, expected %d bytes, got %d", length, len(data)-i)
+	}
+	if !utf8.Valid(data[i : i+length]) {
+		return fmt.Errorf("invalid UTF-8 string")
+	}
+	m.Title = string(data[i : i+length])
+	i += length
+	if len(data)-i < 4 {
+		return fmt.Errorf("not enough data to parse length of text")
+	}
+	length = int(binary.BigEndian.Uint32(data[i:]))
+	i += 4
+	if length > 100000 {
+		return fmt.Errorf("size %d of text exceeds max size 100000", length)
+	}
+	if len(data)-i < length {
+		return fmt.Errorf("not enough data to parse text, expected %d bytes, got %d", length, len(data)-i)
+	}
+	if !utf8.Valid(data[i : i+length]) {
+		return fmt.Errorf("invalid UTF-8 string")
+	}
+	m.Text = string(data[i : i+length])
+	i += length
+	if len(data)-i < 1 {
+		return fmt.Errorf("not enough data to parse round, expected 1 bytes, got %d", len(data)-i)
+	}
+	m.Round = data[i]
+	i += 1
+	if i != len(data) {
+		return fmt.Errorf("expected %d bytes, got %d", i, len(data))
+	}
+	return nil
+}
+
+func (testLongTextMessage) codecLayout() string {
+	return "title:string/2<=18;text:string/4<=100004;round:uint8"
+}
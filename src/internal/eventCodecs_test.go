@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var updateGeneratedCodecs = flag.Bool("update", false, "regenerate eventCodecs_gen.go and eventCodecs_gen_test.go")
+
+// DTOs of other tests, given codecs in eventCodecs_gen_test.go so the codecs of slices, nested structs and WIRE_FORMAT_V2 are covered as well.
+// If any of them change, delete eventCodecs_gen_test.go and run go test ./src/internal -run TestGeneratedCodecsAreUpToDate -update
+var testCodecSpecs = []*EventSpecification[any]{
+	unsafeCastSpec(NewSpecification[testPositionsMessage](1_000_000_001, "TestPositions", "Test", SERVER_ONLY, nil)),
+	unsafeCastSpec(NewSpecification[testFlagsMessage](1_000_000_002, "TestFlags", "Test", SERVER_ONLY, nil)),
+	unsafeCastSpec(NewV2Specification[testLabelledShotMessage](1_000_000_004, "TestLabelledShot", "Test", SERVER_ONLY, nil)),
+	unsafeCastSpec(NewSpecification[testPlacementsMessage](1_000_000_006, "TestPlacements", "Test", SERVER_ONLY, nil)),
+	unsafeCastSpec(NewV2Specification[testLongTextMessage](1_000_000_008, "TestLongText", "Test", SERVER_ONLY, nil)),
+}
+
+func allEventSpecs() []*EventSpecification[any] {
+	var specs []*EventSpecification[any]
+	for _, events := range []map[MessageID]*EventSpecification[any]{LOBBY_MANAGEMENT_EVENTS, COLONY_EVENTS, MINIGAME_INITIATION_EVENTS, ALL_ASTEROIDS_EVENTS} {
+		for _, spec := range events {
+			specs = append(specs, spec)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].ID < specs[j].ID })
+	return specs
+}
+
+func checkGeneratedCodecs(t *testing.T, path string, specs []*EventSpecification[any], generatedBy string) {
+	values := make([]EventSpecification[any], len(specs))
+	for i, spec := range specs {
+		values[i] = *spec
+	}
+	source, err := GenerateGoCodecs(values, generatedBy)
+	if err != nil {
+		t.Fatalf("failed to generate codecs: %v", err)
+	}
+	if *updateGeneratedCodecs {
+		if err := os.WriteFile(path, source, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		return
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(existing, source) {
+		t.Errorf("%s is out of date, regenerate with %s", path, generatedBy)
+	}
+}
+
+func TestGeneratedCodecsAreUpToDate(t *testing.T) {
+	checkGeneratedCodecs(t, "eventCodecs_gen.go", allEventSpecs(), "go run ./src --tools --generate-go-codecs")
+	checkGeneratedCodecs(t, "eventCodecs_gen_test.go", testCodecSpecs, "go test ./src/internal -run TestGeneratedCodecsAreUpToDate -update")
+}
+
+// Sets every field to some value derived from seed, with two elements in every slice
+func fillCodecSample(v reflect.Value, seed *int) {
+	*seed++
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(*seed%2 == 1)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(-*seed))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(*seed))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(*seed) + 0.25)
+	case reflect.String:
+		v.SetString(fmt.Sprintf("é%d", *seed))
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := 0; i < v.Len(); i++ {
+			fillCodecSample(v.Index(i), seed)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fillCodecSample(v.Field(i), seed)
+		}
+	}
+}
+
+func TestGeneratedCodecsMatchReflection(t *testing.T) {
+	for _, spec := range append(allEventSpecs(), testCodecSpecs...) {
+		if !spec.hasGeneratedCodec {
+			t.Errorf("expected %s to have a generated codec", spec.Name)
+			continue
+		}
+		seed := 0
+		sample := reflect.New(spec.DTOType)
+		fillCodecSample(sample.Elem(), &seed)
+
+		withCodec, err := Serialize(spec, sample.Elem().Interface())
+		if err != nil {
+			t.Errorf("%s: failed to serialize with codec: %v", spec.Name, err)
+			continue
+		}
+		withReflection, err := serializeWithReflection(spec, sample.Elem().Interface())
+		if err != nil {
+			t.Errorf("%s: failed to serialize with reflection: %v", spec.Name, err)
+			continue
+		}
+		if !bytes.Equal(withCodec, withReflection) {
+			t.Errorf("%s: serialized differently\ncodec:      %v\nreflection: %v", spec.Name, withCodec, withReflection)
+			continue
+		}
+
+		decoded := reflect.New(spec.DTOType)
+		if err := decoded.Interface().(generatedCodec).UnmarshalBinary(withCodec[4:]); err != nil {
+			t.Errorf("%s: failed to deserialize with codec: %v", spec.Name, err)
+			continue
+		}
+		reflected := reflect.New(spec.DTOType)
+		if err := deserializeWithReflection(spec.Structure, withCodec[4:], 0, reflected.Elem()); err != nil {
+			t.Errorf("%s: failed to deserialize with reflection: %v", spec.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded.Interface(), sample.Interface()) || !reflect.DeepEqual(reflected.Interface(), sample.Interface()) {
+			t.Errorf("%s: data changed during round trip:\noriginal:   %+v\ncodec:      %+v\nreflection: %+v", spec.Name, sample.Elem(), decoded.Elem(), reflected.Elem())
+		}
+	}
+}
+
+func TestOutdatedCodecIsIgnored(t *testing.T) {
+	structure := ASTEROID_SPAWN_EVENT.Structure
+	if !hasGeneratedCodec(ASTEROID_SPAWN_EVENT.DTOType, structure) {
+		t.Fatal("expected AsteroidSpawnMessageDTO to have a generated codec")
+	}
+	if hasGeneratedCodec(ASTEROID_SPAWN_EVENT.DTOType, structure[:len(structure)-1]) {
+		t.Error("expected a codec of a different layout to be ignored")
+	}
+	if hasGeneratedCodec(reflect.TypeOf(BasicMessage{}), ComputedStructure{}) {
+		t.Error("expected no codec for a DTO without one")
+	}
+}
+
+func benchmarkCodec[T any](b *testing.B, spec *EventSpecification[T], data T) {
+	message, err := Serialize(spec, data)
+	if err != nil {
+		b.Fatalf("failed to serialize: %v", err)
+	}
+	body := message[4:]
+
+	b.Run("serialize/codec", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Serialize(spec, data)
+		}
+	})
+	b.Run("serialize/reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			serializeWithReflection(spec, data)
+		}
+	})
+	b.Run("deserialize/codec", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Deserialize(spec, body, true)
+		}
+	})
+	b.Run("deserialize/reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var dest T
+			deserializeWithReflection(spec.Structure, body, 0, reflect.ValueOf(&dest).Elem())
+		}
+	})
+}
+
+func BenchmarkCodecAsteroidSpawn(b *testing.B) {
+	benchmarkCodec(b, ASTEROID_SPAWN_EVENT, AsteroidSpawnMessageDTO{ID: 1, X: 0.5, Y: 0.25, Health: 3, TimeUntilImpact: 5000, Type: 1, CharCode: "abcd"})
+}
+
+func BenchmarkCodecPlayerMove(b *testing.B) {
+	benchmarkCodec(b, PLAYER_MOVE_EVENT, PlayerMoveMessageDTO{PlayerID: 1, ColonyLocationID: 2})
+}
+
+func BenchmarkCodecPlacements(b *testing.B) {
+	spec := NewSpecification[testPlacementsMessage](1_000_000_006, "TestPlacements", "Test", SERVER_ONLY, nil)
+	benchmarkCodec(b, spec, testPlacementsMessage{
+		Origin:  testPoint2D{X: 0.5, Y: 0.25},
+		Players: []testPlayerPlacement{{ID: 1, Position: testPoint2D{X: 0.1, Y: 0.2}}, {ID: 2, Position: testPoint2D{X: 0.3, Y: 0.4}}},
+	})
+}
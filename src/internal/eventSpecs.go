@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"reflect"
+	"slices"
 	"strings"
 	"unsafe"
 
@@ -17,8 +20,22 @@ const (
 	ORIGIN_TYPE_GUEST  OriginType = "guest"
 	ORIGIN_TYPE_OWNER  OriginType = "owner"
 	ORIGIN_TYPE_SERVER OriginType = "server"
+	// A player with additional privileges, fx. kicking other players. Counts as a guest otherwise
+	ORIGIN_TYPE_MODERATOR OriginType = "moderator"
+	// Recieves broadcasts but may not send anything
+	ORIGIN_TYPE_SPECTATOR OriginType = "spectator"
 )
 
+// Maps a role as found in a join ticket to the origin type of the client. The server role is never handed out to clients
+func OriginTypeOfRole(role string) (OriginType, error) {
+	switch role {
+	case ORIGIN_TYPE_OWNER, ORIGIN_TYPE_GUEST, ORIGIN_TYPE_MODERATOR, ORIGIN_TYPE_SPECTATOR:
+		return role, nil
+	default:
+		return "", fmt.Errorf("role %q can not be assigned to a client", role)
+	}
+}
+
 // Lobby, Client, MessageID, Message Data
 type AbstractEventHandler[T any] func(*Lobby, *Client, *EventSpecification[T], []byte) error
 
@@ -33,17 +50,43 @@ type EventSpecification[T any] struct {
 	IDBytes         []byte
 	//In bytes, excluding message header
 	ExpectedMinSize uint32
+	//In bytes, excluding message header. Messages any larger are rejected
+	ExpectedMaxSize uint32
 	Name            string
 	Comment         string
 	// The handler is invoked only after a series of checks have been completed:
 	//
 	// 1. The client is part of the targeted lobby
 	//
-	// 2. The client is allowed to send the message
+	// 2. The client is allowed to send the message, as per SendPermissions and Policies
 	//
-	// 3. The message is of at least the expected size
+	// 3. The message is of at least the expected size and at most the expected max size
 	Handler   AbstractEventHandler[T]
 	Structure ComputedStructure
+	// WIRE_FORMAT_V1 unless created with NewV2Specification
+	WireFormat WireFormat
+	// Of Structure, see ComputedStructure.SchemaHash
+	SchemaHash string
+	// Optional, per client limit on how often this event may be sent. Applied on top of the per client limit across all events
+	RateLimit *RateLimit
+	// Optional, evaluated after SendPermissions. All must be satisfied
+	Policies []AuthorizationPolicy
+	// The type of T, the DTO of the message
+	DTOType reflect.Type
+	// Whether T has a generated codec matching Structure, used by Serialize and Deserialize instead of reflection
+	hasGeneratedCodec bool
+}
+
+// Token bucket parameters
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Limits how often any one client may send this event. Messages over the limit are dropped
+func (eSpec *EventSpecification[T]) WithRateLimit(perSecond float64, burst int) *EventSpecification[T] {
+	eSpec.RateLimit = &RateLimit{PerSecond: perSecond, Burst: burst}
+	return eSpec
 }
 
 func (eSpec *EventSpecification[T]) CopyIDBytes() []byte {
@@ -56,6 +99,18 @@ func (eSpec *EventSpecification[T]) CopyIDBytes() []byte {
 // Which, for all server-only events, is nothing.
 func NewSpecification[T any](id MessageID, name string, comment string, whoMaySend map[OriginType]bool,
 	handler AbstractEventHandler[T]) *EventSpecification[T] {
+	return newSpecification(id, name, comment, whoMaySend, handler, WIRE_FORMAT_V1)
+}
+
+// As NewSpecification, but in WIRE_FORMAT_V2. Every string is length prefixed,
+// so there may be any amount of strings and slices in any position
+func NewV2Specification[T any](id MessageID, name string, comment string, whoMaySend map[OriginType]bool,
+	handler AbstractEventHandler[T]) *EventSpecification[T] {
+	return newSpecification(id, name, comment, whoMaySend, handler, WIRE_FORMAT_V2)
+}
+
+func newSpecification[T any](id MessageID, name string, comment string, whoMaySend map[OriginType]bool,
+	handler AbstractEventHandler[T], format WireFormat) *EventSpecification[T] {
 
 	var idAsBytes = util.BytesOfUint32(id)
 	derived, computeErr := DeriveReferenceDescriptionFromT[T]()
@@ -63,20 +118,27 @@ func NewSpecification[T any](id MessageID, name string, comment string, whoMaySe
 		var tNull T
 		panic(fmt.Sprintf("Specification error: Error deriving reference description for %s: %s", reflect.TypeOf(tNull).String(), computeErr.Error()))
 	}
-	minContentSize, computed := ComputeStructure(name, derived)
+	minContentSize, computed := ComputeStructureForWireFormat(name, derived, format)
 	err := VerifyStructureTCompliance[T](computed)
 	if err != nil {
 		panic(fmt.Sprintf("Specification error: Error verifying T <=> Structure compliance: %v", err))
 	}
+	var tNull T
+	dtoType := reflect.TypeOf(tNull)
 	return &EventSpecification[T]{
-		Name:            name,
-		SendPermissions: whoMaySend,
-		ID:              id,
-		Handler:         handler,
-		IDBytes:         idAsBytes,
-		ExpectedMinSize: minContentSize,
-		Structure:       computed,
-		Comment:         comment,
+		Name:              name,
+		SendPermissions:   whoMaySend,
+		ID:                id,
+		Handler:           handler,
+		IDBytes:           idAsBytes,
+		ExpectedMinSize:   minContentSize,
+		ExpectedMaxSize:   ComputeMaxSize(computed),
+		Structure:         computed,
+		Comment:           comment,
+		WireFormat:        format,
+		SchemaHash:        computed.SchemaHash(),
+		DTOType:           dtoType,
+		hasGeneratedCodec: hasGeneratedCodec(dtoType, computed),
 	}
 }
 
@@ -84,46 +146,95 @@ func NewSpecification[T any](id MessageID, name string, comment string, whoMaySe
 // Will error if the type is not a struct or general field name isn't provided with the JSON tag.
 func DeriveReferenceDescriptionFromT[T any]() (ReferenceStructure, error) {
 	var tNull T
-	tVal := reflect.ValueOf(tNull)
+	return deriveReferenceDescription(reflect.TypeOf(tNull))
+}
 
-	if tVal.Type().Kind() != reflect.Struct {
+func deriveReferenceDescription(t reflect.Type) (ReferenceStructure, error) {
+	if t.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("t is not a struct")
 	}
 
 	var result ReferenceStructure
-	for i := 0; i < tVal.Type().NumField(); i++ {
-		field := tVal.Type().Field(i)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
 		fieldName, err := util.GetFieldNameFromTag(field)
 		if err != nil {
-			return nil, fmt.Errorf("unable to derive reference structure for %s: %s", tVal.Type().String(), err)
+			return nil, fmt.Errorf("unable to derive reference structure for %s: %s", t.String(), err)
 		}
 		kind := field.Type.Kind()
 		comment, err := util.GetCommentValue(field)
 		if err != nil {
-			log.Printf("Warning: Deriving reference structure for %s: %s", tVal.Type().String(), err)
+			log.Printf("Warning: Deriving reference structure for %s: %s", t.String(), err)
 			comment = "no comment provided"
 		}
-		result = append(result, NewElementDescriptor(comment, fieldName, kind))
+		descriptor := NewElementDescriptor(comment, fieldName, kind)
+		maxSize, hasMaxSize, err := util.GetMaxSizeValue(field)
+		if err != nil {
+			return nil, fmt.Errorf("unable to derive reference structure for %s: %s", t.String(), err)
+		}
+		if hasMaxSize {
+			if kind != reflect.String {
+				return nil, fmt.Errorf("unable to derive reference structure for %s: maxSize tag on non-string field %s", t.String(), field.Name)
+			}
+			descriptor.MaxByteSize = maxSize
+		}
+		maxLength, hasMaxLength, err := util.GetMaxLengthValue(field)
+		if err != nil {
+			return nil, fmt.Errorf("unable to derive reference structure for %s: %s", t.String(), err)
+		}
+		if hasMaxLength && kind != reflect.Slice {
+			return nil, fmt.Errorf("unable to derive reference structure for %s: maxLength tag on non-slice field %s", t.String(), field.Name)
+		}
+		if kind == reflect.Slice {
+			descriptor.MaxLength = maxLength
+			descriptor.ElemKind = field.Type.Elem().Kind()
+			if descriptor.ElemKind == reflect.Struct {
+				if descriptor.ElemStructure, err = deriveReferenceDescription(field.Type.Elem()); err != nil {
+					return nil, fmt.Errorf("unable to derive reference structure for elements of %s.%s: %s", t.String(), field.Name, err)
+				}
+			}
+		}
+		if kind == reflect.Struct {
+			if descriptor.Fields, err = deriveReferenceDescription(field.Type); err != nil {
+				return nil, fmt.Errorf("unable to derive reference structure for %s.%s: %s", t.String(), field.Name, err)
+			}
+		}
+		result = append(result, descriptor)
 	}
 	return result, nil
 }
 
 var OWNER_ONLY = map[OriginType]bool{
-	ORIGIN_TYPE_GUEST:  false,
-	ORIGIN_TYPE_OWNER:  true,
-	ORIGIN_TYPE_SERVER: false,
+	ORIGIN_TYPE_GUEST:     false,
+	ORIGIN_TYPE_OWNER:     true,
+	ORIGIN_TYPE_SERVER:    false,
+	ORIGIN_TYPE_MODERATOR: false,
+	ORIGIN_TYPE_SPECTATOR: false,
 }
 
 var SERVER_ONLY = map[OriginType]bool{
-	ORIGIN_TYPE_GUEST:  false,
-	ORIGIN_TYPE_OWNER:  false,
-	ORIGIN_TYPE_SERVER: true,
+	ORIGIN_TYPE_GUEST:     false,
+	ORIGIN_TYPE_OWNER:     false,
+	ORIGIN_TYPE_SERVER:    true,
+	ORIGIN_TYPE_MODERATOR: false,
+	ORIGIN_TYPE_SPECTATOR: false,
 }
 
+// Moderators are players too
 var OWNER_AND_GUESTS = map[OriginType]bool{
-	ORIGIN_TYPE_GUEST:  true,
-	ORIGIN_TYPE_OWNER:  true,
-	ORIGIN_TYPE_SERVER: false,
+	ORIGIN_TYPE_GUEST:     true,
+	ORIGIN_TYPE_OWNER:     true,
+	ORIGIN_TYPE_SERVER:    false,
+	ORIGIN_TYPE_MODERATOR: true,
+	ORIGIN_TYPE_SPECTATOR: false,
+}
+
+var OWNER_AND_MODERATORS = map[OriginType]bool{
+	ORIGIN_TYPE_GUEST:     false,
+	ORIGIN_TYPE_OWNER:     true,
+	ORIGIN_TYPE_SERVER:    false,
+	ORIGIN_TYPE_MODERATOR: true,
+	ORIGIN_TYPE_SPECTATOR: false,
 }
 
 var DEBUG_EVENT = NewSpecification[DebugEventMessageDTO](1, "DebugInfo", "For debug messages", SERVER_ONLY, Handlers_OnDebugMessageRecieved)
@@ -131,6 +242,9 @@ var DEBUG_EVENT = NewSpecification[DebugEventMessageDTO](1, "DebugInfo", "For de
 var SERVER_CLOSING_EVENT = NewSpecification[EmptyDTO](2, "ServerClosing", "Sent when the server shuts down, followed by LOBBY CLOSING",
 	SERVER_ONLY, Handlers_IntentionalIgnoreHandler)
 
+var SENDER_ID_REJECTED_EVENT = NewSpecification[SenderIDRejectedMessageDTO](3, "SenderIDRejected", "Sent to a client whose message had a sender id other than its own. The message is discarded",
+	SERVER_ONLY, Handlers_IntentionalIgnoreHandler)
+
 // Full range: 0 to 4,294,967,295
 //
 // 1-10: System events, 0 is the nil value for uint32, so it's not used
@@ -142,7 +256,7 @@ var SERVER_CLOSING_EVENT = NewSpecification[EmptyDTO](2, "ServerClosing", "Sent
 // 2000-2999: Minigame Initiation Events
 //
 // 1_000_000_000+: Game Events
-var ALL_EVENTS = NewSpecMap(DEBUG_EVENT, SERVER_CLOSING_EVENT)
+var ALL_EVENTS = NewSpecMap(DEBUG_EVENT, SERVER_CLOSING_EVENT, SENDER_ID_REJECTED_EVENT)
 
 // Use only with instances of EventSpecification[T extends any]
 //
@@ -178,14 +292,33 @@ var PLAYER_LEFT_EVENT = NewSpecification[PlayerLeftMessageDTO](12, "PlayerLeft",
 var LOBBY_CLOSING_EVENT = NewSpecification[EmptyDTO](13, "LobbyClosing", "Sent when the lobby closes", SERVER_ONLY,
 	Handlers_IntentionalIgnoreHandler)
 
+var RESUME_TOKEN_EVENT = NewSpecification[ResumeTokenMessageDTO](14, "ResumeToken", "Sent only to the joining player. Holds the token needed to resume the session after a dropped connection",
+	SERVER_ONLY, Handlers_IntentionalIgnoreHandler) // Handled internally
+
+var PLAYER_RECONNECTED_EVENT = NewSpecification[PlayerReconnectedMessageDTO](15, "PlayerReconnected", "Sent when a player resumes their session after a dropped connection",
+	SERVER_ONLY, Handlers_IntentionalIgnoreHandler) // Handled internally
+
+var OWNER_CHANGED_EVENT = NewSpecification[OwnerChangedMessageDTO](16, "OwnerChanged", "Sent when the owner disconnected and another player took over the lobby",
+	SERVER_ONLY, Handlers_IntentionalIgnoreHandler) // Handled internally
+
+var KICK_PLAYER_EVENT = NewSpecification[KickPlayerMessageDTO](17, "KickPlayer", "Sent by the owner or a moderator to remove a player from the lobby. Replicated to everyone, including the kicked player, before they are disconnected",
+	OWNER_AND_MODERATORS, Handlers_OnKickPlayer)
+
+var BAN_PLAYER_EVENT = NewSpecification[BanPlayerMessageDTO](18, "BanPlayer", "As KickPlayer, but the player is not allowed back into the lobby for as long as it exists",
+	OWNER_AND_MODERATORS, Handlers_OnBanPlayer)
+
+var QUEUE_POSITION_EVENT = NewSpecification[QueuePositionMessageDTO](19, "QueuePosition", "Sent only to clients waiting to join a full lobby, whenever their position in the join queue changes. Nothing they send is handled before they are admitted",
+	SERVER_ONLY, Handlers_IntentionalIgnoreHandler) // Handled internally
+
 // 10-999: Lobby Management
-var LOBBY_MANAGEMENT_EVENTS = NewSpecMap(PLAYER_JOINED_EVENT, PLAYER_LEFT_EVENT, LOBBY_CLOSING_EVENT)
+var LOBBY_MANAGEMENT_EVENTS = NewSpecMap(PLAYER_JOINED_EVENT, PLAYER_LEFT_EVENT, LOBBY_CLOSING_EVENT, RESUME_TOKEN_EVENT,
+	PLAYER_RECONNECTED_EVENT, OWNER_CHANGED_EVENT, KICK_PLAYER_EVENT, BAN_PLAYER_EVENT, QUEUE_POSITION_EVENT)
 
 var ENTER_LOCATION_EVENT = NewSpecification[EnterLocationMessageDTO](1001, "EnterLocation", "Send when the owner enters a location",
 	OWNER_ONLY, Handlers_NoCheckReplicate)
 
 var PLAYER_MOVE_EVENT = NewSpecification[PlayerMoveMessageDTO](1002, "PlayerMove", "Sent when any player moves to some location",
-	OWNER_AND_GUESTS, Handlers_NoCheckReplicate)
+	OWNER_AND_GUESTS, Handlers_NoCheckReplicate).WithRateLimit(10, 20)
 
 var LOCATION_UPGRADE_EVENT = NewSpecification[LocationUpgradeMessageDTO](1003, "LocationUpgrade", "Sent from the server when a minigame is won which upgrades a location",
 	SERVER_ONLY, Handlers_IntentionalIgnoreHandler)
@@ -260,6 +393,21 @@ func InitEventSpecifications() error {
 	return nil
 }
 
+// Hash of the ID and schema hash of every event in ALL_EVENTS. Clients generated against the same event specifications
+// send the same version when connecting, see LobbyManager.CheckProtocolVersion
+func ProtocolVersion() string {
+	ids := make([]MessageID, 0, len(ALL_EVENTS))
+	for id := range ALL_EVENTS {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	hash := sha256.New()
+	for _, id := range ids {
+		fmt.Fprintf(hash, "%d:%s;", id, ALL_EVENTS[id].SchemaHash)
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8])
+}
+
 func loadEventsIntoAllEvents(events map[MessageID]*EventSpecification[any]) error {
 	for id, event := range events {
 		if existingEvent, ok := ALL_EVENTS[id]; ok {
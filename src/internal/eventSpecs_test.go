@@ -3,6 +3,8 @@ package internal
 import (
 	"reflect"
 	"testing"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
 )
 
 type testType1 = struct {
@@ -37,3 +39,117 @@ func TestDeriveReferenceStructure(t *testing.T) {
 		t.Error("Expected KindInt for Field2")
 	}
 }
+
+type testTypeWithMaxSize = struct {
+	Field1 uint32 `json:"field1" comment:"Fixed size"`
+	Field2 string `json:"field2" comment:"Bounded" maxSize:"16"`
+}
+
+type testTypeWithInvalidMaxSize = struct {
+	Field1 uint32 `json:"field1" comment:"Fixed size" maxSize:"16"`
+}
+
+func TestDeriveReferenceStructureMaxSize(t *testing.T) {
+	ref, err := DeriveReferenceDescriptionFromT[testTypeWithMaxSize]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref[0].MaxByteSize != 0 {
+		t.Errorf("Expected no max size for Field1, got %d", ref[0].MaxByteSize)
+	}
+	if ref[1].MaxByteSize != 16 {
+		t.Errorf("Expected max size 16 for Field2, got %d", ref[1].MaxByteSize)
+	}
+
+	if _, err := DeriveReferenceDescriptionFromT[testTypeWithInvalidMaxSize](); err == nil {
+		t.Error("Expected an error for a maxSize tag on a fixed size field")
+	}
+}
+
+type testTypeWithSlice = struct {
+	Field1 []uint16 `json:"field1" comment:"Bounded" maxLength:"4"`
+}
+
+type testTypeWithMaxSizeOnSlice = struct {
+	Field1 []uint16 `json:"field1" comment:"Bounded in bytes" maxSize:"8"`
+}
+
+type testTypeWithMaxLengthOnString = struct {
+	Field1 string `json:"field1" comment:"Not a slice" maxLength:"8"`
+}
+
+func TestDeriveReferenceStructureMaxLength(t *testing.T) {
+	ref, err := DeriveReferenceDescriptionFromT[testTypeWithSlice]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref[0].MaxLength != 4 || ref[0].ElemKind != reflect.Uint16 {
+		t.Errorf("Expected max length 4 of uint16 elements, got %d of %s", ref[0].MaxLength, ref[0].ElemKind)
+	}
+
+	if _, err := DeriveReferenceDescriptionFromT[testTypeWithMaxSizeOnSlice](); err == nil {
+		t.Error("Expected an error for a maxSize tag on a slice field")
+	}
+	if _, err := DeriveReferenceDescriptionFromT[testTypeWithMaxLengthOnString](); err == nil {
+		t.Error("Expected an error for a maxLength tag on a string field")
+	}
+}
+
+type testPoint = struct {
+	X float32 `json:"x" comment:"X"`
+	Y float32 `json:"y" comment:"Y"`
+}
+
+type testTypeWithNestedStruct = struct {
+	ID       uint32    `json:"id" comment:"ID"`
+	Position testPoint `json:"position" comment:"Position"`
+}
+
+func TestDeriveReferenceStructureNested(t *testing.T) {
+	ref, err := DeriveReferenceDescriptionFromT[testTypeWithNestedStruct]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref[1].Kind != reflect.Struct || len(ref[1].Fields) != 2 || ref[1].Fields[1].FieldName != "y" {
+		t.Errorf("Expected position to hold the fields x and y, got %+v", ref[1])
+	}
+}
+
+func TestCheckProtocolVersion(t *testing.T) {
+	version := ProtocolVersion()
+	if len(version) != 16 {
+		t.Fatalf("Expected a 16 character protocol version, got %q", version)
+	}
+
+	for _, test := range []struct {
+		policy        meta.ProtocolVersionPolicy
+		clientVersion string
+		rejected      bool
+	}{
+		{meta.PROTOCOL_VERSION_POLICY_REJECT, version, false},
+		{meta.PROTOCOL_VERSION_POLICY_REJECT, "0123456789abcdef", true},
+		{meta.PROTOCOL_VERSION_POLICY_REJECT, "", true},
+		{meta.PROTOCOL_VERSION_POLICY_WARN, "0123456789abcdef", false},
+		{meta.PROTOCOL_VERSION_POLICY_IGNORE, "", false},
+	} {
+		lm := &LobbyManager{configuration: &meta.RuntimeConfiguration{ProtocolVersionPolicy: test.policy}}
+		if err := lm.CheckProtocolVersion(test.clientVersion); (err != nil) != test.rejected {
+			t.Errorf("Policy %s, client version %q: expected rejected %t, got error %v", test.policy, test.clientVersion, test.rejected, err)
+		}
+	}
+}
+
+func TestIGNFieldsShareMaxSize(t *testing.T) {
+	initTestEventsOnce.Do(func() {
+		if err := InitEventSpecifications(); err != nil {
+			panic(err)
+		}
+	})
+	for _, spec := range ALL_EVENTS {
+		for _, element := range spec.Structure {
+			if element.FieldName == "ign" && element.MaxByteSize-element.LengthPrefixSize != MAX_IGN_SIZE {
+				t.Errorf("Expected the IGN of %s to be at most %d bytes, got %d", spec.Name, MAX_IGN_SIZE, element.MaxByteSize-element.LengthPrefixSize)
+			}
+		}
+	}
+}
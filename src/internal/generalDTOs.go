@@ -4,19 +4,61 @@ package internal
 
 type EmptyDTO struct{}
 
+// Max byte size of an IGN, as per the maxSize of every IGN field below. Longer IGNs couldn't be sent to anyone
+const MAX_IGN_SIZE = 64
+
+// Max byte size of a reason, as per the maxSize of every reason field below. Reasons written by the server are cut short to fit
+const MAX_REASON_SIZE = 256
+
 type DebugEventMessageDTO struct {
 	Code    uint32 `json:"code" comment:"HTTP Code-like (if applicable)"`
 	Message string `json:"message" comment:"Debug message"`
 }
 
+type SenderIDRejectedMessageDTO struct {
+	ExpectedID uint32 `json:"expectedID" comment:"ID of the client the connection belongs to"`
+	ReceivedID uint32 `json:"receivedID" comment:"Sender ID found in the message header"`
+	Offences   uint32 `json:"offences" comment:"Amount of messages rejected so far for this reason"`
+	Threshold  uint32 `json:"threshold" comment:"Amount of offences tolerated before being disconnected. 0 if never"`
+}
+
+type OwnerChangedMessageDTO struct {
+	PlayerID uint32 `json:"id" comment:"ID of the new owner"`
+	IGN      string `json:"ign" comment:"IGN of the new owner" maxSize:"64"`
+}
+
 type PlayerJoinedMessageDTO struct {
 	PlayerID uint32 `json:"id" comment:"Player ID"`
-	IGN      string `json:"ign" comment:"Player IGN"`
+	IGN      string `json:"ign" comment:"Player IGN" maxSize:"64"`
 }
 
 type PlayerLeftMessageDTO struct {
 	PlayerID uint32 `json:"id" comment:"Player ID"`
-	IGN      string `json:"ign" comment:"Player IGN"`
+	IGN      string `json:"ign" comment:"Player IGN" maxSize:"64"`
+}
+
+type ResumeTokenMessageDTO struct {
+	Token string `json:"token" comment:"Pass as resumeToken query param on /connect to resume the session after a dropped connection" maxSize:"64"`
+}
+
+type PlayerReconnectedMessageDTO struct {
+	PlayerID uint32 `json:"id" comment:"Player ID"`
+	IGN      string `json:"ign" comment:"Player IGN" maxSize:"64"`
+}
+
+type KickPlayerMessageDTO struct {
+	PlayerID uint32 `json:"id" comment:"ID of the player to kick"`
+	Reason   string `json:"reason" comment:"Shown to the kicked player" maxSize:"256"`
+}
+
+type BanPlayerMessageDTO struct {
+	PlayerID uint32 `json:"id" comment:"ID of the player to ban"`
+	Reason   string `json:"reason" comment:"Shown to the banned player" maxSize:"256"`
+}
+
+type QueuePositionMessageDTO struct {
+	Position    uint32 `json:"position" comment:"Position in the join queue, 1 being the next to be admitted"`
+	QueueLength uint32 `json:"queueLength" comment:"Amount of clients in the join queue"`
 }
 
 type EnterLocationMessageDTO struct {
@@ -37,50 +79,50 @@ type DifficultySelectForMinigameMessageDTO struct {
 	ColonyLocationID uint32 `json:"colonyLocationID" comment:"Colony Location id"`
 	MinigameID       uint32 `json:"minigameID" comment:"Minigame ID"`
 	DifficultyID     uint32 `json:"difficultyID" comment:"Difficulty ID"`
-	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name"`
+	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name" maxSize:"64"`
 }
 
 type DifficultyConfirmedForMinigameMessageDTO struct {
 	ColonyLocationID uint32 `json:"colonyLocationID" comment:"Colony Location id"`
 	MinigameID       uint32 `json:"minigameID" comment:"Minigame ID"`
 	DifficultyID     uint32 `json:"difficultyID" comment:"Difficulty ID"`
-	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name"`
+	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name" maxSize:"64"`
 }
 
 type PlayerReadyMessageDTO struct {
 	PlayerID uint32 `json:"id" comment:"Player ID"`
-	IGN      string `json:"ign" comment:"Player IGN"`
+	IGN      string `json:"ign" comment:"Player IGN" maxSize:"64"`
 }
 
 type PlayerAbortingMinigameMessageDTO struct {
 	PlayerID uint32 `json:"id" comment:"Player ID"`
-	IGN      string `json:"ign" comment:"IGN"`
+	IGN      string `json:"ign" comment:"IGN" maxSize:"64"`
 }
 
 type PlayerJoinActivityMessageDTO struct {
 	PlayerID uint32 `json:"id" comment:"Player ID"`
-	IGN      string `json:"ign" comment:"Player IGN"`
+	IGN      string `json:"ign" comment:"Player IGN" maxSize:"64"`
 }
 
 type PlayerLoadFailureMessageDTO struct {
-	Reason string `json:"reason" comment:"Reason"`
+	Reason string `json:"reason" comment:"Reason" maxSize:"256"`
 }
 
 type GenericUntimelyAbortMessageDTO struct {
 	SourceID uint32 `json:"id" comment:"ID of source (player or server or other)"`
-	Reason   string `json:"reason" comment:"Reason"`
+	Reason   string `json:"reason" comment:"Reason" maxSize:"256"`
 }
 
 type MinigameLostMessageDTO struct {
 	ColonyLocationID uint32 `json:"colonyLocationID" comment:"Colony Location ID"`
 	MinigameID       uint32 `json:"minigameID" comment:"Minigame ID"`
 	DifficultyID     uint32 `json:"difficultyID" comment:"Difficulty ID"`
-	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name"`
+	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name" maxSize:"64"`
 }
 
 type MinigameWonMessageDTO struct {
 	ColonyLocationID uint32 `json:"colonyLocationID" comment:"Colony Location ID"`
 	MinigameID       uint32 `json:"minigameID" comment:"Minigame ID"`
 	DifficultyID     uint32 `json:"difficultyID" comment:"Difficulty ID"`
-	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name"`
+	DifficultyName   string `json:"difficultyName" comment:"Difficulty Name" maxSize:"64"`
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/gorilla/websocket"
 	"github.com/lilybw/bsc-multiplayer-backend/src/util"
 )
 
@@ -32,3 +33,59 @@ func Handlers_OnDebugMessageRecieved[T DebugEventMessageDTO](lobby *Lobby, clien
 	log.Printf("[debug event] %s", fmt.Sprintf("Client id %d says: %s", client.ID, string(remainder)))
 	return nil
 }
+
+func Handlers_OnKickPlayer(lobby *Lobby, client *Client, spec *EventSpecification[KickPlayerMessageDTO], remainder []byte) error {
+	deserialized, err := Deserialize(spec, remainder, true)
+	if err != nil {
+		return err
+	}
+	target, err := findRemovableClient(lobby, client, deserialized.PlayerID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[lobby] User %d kicked user %d from lobby %d: %s", client.ID, target.ID, lobby.ID, deserialized.Reason)
+	return replicateAndRemove(lobby, client, target, spec.IDBytes, remainder, "Kicked: "+deserialized.Reason)
+}
+
+func Handlers_OnBanPlayer(lobby *Lobby, client *Client, spec *EventSpecification[BanPlayerMessageDTO], remainder []byte) error {
+	deserialized, err := Deserialize(spec, remainder, true)
+	if err != nil {
+		return err
+	}
+	target, err := findRemovableClient(lobby, client, deserialized.PlayerID)
+	if err != nil {
+		return err
+	}
+	lobby.Ban(target.ID, deserialized.Reason)
+	log.Printf("[lobby] User %d banned user %d from lobby %d: %s", client.ID, target.ID, lobby.ID, deserialized.Reason)
+	return replicateAndRemove(lobby, client, target, spec.IDBytes, remainder, "Banned: "+deserialized.Reason)
+}
+
+// Returns the client the sender wants removed, if the sender is allowed to remove it.
+// No one can remove themselves or the owner, and moderators cannot remove each other
+func findRemovableClient(lobby *Lobby, sender *Client, targetID ClientID) (*Client, error) {
+	target, exists := lobby.Clients.Load(targetID)
+	if !exists {
+		return nil, fmt.Errorf("client %d is not in the lobby", targetID)
+	}
+	if target.ID == sender.ID {
+		return nil, fmt.Errorf("clients cannot remove themselves")
+	}
+	if target.Type() == ORIGIN_TYPE_OWNER {
+		return nil, fmt.Errorf("the lobby owner cannot be removed")
+	}
+	if sender.Type() == ORIGIN_TYPE_MODERATOR && target.Type() == ORIGIN_TYPE_MODERATOR {
+		return nil, fmt.Errorf("moderators cannot remove other moderators")
+	}
+	return target, nil
+}
+
+// Lets everyone, including the target, know why the target is removed, then removes it
+func replicateAndRemove(lobby *Lobby, sender *Client, target *Client, idBytes []byte, remainder []byte, closeReason string) error {
+	unresponsive := lobby.BroadcastMessage(sender.ID, util.CopyAndAppend(idBytes, remainder))
+	lobby.RemoveClientWithReason(target, websocket.ClosePolicyViolation, closeReason)
+	if len(unresponsive) > 0 {
+		return &UnresponsiveClientsError{UnresponsiveClients: unresponsive}
+	}
+	return nil
+}
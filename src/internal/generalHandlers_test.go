@@ -0,0 +1,58 @@
+package internal
+
+import "testing"
+
+func TestFindRemovableClient(t *testing.T) {
+	lobby := &Lobby{}
+	newClient := func(id ClientID, clientType OriginType) *Client {
+		client := &Client{ID: id}
+		client.setType(clientType)
+		lobby.Clients.Store(id, client)
+		return client
+	}
+	owner := newClient(1, ORIGIN_TYPE_OWNER)
+	moderator := newClient(2, ORIGIN_TYPE_MODERATOR)
+	otherModerator := newClient(3, ORIGIN_TYPE_MODERATOR)
+	guest := newClient(4, ORIGIN_TYPE_GUEST)
+
+	cases := []struct {
+		name      string
+		sender    *Client
+		targetID  ClientID
+		removable bool
+	}{
+		{"owner removes guest", owner, guest.ID, true},
+		{"owner removes moderator", owner, moderator.ID, true},
+		{"moderator removes guest", moderator, guest.ID, true},
+		{"moderator removes moderator", moderator, otherModerator.ID, false},
+		{"moderator removes owner", moderator, owner.ID, false},
+		{"owner removes self", owner, owner.ID, false},
+		{"unknown target", owner, 42, false},
+	}
+	for _, c := range cases {
+		target, err := findRemovableClient(lobby, c.sender, c.targetID)
+		if c.removable && (err != nil || target.ID != c.targetID) {
+			t.Errorf("%s: expected client %d to be removable, got %v", c.name, c.targetID, err)
+		}
+		if !c.removable && err == nil {
+			t.Errorf("%s: expected client %d to not be removable", c.name, c.targetID)
+		}
+	}
+}
+
+func TestTruncateCloseReason(t *testing.T) {
+	short := "Kicked: spam"
+	if truncateCloseReason(short) != short {
+		t.Errorf("Expected short reason to be left as is")
+	}
+
+	// 2 byte characters, so byte 123 is in the middle of one
+	long := ""
+	for i := 0; i < 100; i++ {
+		long += "æ"
+	}
+	truncated := truncateCloseReason(long)
+	if len(truncated) != 122 {
+		t.Errorf("Expected reason to be cut at the last full character, got %d bytes", len(truncated))
+	}
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"log"
+	"slices"
+	"time"
+)
+
+// Owner tickets outlive a transfer of ownership, so anyone but the current owner joins as a guest
+func (lobby *Lobby) effectiveClientType(clientID ClientID, clientType OriginType) OriginType {
+	if clientType == ORIGIN_TYPE_OWNER && clientID != lobby.OwnerID.Load() {
+		return ORIGIN_TYPE_GUEST
+	}
+	return clientType
+}
+
+// Approximate. Whether a client of the given type would be let in right now.
+// Spectators and the owner always are, anyone else only if no one is queued ahead of them
+func (lobby *Lobby) HasRoomFor(clientType OriginType) bool {
+	lobby.admissionLock.Lock()
+	defer lobby.admissionLock.Unlock()
+	return lobby.hasRoomForLocked(clientType)
+}
+
+func (lobby *Lobby) hasRoomForLocked(clientType OriginType) bool {
+	if lobby.MaxPlayers == 0 || clientType == ORIGIN_TYPE_OWNER || clientType == ORIGIN_TYPE_SPECTATOR {
+		return true
+	}
+	return len(lobby.joinQueue) == 0 && uint32(lobby.PlayerCount()) < lobby.MaxPlayers
+}
+
+// Adds the client to the lobby if there is room for it. Otherwise the client is put in the join queue,
+// or rejected with JoinErrorFull if the lobby doesn't queue
+func (lobby *Lobby) admitOrQueue(client *Client) *LobbyJoinError {
+	lobby.admissionLock.Lock()
+	defer lobby.admissionLock.Unlock()
+
+	if slices.ContainsFunc(lobby.joinQueue, func(queued *Client) bool { return queued.ID == client.ID }) {
+		return &LobbyJoinError{Reason: "User is already in the join queue", Type: JoinErrorAlreadyInLobby, LobbyID: lobby.ID}
+	}
+	if lobby.hasRoomForLocked(client.Type()) {
+		return lobby.addClient(client)
+	}
+	if !lobby.QueueWhenFull {
+		return &LobbyJoinError{Reason: "Lobby is full", Type: JoinErrorFull, LobbyID: lobby.ID}
+	}
+
+	client.Queued.Store(true)
+	lobby.joinQueue = append(lobby.joinQueue, client)
+	log.Printf("[lobby] Lobby %d is full, user %d queued at position %d", lobby.ID, client.ID, len(lobby.joinQueue))
+	lobby.sendQueuePosition(client, len(lobby.joinQueue))
+	return nil
+}
+
+// Announces the client to everyone else, adds it to the lobby and sends it its resume token
+func (lobby *Lobby) addClient(client *Client) *LobbyJoinError {
+	if !client.IsSpectator() {
+		msg, err := Serialize(PLAYER_JOINED_EVENT, PlayerJoinedMessageDTO{
+			PlayerID: client.ID,
+			IGN:      client.IGN,
+		})
+		if err != nil {
+			return &LobbyJoinError{Reason: "Failed to serialize player joined message", Type: JoinErrorSerializationFailure, LobbyID: lobby.ID}
+		}
+
+		//Broadcasting before we add the client to the lobbies client map
+		lobby.BroadcastMessage(SERVER_ID, msg)
+	}
+
+	lobby.Clients.Store(client.ID, client)
+	lobby.lifecycle.Publish(&ClientJoinedEvent{
+		LobbyID:  lobby.ID,
+		ClientID: client.ID,
+		IGN:      client.IGN,
+		Type:     client.Type(),
+	})
+
+	tokenMsg, err := Serialize(RESUME_TOKEN_EVENT, ResumeTokenMessageDTO{Token: client.ResumeToken()})
+	if err != nil {
+		log.Printf("[lobby] Error serializing resume token for client %d: %v", client.ID, err)
+	} else if err := SendMessageToClient(client, SERVER_ID, tokenMsg); err != nil {
+		log.Printf("[lobby] Error sending resume token to client %d: %v", client.ID, err)
+	}
+	return nil
+}
+
+// Admits queued clients in the order they joined, for as long as there is room
+func (lobby *Lobby) admitFromJoinQueue() {
+	lobby.admissionLock.Lock()
+	defer lobby.admissionLock.Unlock()
+
+	admitted := false
+	for len(lobby.joinQueue) > 0 && !lobby.Closing.Load() && uint32(lobby.PlayerCount()) < lobby.MaxPlayers {
+		next := lobby.joinQueue[0]
+		lobby.joinQueue = lobby.joinQueue[1:]
+		// Time spent waiting doesn't count towards being the longest connected player
+		next.JoinedAt = time.Now()
+		next.Queued.Store(false)
+		if err := lobby.addClient(next); err != nil {
+			log.Printf("[lobby] Error admitting queued user %d to lobby %d: %v", next.ID, lobby.ID, err)
+			next.closeConnection()
+			continue
+		}
+		log.Printf("[lobby] Admitted queued user %d to lobby %d", next.ID, lobby.ID)
+		admitted = true
+	}
+	if admitted {
+		lobby.sendQueuePositionsLocked()
+	}
+}
+
+// Returns true if the client was in the join queue
+func (lobby *Lobby) removeFromJoinQueue(client *Client) bool {
+	lobby.admissionLock.Lock()
+	defer lobby.admissionLock.Unlock()
+
+	index := slices.Index(lobby.joinQueue, client)
+	if index == -1 {
+		return false
+	}
+	lobby.joinQueue = slices.Delete(lobby.joinQueue, index, index+1)
+	log.Printf("[lobby] User %d left the join queue of lobby %d", client.ID, lobby.ID)
+	lobby.sendQueuePositionsLocked()
+	return true
+}
+
+// Disconnects everyone still waiting
+func (lobby *Lobby) dropJoinQueue() {
+	lobby.admissionLock.Lock()
+	queued := lobby.joinQueue
+	lobby.joinQueue = make([]*Client, 0)
+	lobby.admissionLock.Unlock()
+
+	for _, client := range queued {
+		client.closeConnection()
+	}
+}
+
+// IDs of the clients in the join queue, in the order they'll be admitted
+func (lobby *Lobby) JoinQueue() []ClientID {
+	lobby.admissionLock.Lock()
+	defer lobby.admissionLock.Unlock()
+
+	ids := make([]ClientID, len(lobby.joinQueue))
+	for i, client := range lobby.joinQueue {
+		ids[i] = client.ID
+	}
+	return ids
+}
+
+func (lobby *Lobby) sendQueuePositionsLocked() {
+	for i, client := range lobby.joinQueue {
+		lobby.sendQueuePosition(client, i+1)
+	}
+}
+
+func (lobby *Lobby) sendQueuePosition(client *Client, position int) {
+	msg, err := Serialize(QUEUE_POSITION_EVENT, QueuePositionMessageDTO{
+		Position:    uint32(position),
+		QueueLength: uint32(len(lobby.joinQueue)),
+	})
+	if err != nil {
+		log.Printf("[lobby] Error serializing queue position event: %v", err)
+		return
+	}
+	if err := SendMessageToClient(client, SERVER_ID, msg); err != nil {
+		log.Printf("[lobby] Error sending queue position to user %d: %v", client.ID, err)
+	}
+}
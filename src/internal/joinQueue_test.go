@@ -0,0 +1,56 @@
+package internal
+
+import "testing"
+
+func TestHasRoomFor(t *testing.T) {
+	lobby := &Lobby{MaxPlayers: 2}
+	for id, clientType := range map[ClientID]OriginType{1: ORIGIN_TYPE_OWNER, 2: ORIGIN_TYPE_GUEST, 3: ORIGIN_TYPE_SPECTATOR} {
+		client := &Client{ID: id}
+		client.setType(clientType)
+		lobby.Clients.Store(id, client)
+	}
+
+	if lobby.HasRoomFor(ORIGIN_TYPE_GUEST) || lobby.HasRoomFor(ORIGIN_TYPE_MODERATOR) {
+		t.Error("Expected no room for players once MaxPlayers is reached, spectators not counted")
+	}
+	if !lobby.HasRoomFor(ORIGIN_TYPE_SPECTATOR) || !lobby.HasRoomFor(ORIGIN_TYPE_OWNER) {
+		t.Error("Expected spectators and the owner to always be let in")
+	}
+
+	lobby.Clients.Delete(2)
+	if !lobby.HasRoomFor(ORIGIN_TYPE_GUEST) {
+		t.Error("Expected room for a guest after a player left")
+	}
+	lobby.joinQueue = append(lobby.joinQueue, &Client{ID: 4})
+	if lobby.HasRoomFor(ORIGIN_TYPE_GUEST) {
+		t.Error("Expected no room for a guest while others are queued ahead of it")
+	}
+
+	lobby.MaxPlayers = 0
+	if !lobby.HasRoomFor(ORIGIN_TYPE_GUEST) {
+		t.Error("Expected no limit with MaxPlayers 0")
+	}
+}
+
+func TestOwnerTakesASeat(t *testing.T) {
+	lobby := &Lobby{MaxPlayers: 2}
+	owner := &Client{ID: 1}
+	owner.setType(ORIGIN_TYPE_OWNER)
+	lobby.Clients.Store(owner.ID, owner)
+
+	if !lobby.HasRoomFor(ORIGIN_TYPE_GUEST) {
+		t.Fatal("Expected room for MaxPlayers - 1 guests besides the owner")
+	}
+	guest := &Client{ID: 2}
+	guest.setType(ORIGIN_TYPE_GUEST)
+	lobby.Clients.Store(guest.ID, guest)
+	if lobby.HasRoomFor(ORIGIN_TYPE_GUEST) {
+		t.Error("Expected no room for another guest, as the owner takes one of the seats")
+	}
+
+	lobby.Clients.Delete(guest.ID)
+	lobby.MaxPlayers = 1
+	if lobby.HasRoomFor(ORIGIN_TYPE_GUEST) {
+		t.Error("Expected no room for guests with MaxPlayers 1")
+	}
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Published on the LifecycleBus. Switch on the concrete type to tell them apart
+type LifecycleEvent interface {
+	// ID of the lobby the event concerns
+	Lobby() LobbyID
+	String() string
+}
+
+type LobbyCreatedEvent struct {
+	LobbyID  LobbyID
+	ColonyID uint32
+	OwnerID  ClientID
+}
+
+type ClientJoinedEvent struct {
+	LobbyID  LobbyID
+	ClientID ClientID
+	IGN      string
+	Type     OriginType
+}
+
+type ClientLeftEvent struct {
+	LobbyID  LobbyID
+	ClientID ClientID
+	IGN      string
+	Type     OriginType
+	// Empty unless the client was removed for some reason, fx. kicked
+	Reason string
+}
+
+type PhaseChangedEvent struct {
+	LobbyID LobbyID
+	From    LobbyPhase
+	To      LobbyPhase
+}
+
+type MinigameStartedEvent struct {
+	LobbyID        LobbyID
+	ColonyID       uint32
+	MinigameID     uint32
+	DifficultyID   uint32
+	DifficultyName string
+	Participants   []ClientID
+}
+
+type MinigameEndedEvent struct {
+	LobbyID        LobbyID
+	ColonyID       uint32
+	MinigameID     uint32
+	DifficultyID   uint32
+	DifficultyName string
+	Participants   []ClientID
+	Outcome        MinigameState
+	// Since the minigame started
+	Duration time.Duration
+}
+
+type LobbyClosedEvent struct {
+	LobbyID       LobbyID
+	ColonyID      uint32
+	ColonyOwnerID ClientID
+}
+
+func (e *LobbyCreatedEvent) Lobby() LobbyID    { return e.LobbyID }
+func (e *ClientJoinedEvent) Lobby() LobbyID    { return e.LobbyID }
+func (e *ClientLeftEvent) Lobby() LobbyID      { return e.LobbyID }
+func (e *PhaseChangedEvent) Lobby() LobbyID    { return e.LobbyID }
+func (e *MinigameStartedEvent) Lobby() LobbyID { return e.LobbyID }
+func (e *MinigameEndedEvent) Lobby() LobbyID   { return e.LobbyID }
+func (e *LobbyClosedEvent) Lobby() LobbyID     { return e.LobbyID }
+
+func (e *LobbyCreatedEvent) String() string {
+	return fmt.Sprintf("lobby %d created for colony %d by user %d", e.LobbyID, e.ColonyID, e.OwnerID)
+}
+
+func (e *ClientJoinedEvent) String() string {
+	return fmt.Sprintf("user %d (%s) joined lobby %d as %s", e.ClientID, e.IGN, e.LobbyID, e.Type)
+}
+
+func (e *ClientLeftEvent) String() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("user %d (%s) removed from lobby %d: %s", e.ClientID, e.IGN, e.LobbyID, e.Reason)
+	}
+	return fmt.Sprintf("user %d (%s) left lobby %d", e.ClientID, e.IGN, e.LobbyID)
+}
+
+func (e *PhaseChangedEvent) String() string {
+	return fmt.Sprintf("lobby %d changed phase from %s to %s", e.LobbyID, e.From, e.To)
+}
+
+func (e *MinigameStartedEvent) String() string {
+	return fmt.Sprintf("lobby %d started minigame %d at difficulty %d with %d participant(s)", e.LobbyID, e.MinigameID, e.DifficultyID, len(e.Participants))
+}
+
+func (e *MinigameEndedEvent) String() string {
+	return fmt.Sprintf("lobby %d ended minigame %d at difficulty %d after %s: %s", e.LobbyID, e.MinigameID, e.DifficultyID, e.Duration, e.Outcome)
+}
+
+func (e *LobbyClosedEvent) String() string {
+	return fmt.Sprintf("lobby %d of colony %d closed", e.LobbyID, e.ColonyID)
+}
+
+type LifecycleSubscriber func(event LifecycleEvent)
+
+// Events queued per subscriber before any more are dropped for it
+const LIFECYCLE_SUBSCRIBER_QUEUE_SIZE = 1024
+
+type lifecycleSubscription struct {
+	name       string
+	subscriber LifecycleSubscriber
+	queue      chan LifecycleEvent
+	dropped    atomic.Uint64
+}
+
+// In process fan out of lifecycle events to any number of subscribers.
+//
+// Threadsafe
+type LifecycleBus struct {
+	// Protects subscriptions and closed
+	lock          sync.RWMutex
+	subscriptions []*lifecycleSubscription
+	closed        bool
+	dispatchers   sync.WaitGroup
+}
+
+func NewLifecycleBus() *LifecycleBus {
+	return &LifecycleBus{
+		subscriptions: make([]*lifecycleSubscription, 0),
+	}
+}
+
+// Each subscriber recieves events in the order they were published, on a routine of its own,
+// so a slow subscriber holds up neither the lobby nor any other subscriber
+func (bus *LifecycleBus) Subscribe(name string, subscriber LifecycleSubscriber) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	if bus.closed {
+		log.Printf("[lifecycle] Subscriber %s ignored, the bus is closed", name)
+		return
+	}
+
+	subscription := &lifecycleSubscription{
+		name:       name,
+		subscriber: subscriber,
+		queue:      make(chan LifecycleEvent, LIFECYCLE_SUBSCRIBER_QUEUE_SIZE),
+	}
+	bus.subscriptions = append(bus.subscriptions, subscription)
+	bus.dispatchers.Add(1)
+	go func() {
+		defer bus.dispatchers.Done()
+		for event := range subscription.queue {
+			subscription.subscriber(event)
+		}
+	}()
+}
+
+// Never blocks. If the queue of a subscriber is full, the event is dropped for that subscriber
+func (bus *LifecycleBus) Publish(event LifecycleEvent) {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+	if bus.closed {
+		return
+	}
+
+	for _, subscription := range bus.subscriptions {
+		select {
+		case subscription.queue <- event:
+		default:
+			subscription.dropped.Add(1)
+			log.Printf("[lifecycle] Queue of subscriber %s is full, dropped: %s", subscription.name, event)
+		}
+	}
+}
+
+// Events dropped per subscriber so far
+func (bus *LifecycleBus) DroppedEvents() map[string]uint64 {
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+
+	dropped := make(map[string]uint64, len(bus.subscriptions))
+	for _, subscription := range bus.subscriptions {
+		dropped[subscription.name] = subscription.dropped.Load()
+	}
+	return dropped
+}
+
+// Stops accepting events and blocks until every subscriber has handled those already published
+func (bus *LifecycleBus) Close() {
+	bus.lock.Lock()
+	if !bus.closed {
+		bus.closed = true
+		for _, subscription := range bus.subscriptions {
+			close(subscription.queue)
+		}
+	}
+	bus.lock.Unlock()
+
+	bus.dispatchers.Wait()
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
+)
+
+// Threadsafe counters of lifecycle events since startup
+type LifecycleMetrics struct {
+	LobbiesCreated   atomic.Uint64
+	LobbiesClosed    atomic.Uint64
+	ClientsJoined    atomic.Uint64
+	ClientsLeft      atomic.Uint64
+	PhaseChanges     atomic.Uint64
+	MinigamesStarted atomic.Uint64
+	MinigamesWon     atomic.Uint64
+	MinigamesLost    atomic.Uint64
+	MinigamesAborted atomic.Uint64
+}
+
+// LifecycleSubscriber updating the counters
+func (m *LifecycleMetrics) Record(event LifecycleEvent) {
+	switch e := event.(type) {
+	case *LobbyCreatedEvent:
+		m.LobbiesCreated.Add(1)
+	case *LobbyClosedEvent:
+		m.LobbiesClosed.Add(1)
+	case *ClientJoinedEvent:
+		m.ClientsJoined.Add(1)
+	case *ClientLeftEvent:
+		m.ClientsLeft.Add(1)
+	case *PhaseChangedEvent:
+		m.PhaseChanges.Add(1)
+	case *MinigameStartedEvent:
+		m.MinigamesStarted.Add(1)
+	case *MinigameEndedEvent:
+		switch e.Outcome {
+		case MINIGAME_STATE_VICTORY:
+			m.MinigamesWon.Add(1)
+		case MINIGAME_STATE_DEFEAT:
+			m.MinigamesLost.Add(1)
+		default:
+			m.MinigamesAborted.Add(1)
+		}
+	}
+}
+
+// LifecycleSubscriber logging every event
+func AuditLogLifecycleEvent(event LifecycleEvent) {
+	log.Printf("[audit] %s", event)
+}
+
+type lobbyWebhookPayload struct {
+	LobbyID       LobbyID  `json:"lobbyID"`
+	ColonyID      uint32   `json:"colonyID"`
+	ColonyOwnerID ClientID `json:"colonyOwnerID"`
+}
+
+type playerWebhookPayload struct {
+	LobbyID  LobbyID    `json:"lobbyID"`
+	PlayerID ClientID   `json:"playerID"`
+	IGN      string     `json:"ign"`
+	Role     OriginType `json:"role"`
+	// Only set if the player was removed, fx. kicked
+	Reason string `json:"reason,omitempty"`
+}
+
+type minigameEndedWebhookPayload struct {
+	LobbyID        LobbyID    `json:"lobbyID"`
+	ColonyID       uint32     `json:"colonyID"`
+	MinigameID     uint32     `json:"minigameID"`
+	DifficultyID   uint32     `json:"difficultyID"`
+	DifficultyName string     `json:"difficultyName"`
+	Outcome        string     `json:"outcome"`
+	DurationMS     int64      `json:"durationMS"`
+	Participants   []ClientID `json:"participants"`
+}
+
+// LifecycleSubscriber forwarding lobbies opening and closing, players joining and leaving and minigames ending to the webhook endpoints.
+// Spectators are left out
+func NewWebhookSubscriber(dispatcher *integrations.WebhookDispatcher) LifecycleSubscriber {
+	return func(event LifecycleEvent) {
+		var eventType string
+		var payload any
+		switch e := event.(type) {
+		case *LobbyCreatedEvent:
+			eventType = "lobby.opened"
+			payload = lobbyWebhookPayload{LobbyID: e.LobbyID, ColonyID: e.ColonyID, ColonyOwnerID: e.OwnerID}
+		case *LobbyClosedEvent:
+			eventType = "lobby.closed"
+			payload = lobbyWebhookPayload{LobbyID: e.LobbyID, ColonyID: e.ColonyID, ColonyOwnerID: e.ColonyOwnerID}
+		case *ClientJoinedEvent:
+			if e.Type == ORIGIN_TYPE_SPECTATOR {
+				return
+			}
+			eventType = "player.joined"
+			payload = playerWebhookPayload{LobbyID: e.LobbyID, PlayerID: e.ClientID, IGN: e.IGN, Role: e.Type}
+		case *ClientLeftEvent:
+			if e.Type == ORIGIN_TYPE_SPECTATOR {
+				return
+			}
+			eventType = "player.left"
+			payload = playerWebhookPayload{LobbyID: e.LobbyID, PlayerID: e.ClientID, IGN: e.IGN, Role: e.Type, Reason: e.Reason}
+		case *MinigameEndedEvent:
+			eventType = "minigame.ended"
+			payload = minigameEndedWebhookPayload{
+				LobbyID:        e.LobbyID,
+				ColonyID:       e.ColonyID,
+				MinigameID:     e.MinigameID,
+				DifficultyID:   e.DifficultyID,
+				DifficultyName: e.DifficultyName,
+				Outcome:        e.Outcome.String(),
+				DurationMS:     e.Duration.Milliseconds(),
+				Participants:   e.Participants,
+			}
+		default:
+			return
+		}
+		if err := dispatcher.Send(eventType, payload); err != nil {
+			log.Printf("[lifecycle] Error sending %s webhook: %v", eventType, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
+)
+
+func TestLifecycleBusDeliversInOrder(t *testing.T) {
+	bus := NewLifecycleBus()
+	var recieved []LifecycleEvent
+	bus.Subscribe("test", func(event LifecycleEvent) {
+		recieved = append(recieved, event)
+	})
+
+	bus.Publish(&LobbyCreatedEvent{LobbyID: 1})
+	bus.Publish(&ClientJoinedEvent{LobbyID: 1, ClientID: 2})
+	bus.Publish(&LobbyClosedEvent{LobbyID: 1})
+	bus.Close()
+
+	if len(recieved) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(recieved))
+	}
+	if _, ok := recieved[0].(*LobbyCreatedEvent); !ok {
+		t.Errorf("Expected first event to be LobbyCreatedEvent, got %T", recieved[0])
+	}
+	if joined, ok := recieved[1].(*ClientJoinedEvent); !ok || joined.ClientID != 2 {
+		t.Errorf("Expected second event to be ClientJoinedEvent for client 2, got %v", recieved[1])
+	}
+	if _, ok := recieved[2].(*LobbyClosedEvent); !ok {
+		t.Errorf("Expected last event to be LobbyClosedEvent, got %T", recieved[2])
+	}
+
+	// Ignored once closed
+	bus.Publish(&LobbyCreatedEvent{LobbyID: 2})
+	if len(recieved) != 3 {
+		t.Errorf("Expected no events after close, got %d", len(recieved)-3)
+	}
+}
+
+func TestLifecycleBusDropsForSlowSubscriber(t *testing.T) {
+	bus := NewLifecycleBus()
+	release := make(chan struct{})
+	bus.Subscribe("slow", func(event LifecycleEvent) {
+		<-release
+	})
+
+	// The first event is taken off the queue by the blocked subscriber
+	for i := 0; i < LIFECYCLE_SUBSCRIBER_QUEUE_SIZE+10; i++ {
+		bus.Publish(&PhaseChangedEvent{LobbyID: 1})
+	}
+	dropped := bus.DroppedEvents()["slow"]
+	if dropped < 9 || dropped > 10 {
+		t.Errorf("Expected 9-10 dropped events, got %d", dropped)
+	}
+	close(release)
+	bus.Close()
+}
+
+func TestLifecycleMetricsRecord(t *testing.T) {
+	metrics := &LifecycleMetrics{}
+	metrics.Record(&MinigameEndedEvent{Outcome: MINIGAME_STATE_VICTORY})
+	metrics.Record(&MinigameEndedEvent{Outcome: MINIGAME_STATE_ABORT})
+	metrics.Record(&ClientLeftEvent{})
+
+	if metrics.MinigamesWon.Load() != 1 || metrics.MinigamesAborted.Load() != 1 || metrics.ClientsLeft.Load() != 1 {
+		t.Errorf("Unexpected counts: won %d, aborted %d, left %d", metrics.MinigamesWon.Load(), metrics.MinigamesAborted.Load(), metrics.ClientsLeft.Load())
+	}
+}
+
+func TestWebhookSubscriberMinigameEnded(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+	dispatcher, err := integrations.NewWebhookDispatcher(integrations.WebhookOptions{
+		URLs:        []string{server.URL},
+		Secret:      "test secret",
+		MaxAttempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("Error creating dispatcher: %v", err)
+	}
+	defer dispatcher.Close()
+
+	subscriber := NewWebhookSubscriber(dispatcher)
+	// Not forwarded
+	subscriber(&PhaseChangedEvent{LobbyID: 1})
+	subscriber(&MinigameEndedEvent{
+		LobbyID:        1,
+		MinigameID:     1,
+		DifficultyID:   2,
+		DifficultyName: "hard",
+		Participants:   []ClientID{3, 4},
+		Outcome:        MINIGAME_STATE_VICTORY,
+		Duration:       1500 * time.Millisecond,
+	})
+
+	var envelope struct {
+		Type string                      `json:"type"`
+		Data minigameEndedWebhookPayload `json:"data"`
+	}
+	select {
+	case body := <-bodies:
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			t.Fatalf("Error unmarshalling webhook body: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a webhook request")
+	}
+	if envelope.Type != "minigame.ended" {
+		t.Errorf("Expected type minigame.ended, got %s", envelope.Type)
+	}
+	data := envelope.Data
+	if data.Outcome != "Victory" || data.DurationMS != 1500 || len(data.Participants) != 2 || data.DifficultyName != "hard" {
+		t.Errorf("Unexpected payload: %+v", data)
+	}
+}
+
+func TestAsteroidControlsFromFakeMainBackend(t *testing.T) {
+	fake, err := integrations.NewFakeMainBackend("../../fakeMainBackendSettings.json")
+	if err != nil {
+		t.Fatalf("Error creating fake main backend: %v", err)
+	}
+	lobby := &Lobby{ID: 1, mainBackend: fake}
+
+	if _, err := GetAsteroidMinigameControls(&DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 1}, lobby, func() {}); err != nil {
+		t.Errorf("Expected controls from the fake settings, got: %v", err)
+	}
+	if _, err := GetAsteroidMinigameControls(&DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 999}, lobby, func() {}); !errors.Is(err, integrations.ErrMainBackendNotFound) {
+		t.Errorf("Expected not found error, got: %v", err)
+	}
+}
+
+func TestAsteroidSettingsParsedOncePerETag(t *testing.T) {
+	cache := &asteroidSettingsCache{entries: make(map[uint32]asteroidSettingsCacheEntry)}
+	raw := &integrations.MBMinigameSettingsDTO{
+		Settings:            json.RawMessage(`{"colonyHealth": 3, "charCodeLength": 2}`),
+		OverwritingSettings: json.RawMessage(`{"colonyHealth": 5}`),
+		ETag:                `"v1"`,
+	}
+	settings, err := cache.parse(1, raw)
+	if err != nil || settings.ColonyHealth != 5 || settings.CharCodeLength != 2 {
+		t.Fatalf("Expected merged settings, got %+v, %v", settings, err)
+	}
+
+	// Anything but the ETag is ignored while it is unchanged
+	unparsable := &integrations.MBMinigameSettingsDTO{Settings: json.RawMessage(`not json`), ETag: `"v1"`}
+	if cached, err := cache.parse(1, unparsable); err != nil || cached != settings {
+		t.Errorf("Expected the cached settings for an unchanged ETag, got %+v, %v", cached, err)
+	}
+	if _, err := cache.parse(2, unparsable); err == nil {
+		t.Error("Expected the settings of another difficulty to be parsed")
+	}
+
+	changed := &integrations.MBMinigameSettingsDTO{Settings: json.RawMessage(`{"colonyHealth": 7}`), ETag: `"v2"`}
+	if reparsed, err := cache.parse(1, changed); err != nil || reparsed.ColonyHealth != 7 {
+		t.Errorf("Expected the settings to be parsed again for a new ETag, got %+v, %v", reparsed, err)
+	}
+	withoutETag := &integrations.MBMinigameSettingsDTO{Settings: json.RawMessage(`{"colonyHealth": 9}`)}
+	if parsed, err := cache.parse(1, withoutETag); err != nil || parsed.ColonyHealth != 9 {
+		t.Errorf("Expected settings without an ETag to be parsed, got %+v, %v", parsed, err)
+	}
+}
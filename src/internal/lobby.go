@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
@@ -20,6 +22,8 @@ type MessageEntry struct {
 	Client    *Client
 	Remainder []byte
 	Spec      *EventSpecification[any]
+	// Set instead of the other fields when prefetching the controls of the locked in minigame failed
+	failedPrefetch *minigamePrefetch
 }
 
 func NewMessageEntry(client *Client, remainder []byte, spec *EventSpecification[any]) *MessageEntry {
@@ -32,28 +36,63 @@ func NewMessageEntry(client *Client, remainder []byte, spec *EventSpecification[
 
 // Lobby represents a lobby with a set of users
 type Lobby struct {
-	ID       LobbyID
-	OwnerID  ClientID
-	ColonyID uint32
-	Clients  util.ConcurrentTypedMap[ClientID, *Client] // UserID to User mapping
-	Sync     sync.Mutex                                 // Protects access to the Users map
-	Closing  atomic.Bool                                // Indicates if the lobby is in the process of closing
+	ID LobbyID
+	// Threadsafe. Changes if ownership is transferred
+	OwnerID atomic.Uint32
+	// Readonly. Owner of the colony on the main backend, which stays the same regardless of lobby ownership
+	ColonyOwnerID ClientID
+	ColonyID      uint32
+	Clients       util.ConcurrentTypedMap[ClientID, *Client] // UserID to User mapping
+	Sync          sync.Mutex                                 // Protects access to the Users map
+	Closing       atomic.Bool                                // Indicates if the lobby is in the process of closing
 	//Prepends senderID
 	BroadcastMessage func(senderID ClientID, message []byte) []*Client
 	Encoding         meta.MessageEncoding
 	activityTracker  *ActivityTracker
 	currentActivity  *GenericMinigameControls
 	CloseQueue       chan<- *Lobby // Queue on which to register self for closing
+	lifecycle        *LifecycleBus
+	mainBackend      integrations.MainBackend
+	outbox           *integrations.Outbox
 	// Queue of all messages to be further tracked
 	// All messages must have been through all pre-flight checks and handler before being added here
 	PostProcessQueue chan *MessageEntry
 	//Maybe introduce message channel for messages to be sent to the lobby
+	configuration *meta.RuntimeConfiguration
+	// Readonly. Interval at which each client is pinged, 0 if heartbeats are disabled
+	PingInterval time.Duration
+	// Readonly. Time allowed for a pong after a ping, before a client is considered dead
+	PongTimeout time.Duration
+	// Max size in bytes of any single websocket message read from a client
+	readLimit int64
+	// Clients banned for the remainder of the lobby, and the reason given
+	banned util.ConcurrentTypedMap[ClientID, string]
+	// Readonly. Max amount of players, 0 if unlimited. Spectators and the owner are let in regardless.
+	// The owner takes one of the seats, so that the count holds when ownership is transferred to another player
+	MaxPlayers uint32
+	// Readonly. Whether joins past MaxPlayers wait in the join queue or are rejected
+	QueueWhenFull bool
+	// Protects joinQueue and the decision of whether a client is let in
+	admissionLock sync.Mutex
+	// Clients waiting for a slot to free up, in the order they'll be admitted
+	joinQueue []*Client
+	// When the current activity started. Only accessed alongside currentActivity
+	activityStartedAt time.Time
 }
 
-func NewLobby(id LobbyID, ownerID ClientID, colonyID uint32, encoding meta.MessageEncoding, closeQueue chan<- *Lobby) *Lobby {
+// Per lobby overwrites of the runtime configuration. Zero values use the configured defaults
+type LobbySettings struct {
+	PingInterval  time.Duration
+	PongTimeout   time.Duration
+	MaxPlayers    uint32
+	QueueWhenFull bool
+}
+
+func NewLobby(id LobbyID, ownerID ClientID, colonyID uint32, encoding meta.MessageEncoding, closeQueue chan<- *Lobby,
+	lifecycle *LifecycleBus, mainBackend integrations.MainBackend, outbox *integrations.Outbox, configuration *meta.RuntimeConfiguration, settings LobbySettings) *Lobby {
 	lobby := &Lobby{
 		ID:               id,
-		OwnerID:          ownerID,
+		ColonyOwnerID:    ownerID,
 		ColonyID:         colonyID,
 		Clients:          util.ConcurrentTypedMap[ClientID, *Client]{},
 		Closing:          atomic.Bool{},
@@ -61,7 +100,19 @@ func NewLobby(id LobbyID, ownerID ClientID, colonyID uint32, encoding meta.Messa
 		activityTracker:  NewActivityTracker(),
 		currentActivity:  nil,
 		CloseQueue:       closeQueue,
+		lifecycle:        lifecycle,
+		mainBackend:      mainBackend,
+		outbox:           outbox,
 		PostProcessQueue: make(chan *MessageEntry, 1000),
+		configuration:    configuration,
+		banned:           util.ConcurrentTypedMap[ClientID, string]{},
+		MaxPlayers:       settings.MaxPlayers,
+		QueueWhenFull:    settings.QueueWhenFull,
+		joinQueue:        make([]*Client, 0),
+		PingInterval:     util.Ternary(settings.PingInterval > 0, settings.PingInterval, configuration.PingInterval),
+		PongTimeout:      util.Ternary(settings.PongTimeout > 0, settings.PongTimeout, configuration.PongTimeout),
+		// Base16 encoded messages are twice the size of their binary counterpart
+		readLimit: util.Ternary(configuration.WSReadLimit > 0, configuration.WSReadLimit, 2*int64(LargestMessageSize())),
 	}
 
 	switch encoding {
@@ -79,6 +130,10 @@ func NewLobby(id LobbyID, ownerID ClientID, colonyID uint32, encoding meta.Messa
 		}
 	}
 
+	lobby.OwnerID.Store(ownerID)
+	lobby.activityTracker.onPhaseChange = func(from LobbyPhase, to LobbyPhase) {
+		lobby.lifecycle.Publish(&PhaseChangedEvent{LobbyID: lobby.ID, From: from, To: to})
+	}
 	go lobby.runPostProcess()
 
 	return lobby
@@ -92,6 +147,9 @@ const (
 	JoinErrorAlreadyInLobby       JoinError = 2
 	JoinErrorUnknown              JoinError = 3
 	JoinErrorSerializationFailure JoinError = 4
+	JoinErrorResumeRejected       JoinError = 5
+	JoinErrorBanned               JoinError = 6
+	JoinErrorFull                 JoinError = 7
 )
 
 type LobbyJoinError struct {
@@ -105,43 +163,77 @@ func (e *LobbyJoinError) Error() string {
 }
 
 // Handle user connection and disconnection events
+//
+// Runs for as long as the clients current connection is alive. A resumed session runs this again on the new connection
 func (lobby *Lobby) handleConnection(client *Client) {
+	conn, generation := client.currentConnection()
+	writer := client.startWriter(conn, generation, lobby.PingInterval)
+	if writer == nil {
+		// Resumed again before this connection got going. The routine of the latest connection handles it
+		return
+	}
+
+	// The writer pings the client every PingInterval. Any sign of life extends the read deadline
+	// and if none is seen in time, the read fails and the client is handled as any other dropped connection
+	extendReadDeadline := func() {
+		if lobby.PingInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(lobby.PingInterval + lobby.PongTimeout))
+		}
+	}
+	extendReadDeadline()
+	// Frames over the limit are answered with close code 1009 (message too big) by the websocket library
+	conn.SetReadLimit(lobby.readLimit)
 
 	// Set Ping handler
-	client.Conn.SetPingHandler(func(appData string) error {
+	conn.SetPingHandler(func(appData string) error {
 		log.Printf("[lobby] Received ping from user %d", client.ID)
+		extendReadDeadline()
 		// Respond with Pong automatically
-		return client.Conn.WriteMessage(websocket.PongMessage, []byte(appData))
+		return conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(time.Second))
 	})
 
 	// Set Pong handler
-	client.Conn.SetPongHandler(func(appData string) error {
-		log.Printf("[lobby] Received pong from user %d", client.ID)
+	conn.SetPongHandler(func(appData string) error {
+		extendReadDeadline()
 		return nil
 	})
 
-	var onDisconnect func(*Client)
-	if client.Type == ORIGIN_TYPE_OWNER {
-		onDisconnect = lobby.handleOwnerDisconnect
-	} else {
-		onDisconnect = lobby.handleGuestDisconnect
-	}
-
+	// Only a normal closure is treated as the client leaving on purpose.
+	// Anything else (going away, abnormal closure, read errors) starts the reconnect grace period
+	var intentionalClose = false
 	// Set Close handler
-	client.Conn.SetCloseHandler(func(code int, text string) error {
+	conn.SetCloseHandler(func(code int, text string) error {
 		log.Printf("[lobby] User %d disconnected with close message: %d - %s", client.ID, code, text)
-		onDisconnect(client)
+		intentionalClose = code == websocket.CloseNormalClosure
 		return nil
 	})
 
+	// Set when the client is to be disconnected for misbehaving or missing the heartbeat, skipping the grace period
+	var evictionReason = ""
 	for {
 		// Read the message from the WebSocket
 		// Blocks until TextMessage or BinaryMessage is received.
-		dataType, msg, err := client.Conn.ReadMessage()
+		dataType, msg, err := conn.ReadMessage()
 		if err != nil {
-			log.Printf("User %d disconnected: %v", client.ID, err)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// The connection is presumed dead, so there is no point in awaiting a resume on it
+				log.Printf("[lobby] User %d missed heartbeat deadline in lobby %d, evicting", client.ID, lobby.ID)
+				evictionReason = "Missed heartbeat"
+			} else {
+				log.Printf("User %d disconnected: %v", client.ID, err)
+			}
 			break
 		}
+		extendReadDeadline()
+
+		if client.Queued.Load() {
+			// Nothing is handled before the client is admitted
+			if err := SendDebugInfoToClient(client, 425, "Waiting in join queue"); err != nil {
+				break
+			}
+			continue
+		}
 
 		if dataType == websocket.TextMessage {
 			//Base16, hex, decode the message
@@ -155,6 +247,7 @@ func (lobby *Lobby) handleConnection(client *Client) {
 					log.Printf("[lobby] Error sending debug info to user %d: %v", client.ID, cantSendDebugInfo)
 					break
 				}
+				continue
 			}
 		} else if dataType != websocket.BinaryMessage {
 			log.Printf("[lobby] Invalid message type from user %d", client.ID)
@@ -169,21 +262,35 @@ func (lobby *Lobby) handleConnection(client *Client) {
 		clientID, spec, remainder, extractErr := ExtractMessageHeader(msg)
 		if extractErr != nil {
 			log.Printf("[lobby] Error in message from client id %d: %s", client.ID, extractErr.Error())
-			if cantSendDebugInfo := SendDebugInfoToClient(client, 400, extractErr.Error()); cantSendDebugInfo != nil {
+			code := util.Ternary[uint32](errors.Is(extractErr, ErrMessageTooLarge), 413, 400)
+			if cantSendDebugInfo := SendDebugInfoToClient(client, code, extractErr.Error()); cantSendDebugInfo != nil {
 				log.Printf("[lobby] Error sending debug info to user %d: %v", client.ID, cantSendDebugInfo)
 				break
 			}
 			continue
 		}
-		// Although the client object as returned here, should be the same as the one in the input to this method,
-		// just for safety, we fetch the client object from the lobby's client map anyway
-		_, clientExists := lobby.Clients.Load(clientID)
-		if !clientExists {
-			log.Printf("[lobby] User %d not found in lobby %d", clientID, lobby.ID)
+		// The connection is authenticated as belonging to this client, so any other sender id is spoofed
+		if clientID != client.ID {
+			if lobby.rejectSpoofedSenderID(client, clientID) {
+				evictionReason = "Spoofed sender id"
+				break
+			}
+			continue
+		}
+
+		if !client.RateLimiter.allow(spec) {
+			if client.RateLimiter.recordOffence() {
+				log.Printf("[lobby] User %d exceeded the rate limit offence threshold in lobby %d", client.ID, lobby.ID)
+				evictionReason = "Rate limit exceeded"
+				break
+			}
+			if err := SendDebugInfoToClient(client, 429, fmt.Sprintf("Too many requests: message of id %d dropped", spec.ID)); err != nil {
+				break
+			}
 			continue
 		}
 
-		if !spec.SendPermissions[client.Type] {
+		if !spec.SendPermissions[client.Type()] {
 			log.Printf("[lobby] User %d not allowed to send message ID %d", client.ID, spec.ID)
 			if err := SendDebugInfoToClient(client, 401, fmt.Sprintf("Unauthorized: client %d is not allowed to send messages of id %d", client.ID, spec.ID)); err != nil {
 				break
@@ -191,6 +298,14 @@ func (lobby *Lobby) handleConnection(client *Client) {
 			continue
 		}
 
+		if policyErr := spec.Authorize(NewAuthorizationContext(lobby, client)); policyErr != nil {
+			log.Printf("[lobby] User %d denied sending message ID %d: %v", client.ID, spec.ID, policyErr)
+			if err := SendDebugInfoToClient(client, 403, fmt.Sprintf("Forbidden: %s", policyErr.Error())); err != nil {
+				break
+			}
+			continue
+		}
+
 		// Further processing based on messageID
 		if processingError := lobby.processClientMessage(client, spec, remainder); processingError != nil {
 			log.Printf("[lobby] Error processing message from clientID %d: %v", clientID, processingError)
@@ -201,20 +316,71 @@ func (lobby *Lobby) handleConnection(client *Client) {
 			}
 		}
 	}
-	// Some disconnect issues here.
-	onDisconnect(client)
+	if lobby.removeFromJoinQueue(client) {
+		// Never part of the lobby, so there is nothing to resume
+		writer.stop()
+		client.closeConnection()
+		return
+	}
+	if evictionReason != "" {
+		// Let the writer flush what's queued, fx. the reason for the eviction, before the connection is closed
+		client.queueClose(websocket.ClosePolicyViolation, evictionReason)
+		select {
+		case <-writer.exited:
+		case <-time.After(client.writeTimeout):
+		}
+		writer.stop()
+		lobby.evictClient(client, generation, evictionReason)
+		return
+	}
+	// Until the writer has exited it may take one more message off the queue, which would then be lost for a resume
+	writer.stop()
+	select {
+	case <-writer.exited:
+	case <-time.After(client.writeTimeout):
+	}
+	lobby.handleConnectionLost(client, generation, intentionalClose)
+}
+
+// Counts the offence and notifies the client with a SenderIDRejected event.
+//
+// Returns true if the configured threshold is exceeded and the client should be evicted
+func (lobby *Lobby) rejectSpoofedSenderID(client *Client, receivedID ClientID) bool {
+	offences := client.SpoofedSenderIDs.Add(1)
+	threshold := lobby.configuration.SpoofDisconnectThreshold
+	log.Printf("[lobby] User %d sent a message as user %d in lobby %d, offence %d", client.ID, receivedID, lobby.ID, offences)
+
+	msg, err := Serialize(SENDER_ID_REJECTED_EVENT, SenderIDRejectedMessageDTO{
+		ExpectedID: client.ID,
+		ReceivedID: receivedID,
+		Offences:   offences,
+		Threshold:  uint32(threshold),
+	})
+	if err != nil {
+		log.Printf("[lobby] Error serializing sender id rejected event: %v", err)
+	} else if err := SendMessageToClient(client, SERVER_ID, msg); err != nil {
+		log.Printf("[lobby] Error sending sender id rejected event to user %d: %v", client.ID, err)
+	}
+
+	if threshold <= 0 || offences <= uint32(threshold) {
+		return false
+	}
+	log.Printf("[lobby] User %d exceeded spoofed sender id threshold in lobby %d", client.ID, lobby.ID)
+	return true
 }
 
 // Assumes all pre-flight checks have been done
 func (lobby *Lobby) processClientMessage(client *Client, spec *EventSpecification[any], remainder []byte) error {
 	// Handle message based on messageID
 	if handlingErr := spec.Handler(lobby, client, spec, remainder); handlingErr != nil {
-		if !errors.Is(handlingErr, &UnresponsiveClientsError{}) {
+		var unresponsiveErr *UnresponsiveClientsError
+		if !errors.As(handlingErr, &unresponsiveErr) {
 			SendDebugInfoToClient(client, 500, "Error handling message: "+handlingErr.Error())
 			log.Printf("[lobby] Error handling message ID %d from clientID %d: %v", spec.ID, client.ID, handlingErr)
 			return fmt.Errorf("Error handling message ID %d from clientID %d: %v", spec.ID, client.ID, handlingErr)
 		} else {
-			//TODO: Track unresponsive clients
+			// Dropped messages are tracked per client in Client.SendMetrics
+			log.Printf("[lobby] %d client(s) unreachable while handling message ID %d from clientID %d", len(unresponsiveErr.UnresponsiveClients), spec.ID, client.ID)
 		}
 	}
 
@@ -234,6 +400,10 @@ func (l *Lobby) runPostProcess() {
 	for !l.Closing.Load() {
 		// Blocks until a messageInfo is received
 		messageInfo := <-l.PostProcessQueue
+		if messageInfo.failedPrefetch != nil {
+			l.onPrefetchFailed(messageInfo.failedPrefetch)
+			continue
+		}
 		currentPhase := l.activityTracker.phase.Load()
 
 		if messageInfo.Spec.ID == GENERIC_MINIGAME_SEQUENCE_RESET.ID {
@@ -295,7 +465,7 @@ func (l *Lobby) runPostProcess() {
 					// However, as of current control flow, this shouldn't be able to happen
 					// So if it fails, let it fail, as the error wouldn't be here, but earlier.
 				})
-				controls, err := LoadMinigameControls(diff, l, l.dismountCurrentActivity)
+				controls, err := l.awaitMinigameControls(diff)
 				if err != nil {
 					err := OnUntimelyMinigameAbort(err.Error(), SERVER_ID, l, nil)
 					if err != nil {
@@ -314,8 +484,17 @@ func (l *Lobby) runPostProcess() {
 					return
 				}
 
+				l.activityStartedAt = time.Now()
 				controls.StartLoop()
 				l.currentActivity = controls
+				l.lifecycle.Publish(&MinigameStartedEvent{
+					LobbyID:        l.ID,
+					ColonyID:       l.ColonyID,
+					MinigameID:     diff.MinigameID,
+					DifficultyID:   diff.DifficultyID,
+					DifficultyName: diff.DifficultyName,
+					Participants:   l.activityTracker.Participants(),
+				})
 			}
 		case uint32(LOBBY_PHASE_IN_MINIGAME):
 			_, isInGame := l.activityTracker.participantTracker.OptIn.Load(messageInfo.Client.ID)
@@ -334,6 +513,21 @@ func (l *Lobby) runPostProcess() {
 func (l *Lobby) dismountCurrentActivity() {
 	if l.currentActivity != nil {
 		l.currentActivity.ExecFallingEdge()
+		ended := &MinigameEndedEvent{
+			LobbyID:      l.ID,
+			ColonyID:     l.ColonyID,
+			Participants: l.activityTracker.Participants(),
+			Outcome:      MinigameStateFrom(l.currentActivity.State.Load()),
+			Duration:     time.Since(l.activityStartedAt),
+		}
+		l.activityTracker.diffConfirmed.Do(func(v **DifficultyConfirmedForMinigameMessageDTO) {
+			if *v != nil {
+				ended.MinigameID = (*v).MinigameID
+				ended.DifficultyID = (*v).DifficultyID
+				ended.DifficultyName = (*v).DifficultyName
+			}
+		})
+		l.lifecycle.Publish(ended)
 		l.currentActivity = nil
 	}
 	l.activityTracker.ReleaseLock()
@@ -355,9 +549,13 @@ func (l *Lobby) trackPhaseRoamningColony(client *Client, spec *EventSpecificatio
 			return
 		}
 		if l.activityTracker.SetDiffConfirmed(deserialized) {
-			if !l.activityTracker.LockIn(uint32(l.ClientCount())) {
+			// Players in their reconnect grace period are expected to take part as well.
+			// If they don't resume in time, removing them accounts for them
+			if !l.activityTracker.LockIn(uint32(l.PlayerCount())) {
 				log.Println("How?! (Concurrency bug) lobby.trackPhaseRoamingColony")
+				return
 			}
+			l.prefetchMinigameControls(deserialized)
 		} else {
 			log.Printf("[lobby] Multiple lock in attempts ignored: Activity ID and Difficulty ID has already been locked in. Message from %d", client.ID)
 			SendDebugInfoToClient(client, 400, "Multiple lock in attempts ignored: Activity ID and Difficulty ID has already been locked in")
@@ -376,7 +574,7 @@ func (l *Lobby) trackPhaseAwaitingParticipants(client *Client, spec *EventSpecif
 		if !l.activityTracker.RemoveParticipant(client) {
 			log.Printf("[lobby] Error removing participant from activity because it is not yet locked in. Message from %d", client.ID)
 			SendDebugInfoToClient(client, 400, "Cannot remove participant from activity because the Activity is not yet locked in")
-		} else if client.ID == l.OwnerID {
+		} else if client.ID == l.OwnerID.Load() {
 			//Emit generic sequence reset
 			l.BroadcastMessage(SERVER_ID, GENERIC_MINIGAME_SEQUENCE_RESET.CopyIDBytes())
 		}
@@ -384,28 +582,214 @@ func (l *Lobby) trackPhaseAwaitingParticipants(client *Client, spec *EventSpecif
 
 }
 
+// Called when the read loop of some connection of the client exits.
+//
+// Unless the client left intentionally, it is kept in the lobby as disconnected for the reconnect grace period,
+// after which it is removed as usual.
+func (lobby *Lobby) handleConnectionLost(client *Client, generation uint32, intentional bool) {
+	if current, exists := lobby.Clients.Load(client.ID); !exists || current != client {
+		// Already removed, fx. the lobby is shutting down
+		return
+	}
+	if !client.markDisconnected(generation) {
+		// The session has already been resumed on a newer connection
+		return
+	}
+
+	gracePeriod := lobby.configuration.ReconnectGracePeriod
+	if intentional || lobby.Closing.Load() || gracePeriod <= 0 {
+		lobby.handleDisconnect(client, "")
+		return
+	}
+
+	log.Printf("[lobby] User %d lost connection to lobby %d, awaiting resume for %s", client.ID, lobby.ID, gracePeriod)
+	time.AfterFunc(gracePeriod, func() {
+		_, currentGeneration := client.currentConnection()
+		if client.Connected.Load() || currentGeneration != generation {
+			return
+		}
+		log.Printf("[lobby] Reconnect grace period expired for user %d in lobby %d", client.ID, lobby.ID)
+		lobby.handleDisconnect(client, "")
+	})
+}
+
+// Disconnects the client right away, without any grace period
+func (lobby *Lobby) evictClient(client *Client, generation uint32, reason string) {
+	if current, exists := lobby.Clients.Load(client.ID); !exists || current != client {
+		return
+	}
+	if !client.markDisconnected(generation) {
+		return
+	}
+	log.Printf("[lobby] Evicting user %d from lobby %d: %s", client.ID, lobby.ID, reason)
+	client.closeConnection()
+	lobby.handleDisconnect(client, reason)
+}
+
+// The reason is empty unless the client was evicted
+func (lobby *Lobby) handleDisconnect(client *Client, reason string) {
+	if client.Type() == ORIGIN_TYPE_OWNER {
+		lobby.handleOwnerDisconnect(client)
+	} else {
+		lobby.handleGuestDisconnect(client, reason)
+	}
+}
+
+// Swaps in the new connection on a disconnected (or not yet known to be disconnected) client,
+// resends any broadcasts missed in the meantime and notifies everyone else.
+//
+// The resume token is checked again, as another resume may have used it since it was verified.
+// On success the client is sent a new token, as each token only works once
+func (lobby *Lobby) ResumeClient(client *Client, conn *websocket.Conn, resumeToken string) *LobbyJoinError {
+	newToken, resumed := client.swapConnection(conn, resumeToken)
+	if !resumed {
+		return &LobbyJoinError{Reason: "Invalid resume token", Type: JoinErrorResumeRejected, LobbyID: lobby.ID}
+	}
+	log.Printf("[lobby] User %d resumed session in lobby %d", client.ID, lobby.ID)
+
+	tokenMsg, err := Serialize(RESUME_TOKEN_EVENT, ResumeTokenMessageDTO{Token: newToken})
+	if err != nil {
+		log.Printf("[lobby] Error serializing resume token for client %d: %v", client.ID, err)
+	} else if err := SendMessageToClient(client, SERVER_ID, tokenMsg); err != nil {
+		log.Printf("[lobby] Error sending resume token to client %d: %v", client.ID, err)
+	}
+
+	if !client.IsSpectator() {
+		msg, err := Serialize(PLAYER_RECONNECTED_EVENT, PlayerReconnectedMessageDTO{
+			PlayerID: client.ID,
+			IGN:      client.IGN,
+		})
+		if err != nil {
+			log.Printf("[lobby] Error serializing player reconnected event: %v", err)
+		} else {
+			lobby.BroadcastMessage(SERVER_ID, msg)
+		}
+	}
+
+	go lobby.handleConnection(client)
+	return nil
+}
+
 // Handle user disconnection, and close the lobby if the owner disconnects
-func (lobby *Lobby) handleGuestDisconnect(user *Client) {
-	lobby.RemoveClient(user)
+func (lobby *Lobby) handleGuestDisconnect(user *Client, reason string) {
+	lobby.RemoveClientWithReason(user, 0, reason)
 }
+
+// What happens depends on the configured OwnerDisconnectPolicy. The lobby is closed, unless some player can take over
 func (lobby *Lobby) handleOwnerDisconnect(user *Client) {
-	log.Println("Lobby owner disconnected, closing lobby: ", lobby.ID)
-	// If the lobby owner disconnects, close the lobby and notify everyone
-	lobby.close()
+	if lobby.Closing.Load() {
+		return
+	}
+
+	switch lobby.configuration.OwnerDisconnectPolicy {
+	case meta.OWNER_DISCONNECT_POLICY_WAIT:
+		lobby.awaitOwnerRejoin(user)
+	case meta.OWNER_DISCONNECT_POLICY_PROMOTE:
+		successor := lobby.longestConnectedPlayer(user.ID)
+		if successor == nil {
+			log.Println("[lobby] Lobby owner disconnected and no one is left to take over, closing lobby: ", lobby.ID)
+			lobby.close()
+			return
+		}
+		lobby.RemoveClient(user)
+		lobby.transferOwnership(successor)
+	default:
+		log.Println("Lobby owner disconnected, closing lobby: ", lobby.ID)
+		// If the lobby owner disconnects, close the lobby and notify everyone
+		lobby.close()
+	}
+}
+
+// Keeps the owner in the lobby as disconnected for the configured time. If the owner hasn't resumed their session by then, the lobby is closed.
+//
+// The owner isn't removed, as owner tickets are only minted for new lobbies, so resuming is the only way back in
+func (lobby *Lobby) awaitOwnerRejoin(owner *Client) {
+	wait := lobby.configuration.OwnerReconnectWait
+	if wait <= 0 {
+		lobby.close()
+		return
+	}
+	_, generation := owner.currentConnection()
+	log.Printf("[lobby] Lobby owner %d disconnected from lobby %d, awaiting resume for %s", owner.ID, lobby.ID, wait)
+
+	time.AfterFunc(wait, func() {
+		// Any resume invalidates this wait, also if the owner has since been disconnected again
+		_, currentGeneration := owner.currentConnection()
+		if lobby.Closing.Load() || owner.Connected.Load() || currentGeneration != generation {
+			return
+		}
+		log.Println("[lobby] Lobby owner did not resume in time, closing lobby: ", lobby.ID)
+		lobby.close()
+	})
+}
+
+// Returns nil if no connected player (spectators excluded) other than the one given is in the lobby
+func (lobby *Lobby) longestConnectedPlayer(excluding ClientID) *Client {
+	var longest *Client
+	lobby.Clients.Range(func(id ClientID, client *Client) bool {
+		// A player awaiting resume may never come back, leaving the lobby without a reachable owner
+		if id == excluding || client.IsSpectator() || !client.Connected.Load() {
+			return true
+		}
+		if longest == nil || client.JoinedAt.Before(longest.JoinedAt) {
+			longest = client
+		}
+		return true
+	})
+	return longest
+}
+
+// Makes the client the owner of the lobby and notifies everyone
+func (lobby *Lobby) transferOwnership(newOwner *Client) {
+	lobby.OwnerID.Store(newOwner.ID)
+	newOwner.setType(ORIGIN_TYPE_OWNER)
+	log.Printf("[lobby] Ownership of lobby %d transferred to user %d", lobby.ID, newOwner.ID)
+
+	msg, err := Serialize(OWNER_CHANGED_EVENT, OwnerChangedMessageDTO{
+		PlayerID: newOwner.ID,
+		IGN:      newOwner.IGN,
+	})
+	if err != nil {
+		log.Printf("[lobby] Error serializing owner changed event: %v", err)
+		return
+	}
+	lobby.BroadcastMessage(SERVER_ID, msg)
 }
 
 // Remove a client from the lobby and notify all other clients
 //
 // Also closes the clients web socket connection
 func (lobby *Lobby) RemoveClient(client *Client) {
-	client, exists := lobby.Clients.Load(client.ID)
-	if !exists {
-		log.Printf("[lobby] User %v not found in lobby %d", client, lobby.ID)
+	lobby.RemoveClientWithReason(client, 0, "")
+}
+
+// As RemoveClient, but if a close code is given, the client is sent a close frame with the code and reason
+// after anything already queued for it, before the connection is closed
+func (lobby *Lobby) RemoveClientWithReason(client *Client, closeCode int, reason string) {
+	// Loaded and deleted at once, so of concurrent removals, fx. a kick and a disconnect, only one continues.
+	// Once removed, the read loop of the client exiting is not treated as a lost connection
+	removed, loaded := lobby.Clients.LoadAndDelete(client.ID)
+	if !loaded {
+		log.Printf("[lobby] User %d not found in lobby %d", client.ID, lobby.ID)
+		return
+	}
+	client = removed
+	lobby.lifecycle.Publish(&ClientLeftEvent{
+		LobbyID:  lobby.ID,
+		ClientID: client.ID,
+		IGN:      client.IGN,
+		Type:     client.Type(),
+		Reason:   reason,
+	})
+	if closeCode != 0 {
+		client.queueClose(closeCode, reason)
+		time.AfterFunc(client.writeTimeout, func() { client.closeConnection() })
+	} else {
+		client.closeConnection()
+	}
+	if client.IsSpectator() {
 		return
 	}
-
-	lobby.Clients.Delete(client.ID)
-	client.Conn.Close()
 
 	lobby.activityTracker.RemoveParticipant(client)
 
@@ -419,31 +803,52 @@ func (lobby *Lobby) RemoveClient(client *Client) {
 	} else {
 		lobby.BroadcastMessage(SERVER_ID, serialized)
 	}
+
+	lobby.admitFromJoinQueue()
+}
+
+// Bans the client for the remainder of the lobby. Does not remove the client if present
+func (lobby *Lobby) Ban(clientID ClientID, reason string) {
+	lobby.banned.Store(clientID, reason)
+}
+
+func (lobby *Lobby) IsBanned(clientID ClientID) bool {
+	_, banned := lobby.banned.Load(clientID)
+	return banned
 }
 
 // Notify all clients in the lobby that the lobby is closing
 //
-// Adds lobby to lobby manager closing channel
+// Adds lobby to lobby manager closing channel. Only the first call does anything,
+// as the lobby may be closed from several places at once, fx. the owner wait expiring during shutdown
 func (lobby *Lobby) close() {
-	lobby.Closing.Store(true)
+	if !lobby.Closing.CompareAndSwap(false, true) {
+		return
+	}
 	lobby.BroadcastMessage(SERVER_ID, LOBBY_CLOSING_EVENT.CopyIDBytes())
-	err := integrations.GetMainBackendIntegration().CloseColony(lobby.ColonyID, lobby.OwnerID)
-	if err != nil {
-		log.Printf("[lobby] Error closing colony %d: %v", lobby.ColonyID, err)
+	// Queued here rather than by a lifecycle subscriber, as the bus drops events for subscribers that fall behind
+	if err := lobby.outbox.CloseColony(lobby.ColonyID, lobby.ColonyOwnerID); err != nil {
+		log.Printf("[lobby] Error queueing closing of colony %d: %v", lobby.ColonyID, err)
 	}
+	lobby.lifecycle.Publish(&LobbyClosedEvent{
+		LobbyID:       lobby.ID,
+		ColonyID:      lobby.ColonyID,
+		ColonyOwnerID: lobby.ColonyOwnerID,
+	})
 	lobby.CloseQueue <- lobby
 }
 
 // Only called indirectly by the lobby manager while it is processing the close queue
 func (lobby *Lobby) shutdown() {
 	log.Println("[lobby] Shutting down lobby: ", lobby.ID)
+	lobby.dropJoinQueue()
 	lobby.Clients.Range(func(key ClientID, value *Client) bool {
 		lobby.RemoveClient(value)
 		return true
 	})
 }
 
-// Approximate
+// Approximate. Includes clients in their reconnect grace period, as they keep their place in the lobby
 func (lobby *Lobby) ClientCount() int {
 	var count = 0
 	lobby.Clients.Range(func(key ClientID, value *Client) bool {
@@ -452,3 +857,15 @@ func (lobby *Lobby) ClientCount() int {
 	})
 	return count
 }
+
+// Approximate. Excludes spectators, but includes players in their reconnect grace period
+func (lobby *Lobby) PlayerCount() int {
+	var count = 0
+	lobby.Clients.Range(func(key ClientID, value *Client) bool {
+		if !value.IsSpectator() {
+			count++
+		}
+		return true
+	})
+	return count
+}
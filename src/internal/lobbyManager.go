@@ -17,20 +17,50 @@ type LobbyManager struct {
 	nextLobbyID       atomic.Uint32
 	acceptsNewLobbies atomic.Bool
 	CloseQueue        chan *Lobby // Queue of lobbies that need to be closed
-	configuration     *meta.RuntimeConfiguration
+	// Lifecycle events of all lobbies are published here
+	Lifecycle *LifecycleBus
+	Metrics   *LifecycleMetrics
+	// Nil if no webhook endpoints are configured
+	Webhooks    *integrations.WebhookDispatcher
+	MainBackend integrations.MainBackend
+	// Calls to the main backend that must not be lost
+	Outbox        *integrations.Outbox
+	configuration *meta.RuntimeConfiguration
 }
 
-func CreateLobbyManager(runtimeConfiguration *meta.RuntimeConfiguration) *LobbyManager {
+func CreateLobbyManager(runtimeConfiguration *meta.RuntimeConfiguration, mainBackend integrations.MainBackend, outbox *integrations.Outbox) *LobbyManager {
 	lm := &LobbyManager{
 		Lobbies:           util.ConcurrentTypedMap[LobbyID, *Lobby]{},
 		acceptsNewLobbies: atomic.Bool{},
 		nextLobbyID:       atomic.Uint32{},
 		CloseQueue:        make(chan *Lobby, 10), // A queue to handle closing lobbies
 		configuration:     runtimeConfiguration,
+		Lifecycle:         NewLifecycleBus(),
+		Metrics:           &LifecycleMetrics{},
+		MainBackend:       mainBackend,
+		Outbox:            outbox,
 	}
 	lm.nextLobbyID.Store(1)
 	lm.acceptsNewLobbies.Store(true)
 
+	lm.Lifecycle.Subscribe("metrics", lm.Metrics.Record)
+	lm.Lifecycle.Subscribe("audit log", AuditLogLifecycleEvent)
+	if len(runtimeConfiguration.WebhookURLs) > 0 {
+		webhooks, err := integrations.NewWebhookDispatcher(integrations.WebhookOptions{
+			URLs:           runtimeConfiguration.WebhookURLs,
+			Secret:         runtimeConfiguration.WebhookSecret,
+			MaxAttempts:    runtimeConfiguration.WebhookMaxAttempts,
+			InitialBackoff: runtimeConfiguration.WebhookInitialBackoff,
+			MaxBackoff:     runtimeConfiguration.WebhookMaxBackoff,
+		})
+		if err != nil {
+			log.Printf("[lob man] Webhooks disabled: %v", err)
+		} else {
+			lm.Webhooks = webhooks
+			lm.Lifecycle.Subscribe("webhooks", NewWebhookSubscriber(webhooks))
+		}
+	}
+
 	go lm.processClosures() // Start a goroutine to process lobby closures
 	return lm
 }
@@ -67,6 +97,14 @@ func (lm *LobbyManager) ShutdownLobbyManager() {
 
 	//Dunno if this should be done like this
 	close(lm.CloseQueue)
+
+	// Lets the subscribers finish, fx. queueing the lobby.closed webhooks
+	lm.Lifecycle.Close()
+	if lm.Webhooks != nil {
+		lm.Webhooks.Close()
+	}
+	// Anything still pending is picked up again on the next start
+	lm.Outbox.Close()
 }
 
 // Unregister a lobby and clean it up
@@ -79,10 +117,10 @@ func (lm *LobbyManager) UnregisterLobby(lobby *Lobby) {
 	}
 }
 
-// Create a new lobby and assign an owner
-func (lm *LobbyManager) CreateLobby(ownerID ClientID, colonyID uint32, userSetEncoding meta.MessageEncoding) (*Lobby, error) {
+// Create a new lobby and assign an owner. If the colony already has a lobby, that is returned instead and created is false
+func (lm *LobbyManager) CreateLobby(ownerID ClientID, colonyID uint32, userSetEncoding meta.MessageEncoding, settings LobbySettings) (lobby *Lobby, created bool, err error) {
 	if !lm.acceptsNewLobbies.Load() {
-		return nil, fmt.Errorf("[lob man] Lobby manager is not accepting new lobbies at this point")
+		return nil, false, fmt.Errorf("[lob man] Lobby manager is not accepting new lobbies at this point")
 	}
 
 	var existingLobby *Lobby
@@ -95,7 +133,7 @@ func (lm *LobbyManager) CreateLobby(ownerID ClientID, colonyID uint32, userSetEn
 	})
 
 	if existingLobby != nil {
-		return existingLobby, nil
+		return existingLobby, false, nil
 	}
 
 	lobbyID := lm.nextLobbyID.Add(1)
@@ -106,20 +144,41 @@ func (lm *LobbyManager) CreateLobby(ownerID ClientID, colonyID uint32, userSetEn
 		encodingToUse = lm.configuration.Encoding
 	}
 
-	lobby := NewLobby(lobbyID, ownerID, colonyID, encodingToUse, lm.CloseQueue)
+	lobby = NewLobby(lobbyID, ownerID, colonyID, encodingToUse, lm.CloseQueue, lm.Lifecycle, lm.MainBackend, lm.Outbox, lm.configuration, settings)
 	lm.Lobbies.Store(lobbyID, lobby)
+	lm.Lifecycle.Publish(&LobbyCreatedEvent{LobbyID: lobbyID, ColonyID: colonyID, OwnerID: ownerID})
 
-	log.Println("[lob man] Lobby created, id:", lobbyID, " chosen broadcasting encoding: ", encodingToUse)
-	return lobby, nil
+	log.Println("[lob man] Lobby created, id:", lobbyID, " chosen broadcasting encoding: ", encodingToUse,
+		" ping interval: ", lobby.PingInterval, " pong timeout: ", lobby.PongTimeout)
+	return lobby, true, nil
 }
 
-func (lm *LobbyManager) IsJoinPossible(lobbyID LobbyID, clientID ClientID, colonyID uint32, colonyOwnerID uint32) *LobbyJoinError {
+// Compares the protocol version sent by a connecting client with ProtocolVersion, as per the protocol version policy.
+// Only errors if the client is to be rejected
+func (lm *LobbyManager) CheckProtocolVersion(clientVersion string) error {
+	policy := lm.configuration.ProtocolVersionPolicy
+	serverVersion := ProtocolVersion()
+	if policy == meta.PROTOCOL_VERSION_POLICY_IGNORE || clientVersion == serverVersion {
+		return nil
+	}
+	err := fmt.Errorf("client protocol version \"%s\" does not match \"%s\"", clientVersion, serverVersion)
+	if clientVersion == "" {
+		err = fmt.Errorf("client sent no protocol version, expected \"%s\"", serverVersion)
+	}
+	if policy == meta.PROTOCOL_VERSION_POLICY_REJECT {
+		return err
+	}
+	log.Printf("[lob man] Warning: %v", err)
+	return nil
+}
+
+func (lm *LobbyManager) IsJoinPossible(lobbyID LobbyID, clientID ClientID, clientType OriginType, colonyID uint32, colonyOwnerID uint32) *LobbyJoinError {
 	lobby, exists := lm.Lobbies.Load(lobbyID)
 	if !exists {
 		//In the case we have a de-sync issue, attempt to close the colony
 		//it will error if the colony is already closed, or doesn't exist, but in this specific case
 		//we don't mind
-		go integrations.GetMainBackendIntegration().CloseColony(colonyID, colonyOwnerID)
+		go lm.MainBackend.CloseColony(colonyID, colonyOwnerID)
 		return &LobbyJoinError{Reason: "Lobby does not exist", Type: JoinErrorNotFound, LobbyID: lobbyID}
 	}
 
@@ -134,11 +193,19 @@ func (lm *LobbyManager) IsJoinPossible(lobbyID LobbyID, clientID ClientID, colon
 		//IMPOSTER!
 		return &LobbyJoinError{Reason: "User is already in lobby", Type: JoinErrorAlreadyInLobby, LobbyID: lobbyID}
 	}
+
+	if lobby.IsBanned(clientID) {
+		return &LobbyJoinError{Reason: "User is banned from lobby", Type: JoinErrorBanned, LobbyID: lobbyID}
+	}
+
+	if !lobby.QueueWhenFull && !lobby.HasRoomFor(lobby.effectiveClientType(clientID, clientType)) {
+		return &LobbyJoinError{Reason: "Lobby is full", Type: JoinErrorFull, LobbyID: lobbyID}
+	}
 	return nil
 }
 
-// JoinLobby allows a user to join a specific lobby
-func (lm *LobbyManager) JoinLobby(lobbyID LobbyID, clientID ClientID, clientIGN string, conn *websocket.Conn) *LobbyJoinError {
+// JoinLobby allows a user to join a specific lobby. The client type is taken from the join ticket the user connected with
+func (lm *LobbyManager) JoinLobby(lobbyID LobbyID, clientID ClientID, clientIGN string, clientType OriginType, conn *websocket.Conn) *LobbyJoinError {
 	lobby, exists := lm.Lobbies.Load(lobbyID)
 	if !exists {
 		return &LobbyJoinError{Reason: "Lobby does not exist", Type: JoinErrorNotFound, LobbyID: lobbyID}
@@ -153,25 +220,53 @@ func (lm *LobbyManager) JoinLobby(lobbyID LobbyID, clientID ClientID, clientIGN
 		return &LobbyJoinError{Reason: "User is already in lobby", Type: JoinErrorAlreadyInLobby, LobbyID: lobbyID}
 	}
 
-	client := NewClient(clientID, clientIGN,
-		util.Ternary(lobby.OwnerID == clientID, ORIGIN_TYPE_OWNER, ORIGIN_TYPE_GUEST),
-		conn, lobby.Encoding,
-	)
-
-	msg, err := Serialize(PLAYER_JOINED_EVENT, PlayerJoinedMessageDTO{
-		PlayerID: client.ID,
-		IGN:      client.IGN,
-	})
-	if err != nil {
-		return &LobbyJoinError{Reason: "Failed to serialize player joined message", Type: JoinErrorSerializationFailure, LobbyID: lobbyID}
+	if lobby.IsBanned(clientID) {
+		return &LobbyJoinError{Reason: "User is banned from lobby", Type: JoinErrorBanned, LobbyID: lobbyID}
 	}
 
-	//Broadcasting before we add the client to the lobbies client map
-	lobby.BroadcastMessage(SERVER_ID, msg)
+	client := NewClient(clientID, clientIGN, lobby.effectiveClientType(clientID, clientType), conn, lobby.Encoding, lm.configuration)
+	if err := lobby.admitOrQueue(client); err != nil {
+		return err
+	}
 
-	lobby.Clients.Store(client.ID, client)
 	// Handle the user's connection
 	go lobby.handleConnection(client)
 
 	return nil
 }
+
+// Verifies that the client is still part of the lobby and that the resume token matches
+func (lm *LobbyManager) IsResumePossible(lobbyID LobbyID, clientID ClientID, resumeToken string) *LobbyJoinError {
+	_, _, err := lm.findResumableClient(lobbyID, clientID, resumeToken)
+	return err
+}
+
+// Resumes the session of a client that lost its connection, swapping in the new connection
+func (lm *LobbyManager) ResumeSession(lobbyID LobbyID, clientID ClientID, resumeToken string, conn *websocket.Conn) *LobbyJoinError {
+	lobby, client, err := lm.findResumableClient(lobbyID, clientID, resumeToken)
+	if err != nil {
+		return err
+	}
+	return lobby.ResumeClient(client, conn, resumeToken)
+}
+
+func (lm *LobbyManager) findResumableClient(lobbyID LobbyID, clientID ClientID, resumeToken string) (*Lobby, *Client, *LobbyJoinError) {
+	lobby, exists := lm.Lobbies.Load(lobbyID)
+	if !exists {
+		return nil, nil, &LobbyJoinError{Reason: "Lobby does not exist", Type: JoinErrorNotFound, LobbyID: lobbyID}
+	}
+
+	if lobby.Closing.Load() {
+		return nil, nil, &LobbyJoinError{Reason: "Lobby is closing", Type: JoinErrorClosing, LobbyID: lobbyID}
+	}
+
+	client, exists := lobby.Clients.Load(clientID)
+	if !exists {
+		return nil, nil, &LobbyJoinError{Reason: "No session to resume, the grace period may have expired", Type: JoinErrorResumeRejected, LobbyID: lobbyID}
+	}
+
+	if !client.hasResumeToken(resumeToken) {
+		return nil, nil, &LobbyJoinError{Reason: "Invalid resume token", Type: JoinErrorResumeRejected, LobbyID: lobbyID}
+	}
+	return lobby, client, nil
+}
@@ -0,0 +1,477 @@
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
+	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
+)
+
+const testTimeout = 2 * time.Second
+
+var initTestEventsOnce sync.Once
+
+// Lobby manager on the fake main backend, with its outbox in a temporary directory.
+// configure may change the runtime configuration before anything is created with it
+func newTestLobbyManager(t *testing.T, configure func(*meta.RuntimeConfiguration)) *LobbyManager {
+	initTestEventsOnce.Do(func() {
+		if err := InitEventSpecifications(); err != nil {
+			panic(err)
+		}
+	})
+	configuration := meta.NewRuntimeConfiguration(meta.RUNTIME_MODE_DEV, meta.MESSAGE_ENCODING_BINARY)
+	if configure != nil {
+		configure(configuration)
+	}
+	fake, err := integrations.NewFakeMainBackend("../../fakeMainBackendSettings.json")
+	if err != nil {
+		t.Fatalf("Error creating fake main backend: %v", err)
+	}
+	outbox, err := integrations.NewOutbox(fake, integrations.OutboxOptions{Path: filepath.Join(t.TempDir(), "outbox.json")})
+	if err != nil {
+		t.Fatalf("Error creating outbox: %v", err)
+	}
+	t.Cleanup(outbox.Close)
+	return CreateLobbyManager(configuration, fake, outbox)
+}
+
+func newTestLobby(t *testing.T, lm *LobbyManager, ownerID ClientID, settings LobbySettings) *Lobby {
+	lobby, _, err := lm.CreateLobby(ownerID, ownerID, meta.MESSAGE_ENCODING_BINARY, settings)
+	if err != nil {
+		t.Fatalf("Error creating lobby: %v", err)
+	}
+	return lobby
+}
+
+// Serves /connect like the public API, but takes the lobby, client and role from query params rather than a join ticket.
+// Failed joins are closed with close code 1008 and the reason
+func newTestLobbyServer(t *testing.T, lm *LobbyManager) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		lobbyID, _ := strconv.ParseUint(query.Get("lobbyID"), 10, 32)
+		clientID, _ := strconv.ParseUint(query.Get("clientID"), 10, 32)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		var joinErr *LobbyJoinError
+		if resumeToken := query.Get("resumeToken"); resumeToken != "" {
+			joinErr = lm.ResumeSession(LobbyID(lobbyID), ClientID(clientID), resumeToken, conn)
+		} else {
+			joinErr = lm.JoinLobby(LobbyID(lobbyID), ClientID(clientID), "Player"+query.Get("clientID"), query.Get("role"), conn)
+		}
+		if joinErr != nil {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, joinErr.Reason))
+			conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// The client side of a connection to a test lobby
+type testConnection struct {
+	t    *testing.T
+	ID   ClientID
+	conn *websocket.Conn
+	// Of the last ResumeToken event read
+	resumeToken string
+}
+
+// Connects to the lobby. Resumes the session if a resume token is given
+func dialTestLobby(t *testing.T, server *httptest.Server, lobby *Lobby, clientID ClientID, role OriginType, resumeToken string) *testConnection {
+	url := fmt.Sprintf("ws%s/connect?lobbyID=%d&clientID=%d&role=%s&resumeToken=%s", strings.TrimPrefix(server.URL, "http"), lobby.ID, clientID, role, resumeToken)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Error connecting client %d: %v", clientID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testConnection{t: t, ID: clientID, conn: conn}
+}
+
+// Joins the lobby and waits for the resume token, by when the client has been added
+func joinTestLobby(t *testing.T, server *httptest.Server, lobby *Lobby, clientID ClientID, role OriginType) *testConnection {
+	tc := dialTestLobby(t, server, lobby, clientID, role, "")
+	tc.awaitEvent(RESUME_TOKEN_EVENT.ID)
+	return tc
+}
+
+// Reads until a message with the given id arrives, and returns its remainder. Fails the test if none does in time
+func (tc *testConnection) awaitEvent(messageID MessageID) []byte {
+	tc.t.Helper()
+	for {
+		tc.conn.SetReadDeadline(time.Now().Add(testTimeout))
+		_, data, err := tc.conn.ReadMessage()
+		if err != nil {
+			tc.t.Fatalf("Client %d did not recieve message of id %d: %v", tc.ID, messageID, err)
+		}
+		// Sender id, then message id
+		if len(data) < 8 {
+			continue
+		}
+		id := binary.BigEndian.Uint32(data[4:8])
+		if id == RESUME_TOKEN_EVENT.ID {
+			if token, err := Deserialize(RESUME_TOKEN_EVENT, data[8:], true); err == nil {
+				tc.resumeToken = token.Token
+			}
+		}
+		if id == messageID {
+			return data[8:]
+		}
+	}
+}
+
+// Reads until the connection is closed. Returns the close code, or -1 if closed without a close frame
+func (tc *testConnection) awaitClose() int {
+	tc.t.Helper()
+	for {
+		tc.conn.SetReadDeadline(time.Now().Add(testTimeout))
+		_, _, err := tc.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			return closeErr.Code
+		}
+		if strings.Contains(err.Error(), "timeout") {
+			tc.t.Fatalf("Connection of client %d was not closed in time", tc.ID)
+		}
+		return -1
+	}
+}
+
+// Sends the serialized message, prepended with the sender id
+func (tc *testConnection) send(senderID ClientID, message []byte) {
+	tc.t.Helper()
+	data := binary.BigEndian.AppendUint32(nil, senderID)
+	if err := tc.conn.WriteMessage(websocket.BinaryMessage, append(data, message...)); err != nil {
+		tc.t.Fatalf("Error sending from client %d: %v", tc.ID, err)
+	}
+}
+
+// Closes the underlying connection without a close frame, as a dropped connection would
+func (tc *testConnection) drop() {
+	tc.conn.UnderlyingConn().Close()
+}
+
+// Forwards lifecycle events of type T. Events that don't fit the buffer are dropped rather than blocking the bus
+func subscribeTestLifecycle[T LifecycleEvent](lm *LobbyManager) <-chan T {
+	events := make(chan T, 64)
+	lm.Lifecycle.Subscribe(fmt.Sprintf("test %T", *new(T)), func(event LifecycleEvent) {
+		if typed, ok := event.(T); ok {
+			select {
+			case events <- typed:
+			default:
+			}
+		}
+	})
+	return events
+}
+
+func awaitTestLifecycle[T LifecycleEvent](t *testing.T, events <-chan T, matches func(T) bool) T {
+	t.Helper()
+	timeout := time.After(testTimeout)
+	for {
+		select {
+		case event := <-events:
+			if matches(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("Expected lifecycle event %T in time", *new(T))
+		}
+	}
+}
+
+// For state that changes without any event to wait for
+func awaitCondition(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s in time", description)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func awaitDisconnected(t *testing.T, lobby *Lobby, clientID ClientID) *Client {
+	t.Helper()
+	client, _ := lobby.Clients.Load(clientID)
+	if client == nil {
+		t.Fatalf("Client %d not in lobby", clientID)
+	}
+	awaitCondition(t, fmt.Sprintf("client %d to be disconnected", clientID), func() bool { return !client.Connected.Load() })
+	return client
+}
+
+func TestResumeWithinGracePeriod(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	owner := joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	guest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+
+	guest.drop()
+	awaitDisconnected(t, lobby, 2)
+	// Broadcast while the guest is away
+	joinTestLobby(t, server, lobby, 3, ORIGIN_TYPE_GUEST)
+
+	resumed := dialTestLobby(t, server, lobby, 2, "", guest.resumeToken)
+	joined, err := Deserialize(PLAYER_JOINED_EVENT, resumed.awaitEvent(PLAYER_JOINED_EVENT.ID), true)
+	if err != nil || joined.PlayerID != 3 {
+		t.Errorf("Expected the missed PlayerJoined of client 3, got %v, %v", joined, err)
+	}
+	resumed.awaitEvent(RESUME_TOKEN_EVENT.ID)
+	if resumed.resumeToken == "" || resumed.resumeToken == guest.resumeToken {
+		t.Errorf("Expected a new resume token, got %q", resumed.resumeToken)
+	}
+	reconnected, err := Deserialize(PLAYER_RECONNECTED_EVENT, owner.awaitEvent(PLAYER_RECONNECTED_EVENT.ID), true)
+	if err != nil || reconnected.PlayerID != 2 {
+		t.Errorf("Expected the owner to be told client 2 reconnected, got %v, %v", reconnected, err)
+	}
+
+	// A leaked token can't be replayed
+	if code := dialTestLobby(t, server, lobby, 2, "", guest.resumeToken).awaitClose(); code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected a used resume token to be rejected with %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+	client, _ := lobby.Clients.Load(2)
+	if !client.Connected.Load() {
+		t.Error("Expected the resumed session to be unaffected by the rejected resume")
+	}
+}
+
+func TestPromoteSkipsDisconnectedPlayers(t *testing.T) {
+	lm := newTestLobbyManager(t, func(configuration *meta.RuntimeConfiguration) {
+		configuration.OwnerDisconnectPolicy = meta.OWNER_DISCONNECT_POLICY_PROMOTE
+	})
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	owner := joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	earliest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+	latest := joinTestLobby(t, server, lobby, 3, ORIGIN_TYPE_GUEST)
+
+	earliest.drop()
+	awaitDisconnected(t, lobby, 2)
+	owner.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+
+	changed, err := Deserialize(OWNER_CHANGED_EVENT, latest.awaitEvent(OWNER_CHANGED_EVENT.ID), true)
+	if err != nil || changed.PlayerID != 3 {
+		t.Errorf("Expected the connected client 3 to be promoted, got %v, %v", changed, err)
+	}
+	if ownerID := lobby.OwnerID.Load(); ownerID != 3 {
+		t.Errorf("Expected client 3 to own the lobby, got %d", ownerID)
+	}
+}
+
+func TestResumeRejectsWrongToken(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	guest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+
+	guest.drop()
+	client := awaitDisconnected(t, lobby, 2)
+	if code := dialTestLobby(t, server, lobby, 2, "", "wrong").awaitClose(); code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected a wrong resume token to be rejected with %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+	if client.Connected.Load() {
+		t.Error("Expected the client to stay disconnected")
+	}
+	if !client.hasResumeToken(guest.resumeToken) {
+		t.Error("Expected the actual resume token to still be valid")
+	}
+}
+
+func TestResumeAfterGracePeriodExpired(t *testing.T) {
+	lm := newTestLobbyManager(t, func(configuration *meta.RuntimeConfiguration) {
+		configuration.ReconnectGracePeriod = 50 * time.Millisecond
+	})
+	left := subscribeTestLifecycle[*ClientLeftEvent](lm)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	owner := joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	guest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+
+	guest.drop()
+	awaitTestLifecycle(t, left, func(event *ClientLeftEvent) bool { return event.ClientID == 2 })
+	playerLeft, err := Deserialize(PLAYER_LEFT_EVENT, owner.awaitEvent(PLAYER_LEFT_EVENT.ID), true)
+	if err != nil || playerLeft.PlayerID != 2 {
+		t.Errorf("Expected the owner to be told client 2 left, got %v, %v", playerLeft, err)
+	}
+	if code := dialTestLobby(t, server, lobby, 2, "", guest.resumeToken).awaitClose(); code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected resuming after the grace period to be rejected with %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+}
+
+// Players in their grace period keep their place, and are expected to take part in a locked in activity
+func TestDisconnectedPlayersAreCounted(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	guest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+	joinTestLobby(t, server, lobby, 3, ORIGIN_TYPE_SPECTATOR)
+
+	guest.drop()
+	awaitDisconnected(t, lobby, 2)
+	if lobby.ClientCount() != 3 || lobby.PlayerCount() != 2 {
+		t.Errorf("Expected 3 clients and 2 players, got %d and %d", lobby.ClientCount(), lobby.PlayerCount())
+	}
+
+	owner, _ := lobby.Clients.Load(1)
+	msg, err := Serialize(DIFFICULTY_CONFIRMED_FOR_MINIGAME_EVENT, DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 1, DifficultyName: "Test"})
+	if err != nil {
+		t.Fatalf("Error serializing: %v", err)
+	}
+	lobby.trackPhaseRoamningColony(owner, ALL_EVENTS[DIFFICULTY_CONFIRMED_FOR_MINIGAME_EVENT.ID], msg[4:])
+	if expected := lobby.activityTracker.participantTracker.playersToAccountFor.Load(); expected != 2 {
+		t.Errorf("Expected 2 participants to account for, got %d", expected)
+	}
+}
+
+func TestSilentClientIsEvicted(t *testing.T) {
+	// The grace period is left long, as a missed heartbeat skips it
+	lm := newTestLobbyManager(t, nil)
+	left := subscribeTestLifecycle[*ClientLeftEvent](lm)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{PingInterval: 100 * time.Millisecond, PongTimeout: 100 * time.Millisecond})
+	server := newTestLobbyServer(t, lm)
+	// Pongs are only sent while reading, which the owner does for as long as it waits for PlayerLeft
+	owner := joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+
+	playerLeft, err := Deserialize(PLAYER_LEFT_EVENT, owner.awaitEvent(PLAYER_LEFT_EVENT.ID), true)
+	if err != nil || playerLeft.PlayerID != 2 {
+		t.Fatalf("Expected the owner to be told client 2 left, got %v, %v", playerLeft, err)
+	}
+	event := awaitTestLifecycle(t, left, func(event *ClientLeftEvent) bool { return event.ClientID == 2 })
+	if event.Reason != "Missed heartbeat" {
+		t.Errorf("Expected the client to be evicted for missing the heartbeat, got reason %q", event.Reason)
+	}
+	if _, stillThere := lobby.Clients.Load(1); !stillThere {
+		t.Error("Expected the owner, who answered every ping, to stay")
+	}
+}
+
+func TestSpoofedSenderIDIsRejected(t *testing.T) {
+	lm := newTestLobbyManager(t, func(configuration *meta.RuntimeConfiguration) {
+		configuration.SpoofDisconnectThreshold = 2
+	})
+	left := subscribeTestLifecycle[*ClientLeftEvent](lm)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	guest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+
+	msg := PLAYER_LOAD_COMPLETE_EVENT.CopyIDBytes()
+	for offence := uint32(1); offence <= 2; offence++ {
+		guest.send(1, msg)
+		rejected, err := Deserialize(SENDER_ID_REJECTED_EVENT, guest.awaitEvent(SENDER_ID_REJECTED_EVENT.ID), true)
+		if err != nil {
+			t.Fatalf("Error deserializing: %v", err)
+		}
+		expected := SenderIDRejectedMessageDTO{ExpectedID: 2, ReceivedID: 1, Offences: offence, Threshold: 2}
+		if *rejected != expected {
+			t.Errorf("Expected %+v, got %+v", expected, *rejected)
+		}
+	}
+
+	// Tolerated up to the threshold, evicted past it
+	guest.send(1, msg)
+	if code := guest.awaitClose(); code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected to be disconnected with %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+	event := awaitTestLifecycle(t, left, func(event *ClientLeftEvent) bool { return event.ClientID == 2 })
+	if event.Reason != "Spoofed sender id" {
+		t.Errorf("Expected the client to be evicted for spoofing, got reason %q", event.Reason)
+	}
+}
+
+func TestSpoofedSenderIDWithoutThreshold(t *testing.T) {
+	lm := newTestLobbyManager(t, func(configuration *meta.RuntimeConfiguration) {
+		configuration.SpoofDisconnectThreshold = 0
+	})
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	guest := joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+
+	for offence := uint32(1); offence <= 5; offence++ {
+		guest.send(3, PLAYER_LOAD_COMPLETE_EVENT.CopyIDBytes())
+		rejected, err := Deserialize(SENDER_ID_REJECTED_EVENT, guest.awaitEvent(SENDER_ID_REJECTED_EVENT.ID), true)
+		if err != nil || rejected.Offences != offence || rejected.Threshold != 0 {
+			t.Fatalf("Expected offence %d without threshold, got %+v, %v", offence, rejected, err)
+		}
+	}
+	if client, _ := lobby.Clients.Load(2); client == nil || !client.Connected.Load() {
+		t.Error("Expected the client to stay connected when no threshold is configured")
+	}
+}
+
+// Closing the colony is queued on the outbox directly, so it isn't lost even if the lifecycle bus drops events
+func TestClosingLobbyClosesColony(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	lobby.close()
+
+	fake := lm.MainBackend.(*integrations.FakeMainBackend)
+	awaitCondition(t, "colony 1 to be closed", func() bool { return fake.IsColonyClosed(lobby.ColonyID) })
+}
+
+func TestLobbyIsClosedOnce(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lobby.close()
+		}()
+	}
+	wg.Wait()
+	// Lets the subscribers finish
+	lm.Lifecycle.Close()
+	if closed := lm.Metrics.LobbiesClosed.Load(); closed != 1 {
+		t.Errorf("Expected a single LobbyClosed event, got %d", closed)
+	}
+}
+
+func TestClientIsRemovedOnce(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	server := newTestLobbyServer(t, lm)
+	joinTestLobby(t, server, lobby, 1, ORIGIN_TYPE_OWNER)
+	joinTestLobby(t, server, lobby, 2, ORIGIN_TYPE_GUEST)
+	guest, _ := lobby.Clients.Load(2)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lobby.RemoveClientWithReason(guest, websocket.ClosePolicyViolation, "Kicked")
+		}()
+	}
+	wg.Wait()
+	// Lets the subscribers finish
+	lm.Lifecycle.Close()
+	if left := lm.Metrics.ClientsLeft.Load(); left != 1 {
+		t.Errorf("Expected a single ClientLeft event, got %d", left)
+	}
+}
@@ -1,8 +1,12 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"reflect"
+	"strings"
 
 	"github.com/lilybw/bsc-multiplayer-backend/src/util"
 )
@@ -14,12 +18,34 @@ type MessageElementDescriptor struct {
 	Offset      uint32
 	FieldName   string
 	Description string
-	Kind        reflect.Kind //We do not intend to encode structs, so this is fine
+	Kind        reflect.Kind //Never a struct, nested structs are flattened into their fields
+	// Upper bound of the byte size. Only set for variable size elements. Includes any length prefix
+	MaxByteSize uint32
+	// Byte size of the length prefix of slices, and of strings in WIRE_FORMAT_V2. 0 for everything else
+	LengthPrefixSize uint32
+	// Only for slices. Kind of the elements, either a fixed size kind or a struct of only those
+	ElemKind reflect.Kind
+	// Only for slices. Byte size of a single element
+	ElemByteSize uint32
+	// Only for slices of structs. Offsets are relative to the start of the element. Nested structs are flattened as in the message itself
+	ElemStructure ComputedStructure
+	// Only for slices. Upper bound of the amount of elements
+	MaxLength uint32
 }
 type ShortElementDescriptor struct {
 	Description string
 	FieldName   string
-	Kind        reflect.Kind //We do not intend to encode structs, so this is fine
+	Kind        reflect.Kind
+	// Only for strings. 0 means DEFAULT_MAX_VARIABLE_ELEMENT_SIZE
+	MaxByteSize uint32
+	// Only for slices
+	ElemKind reflect.Kind
+	// Only for slices of structs
+	ElemStructure ReferenceStructure
+	// Only for structs
+	Fields ReferenceStructure
+	// Only for slices. 0 means as many elements as fit in DEFAULT_MAX_VARIABLE_ELEMENT_SIZE
+	MaxLength uint32
 }
 
 // description is a human readable description of the element, appears as a comment in generated code
@@ -44,11 +70,43 @@ type ComputedStructure []MessageElementDescriptor
 // in bytes
 const MESSAGE_HEADER_SIZE uint32 = 8
 
+// in bytes. Applies to variable size elements declaring no maxSize tag
+const DEFAULT_MAX_VARIABLE_ELEMENT_SIZE uint32 = 1024
+
+// in bytes. Slices are sent as a big endian uint32 element count followed by the elements back to back
+const SLICE_LENGTH_PREFIX_SIZE uint32 = 4
+
+// in bytes. In WIRE_FORMAT_V2 strings are sent as a big endian uint16 byte count followed by the string
+const STRING_LENGTH_PREFIX_SIZE uint32 = 2
+
+// in bytes. As STRING_LENGTH_PREFIX_SIZE, but a uint32 byte count, for strings whose max size doesn't fit a uint16
+const LONG_STRING_LENGTH_PREFIX_SIZE uint32 = 4
+
+// How variable size elements are laid out on the wire
+type WireFormat uint8
+
+const (
+	// At most one variable size element, which must be last. Strings take up the rest of the message
+	WIRE_FORMAT_V1 WireFormat = 1
+	// Any amount of variable size elements in any position. Strings are length prefixed as well,
+	// so offsets past the first variable size element are only known at runtime
+	WIRE_FORMAT_V2 WireFormat = 2
+)
+
 // PANICS if the kind is not supported, or the ReferenceStructure does not adhere to simplified message format
 //
 // Returns the minimum total size of any message of this description as well as the full computed structure
 // the min size does not include the message header
 func ComputeStructure(messageName string, shortDescription ReferenceStructure) (uint32, ComputedStructure) {
+	return ComputeStructureForWireFormat(messageName, shortDescription, WIRE_FORMAT_V1)
+}
+
+// As ComputeStructure, but in WIRE_FORMAT_V2 variable size elements are allowed in any position.
+// Offsets past the first variable size element are then the least possible, i.e. as if every variable size element before it was empty
+func ComputeStructureForWireFormat(messageName string, shortDescription ReferenceStructure, format WireFormat) (uint32, ComputedStructure) {
+	if format != WIRE_FORMAT_V1 && format != WIRE_FORMAT_V2 {
+		panic(fmt.Errorf("message %s has unknown wire format %d", messageName, format))
+	}
 	var computedStructure ComputedStructure
 	var offset uint32 = MESSAGE_HEADER_SIZE
 	var hasVariableSizeElement bool = false
@@ -59,32 +117,171 @@ func ComputeStructure(messageName string, shortDescription ReferenceStructure) (
 			panic(err)
 		}
 
-		var isVariable bool = IsKindOfVariableSize(element.Kind)
-		if isVariable && hasVariableSizeElement {
-			panic(fmt.Errorf("message %s has multiple variable size elements", messageName))
+		if element.Kind == reflect.Struct {
+			fields, err := flattenFixedSizeFields(element.FieldName+".", element.Fields, offset)
+			if err != nil {
+				panic(fmt.Errorf("message %s: %s", messageName, err.Error()))
+			}
+			if len(fields) == 0 {
+				panic(fmt.Errorf("message %s: struct %s is empty", messageName, element.FieldName))
+			}
+			for _, field := range fields {
+				computedStructure = append(computedStructure, field)
+				offset += field.ByteSize
+				minimumTotalSize += field.ByteSize
+			}
+			continue
 		}
 
-		// Any variable elements should be on the end of the message
-		if isVariable && index != len(shortDescription)-1 {
-			panic(fmt.Errorf("message %s has a variable size element that is not the last element", messageName))
+		var isVariable bool = IsKindOfVariableSize(element.Kind)
+		if format == WIRE_FORMAT_V1 {
+			if isVariable && hasVariableSizeElement {
+				panic(fmt.Errorf("message %s has multiple variable size elements", messageName))
+			}
+
+			// Any variable elements should be on the end of the message
+			if isVariable && index != len(shortDescription)-1 {
+				panic(fmt.Errorf("message %s has a variable size element that is not the last element", messageName))
+			}
 		}
+		hasVariableSizeElement = hasVariableSizeElement || isVariable
 
 		// Extract the actual value from the interface and use unsafe.Sizeof
 		sizeOfElement := util.SizeOfSerializedKind(element.Kind)
 
-		computedStructure = append(computedStructure, MessageElementDescriptor{
+		computed := MessageElementDescriptor{
 			ByteSize:    sizeOfElement,
 			Offset:      offset,
 			FieldName:   element.FieldName,
 			Description: element.Description,
 			Kind:        element.Kind,
-		})
-		offset += sizeOfElement
-		minimumTotalSize += sizeOfElement
+		}
+		if element.Kind == reflect.Slice {
+			computeSliceElement(messageName, element, &computed)
+		} else if isVariable {
+			computed.MaxByteSize = util.Ternary(element.MaxByteSize == 0, DEFAULT_MAX_VARIABLE_ELEMENT_SIZE, element.MaxByteSize)
+			if format == WIRE_FORMAT_V2 {
+				computed.LengthPrefixSize = util.Ternary(computed.MaxByteSize > math.MaxUint16, LONG_STRING_LENGTH_PREFIX_SIZE, STRING_LENGTH_PREFIX_SIZE)
+				if computed.MaxByteSize > math.MaxUint32-computed.LengthPrefixSize {
+					panic(fmt.Errorf("message %s: max size %d of %s does not fit its length prefix", messageName, computed.MaxByteSize, element.FieldName))
+				}
+				computed.MaxByteSize += computed.LengthPrefixSize
+			}
+		}
+
+		computedStructure = append(computedStructure, computed)
+		// Even an empty slice or string has its length prefix
+		offset += sizeOfElement + computed.LengthPrefixSize
+		minimumTotalSize += sizeOfElement + computed.LengthPrefixSize
 	}
 	return minimumTotalSize, computedStructure
 }
 
+// PANICS if the elements are not of a fixed size kind or a struct of only those
+func computeSliceElement(messageName string, element ShortElementDescriptor, computed *MessageElementDescriptor) {
+	computed.ElemKind = element.ElemKind
+	computed.LengthPrefixSize = SLICE_LENGTH_PREFIX_SIZE
+	if element.ElemKind == reflect.Struct {
+		elemStructure, err := flattenFixedSizeFields("", element.ElemStructure, 0)
+		if err != nil {
+			panic(fmt.Errorf("message %s: elements of %s: %s", messageName, element.FieldName, err.Error()))
+		}
+		computed.ElemStructure = elemStructure
+		for _, field := range elemStructure {
+			computed.ElemByteSize += field.ByteSize
+		}
+	} else if isFixedSizeElemKind(element.ElemKind) {
+		computed.ElemByteSize = util.SizeOfSerializedKind(element.ElemKind)
+	} else {
+		panic(fmt.Errorf("message %s: elements of %s are of kind %s, only fixed size kinds and structs of those are supported", messageName, element.FieldName, element.ElemKind))
+	}
+	if computed.ElemByteSize == 0 {
+		panic(fmt.Errorf("message %s: elements of %s are empty", messageName, element.FieldName))
+	}
+
+	computed.MaxLength = util.Ternary(element.MaxLength == 0, max(DEFAULT_MAX_VARIABLE_ELEMENT_SIZE/computed.ElemByteSize, 1), element.MaxLength)
+	computed.MaxByteSize = SLICE_LENGTH_PREFIX_SIZE + computed.MaxLength*computed.ElemByteSize
+}
+
+// Flattens the fields, and the fields of any nested structs, into fixed size elements named by their dotted path, fx. "position.x".
+// Offsets count from offset. Errors if any field is not of a fixed size kind or a struct of those
+func flattenFixedSizeFields(path string, fields ReferenceStructure, offset uint32) (ComputedStructure, error) {
+	var flattened ComputedStructure
+	for _, field := range fields {
+		switch {
+		case field.Kind == reflect.Struct:
+			nested, err := flattenFixedSizeFields(path+field.FieldName+".", field.Fields, offset)
+			if err != nil {
+				return nil, err
+			}
+			if len(nested) == 0 {
+				return nil, fmt.Errorf("struct %s%s is empty", path, field.FieldName)
+			}
+			flattened = append(flattened, nested...)
+			last := nested[len(nested)-1]
+			offset = last.Offset + last.ByteSize
+		case isFixedSizeElemKind(field.Kind):
+			flattened = append(flattened, MessageElementDescriptor{
+				ByteSize:    util.SizeOfSerializedKind(field.Kind),
+				Offset:      offset,
+				FieldName:   path + field.FieldName,
+				Description: field.Description,
+				Kind:        field.Kind,
+			})
+			offset += util.SizeOfSerializedKind(field.Kind)
+		default:
+			return nil, fmt.Errorf("field %s%s is of kind %s, only fixed size kinds and structs of those are supported in structs", path, field.FieldName, field.Kind)
+		}
+	}
+	return flattened, nil
+}
+
+// Returns the maximum total size of any message of this structure, not including the message header
+func ComputeMaxSize(structure ComputedStructure) uint32 {
+	var maximumTotalSize uint32 = 0
+	for _, element := range structure {
+		maximumTotalSize += element.ByteSize + element.MaxByteSize
+	}
+	return maximumTotalSize
+}
+
+// Canonical description of how the structure is laid out on the wire, fx. "id:uint32;name:string/2<=130".
+// Structures of equal layout serialize any value the same way. Offsets and descriptions are left out
+func (structure ComputedStructure) Layout() string {
+	return structure.layout(";")
+}
+
+// Short hash of the layout. Changes whenever the way the structure is serialized does
+func (structure ComputedStructure) SchemaHash() string {
+	sum := sha256.Sum256([]byte(structure.Layout()))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (structure ComputedStructure) layout(separator string) string {
+	parts := make([]string, len(structure))
+	for i, element := range structure {
+		part := element.FieldName + ":"
+		switch {
+		case element.Kind == reflect.Slice && element.ElemKind == reflect.Struct:
+			part += "[]{" + element.ElemStructure.layout(",") + "}"
+		case element.Kind == reflect.Slice:
+			part += "[]" + element.ElemKind.String()
+		default:
+			part += element.Kind.String()
+		}
+		if element.LengthPrefixSize > 0 {
+			part += fmt.Sprintf("/%d", element.LengthPrefixSize)
+		}
+		if element.Kind == reflect.Slice {
+			part += fmt.Sprintf("<=%d", element.MaxLength)
+		} else if element.ByteSize == 0 {
+			part += fmt.Sprintf("<=%d", element.MaxByteSize)
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, separator)
+}
+
 func VerifyStructureTCompliance[T any](structure ComputedStructure) error {
 	for _, element := range structure {
 		if err := isValidKind(element.Kind); err != nil {
@@ -92,10 +289,13 @@ func VerifyStructureTCompliance[T any](structure ComputedStructure) error {
 		}
 	}
 	var tNull T
-	tVal := reflect.ValueOf(tNull)
+	return verifyStructureCompliance(reflect.ValueOf(tNull), structure)
+}
+
+func verifyStructureCompliance(tVal reflect.Value, structure ComputedStructure) error {
 	for _, element := range structure {
 		//Check if the field with a json tag by that name exists
-		field, present := util.FindFieldByJSONTagValue(tVal, element.FieldName)
+		field, present := util.FindFieldByJSONTagPath(tVal, element.FieldName)
 		if !present {
 			return fmt.Errorf("field %s not found in struct", element.FieldName)
 		}
@@ -103,6 +303,18 @@ func VerifyStructureTCompliance[T any](structure ComputedStructure) error {
 		if field.Kind() != element.Kind {
 			return fmt.Errorf("field %s has kind %s, expected %s", element.FieldName, field.Kind(), element.Kind)
 		}
+		if element.Kind != reflect.Slice {
+			continue
+		}
+		elemType := field.Type().Elem()
+		if elemType.Kind() != element.ElemKind {
+			return fmt.Errorf("elements of field %s have kind %s, expected %s", element.FieldName, elemType.Kind(), element.ElemKind)
+		}
+		if element.ElemKind == reflect.Struct {
+			if err := verifyStructureCompliance(reflect.Zero(elemType), element.ElemStructure); err != nil {
+				return fmt.Errorf("elements of field %s: %s", element.FieldName, err.Error())
+			}
+		}
 	}
 
 	return nil
@@ -111,20 +323,29 @@ func VerifyStructureTCompliance[T any](structure ComputedStructure) error {
 // In terms of expected message contents
 func isValidKind(kind reflect.Kind) error {
 	switch kind {
-	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String:
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String, reflect.Slice, reflect.Struct:
 		return nil
 	default:
 		return fmt.Errorf("kind %s is not supported", kind)
 	}
 }
 
+// Kinds slice elements, or the fields of structs, may be of
+func isFixedSizeElemKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
 var TypesAllowed = []reflect.Kind{
-	reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String,
+	reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String, reflect.Slice, reflect.Struct,
 }
 
 func IsKindOfVariableSize(kind reflect.Kind) bool {
 	switch kind {
-	case reflect.String, reflect.Slice, reflect.Array, reflect.Interface, reflect.Struct:
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Interface:
 		return true
 	}
 
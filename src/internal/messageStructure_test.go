@@ -1 +1,227 @@
 package internal
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestComputeMaxSize(t *testing.T) {
+	_, bounded := ComputeStructure("Bounded", ReferenceStructure{
+		{FieldName: "a", Kind: reflect.Uint32},
+		{FieldName: "b", Kind: reflect.String, MaxByteSize: 16},
+	})
+	if maxSize := ComputeMaxSize(bounded); maxSize != 4+16 {
+		t.Errorf("Expected max size 20, got %d", maxSize)
+	}
+
+	_, unbounded := ComputeStructure("Unbounded", ReferenceStructure{
+		{FieldName: "a", Kind: reflect.Uint32},
+		{FieldName: "b", Kind: reflect.String},
+	})
+	if maxSize := ComputeMaxSize(unbounded); maxSize != 4+DEFAULT_MAX_VARIABLE_ELEMENT_SIZE {
+		t.Errorf("Expected max size %d, got %d", 4+DEFAULT_MAX_VARIABLE_ELEMENT_SIZE, maxSize)
+	}
+
+	_, fixed := ComputeStructure("Fixed", ReferenceStructure{
+		{FieldName: "a", Kind: reflect.Uint32},
+		{FieldName: "b", Kind: reflect.Uint16},
+	})
+	if maxSize := ComputeMaxSize(fixed); maxSize != 6 {
+		t.Errorf("Expected max size 6, got %d", maxSize)
+	}
+}
+
+func TestExtractMessageHeaderBounds(t *testing.T) {
+	header := append([]byte{0, 0, 0, 1}, DEBUG_EVENT.CopyIDBytes()...)
+	code := []byte{0, 0, 0, 1}
+
+	message := make([]byte, DEFAULT_MAX_VARIABLE_ELEMENT_SIZE)
+	for i := range message {
+		message[i] = 'a'
+	}
+	if _, _, _, err := ExtractMessageHeader(append(append(header, code...), message...)); err != nil {
+		t.Errorf("Expected message at max size to be accepted, got %v", err)
+	}
+
+	_, _, _, err := ExtractMessageHeader(append(append(header, code...), append(message, 'a')...))
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+	}
+
+	_, _, _, err = ExtractMessageHeader(append(append(header, code...), 0xff, 0xfe))
+	if err == nil {
+		t.Error("Expected invalid UTF-8 to be rejected")
+	}
+}
+
+func TestComputeStructureSlices(t *testing.T) {
+	minSize, structure := ComputeStructure("Positions", ReferenceStructure{
+		{FieldName: "round", Kind: reflect.Uint8},
+		{FieldName: "positions", Kind: reflect.Slice, ElemKind: reflect.Struct, MaxLength: 8, ElemStructure: ReferenceStructure{
+			{FieldName: "id", Kind: reflect.Uint32},
+			{FieldName: "x", Kind: reflect.Float32},
+			{FieldName: "y", Kind: reflect.Float32},
+		}},
+	})
+	if minSize != 1+SLICE_LENGTH_PREFIX_SIZE {
+		t.Errorf("Expected min size %d, got %d", 1+SLICE_LENGTH_PREFIX_SIZE, minSize)
+	}
+	positions := structure[1]
+	if positions.Offset != MESSAGE_HEADER_SIZE+1 || positions.ElemByteSize != 12 || positions.ElemStructure[2].Offset != 8 {
+		t.Errorf("Unexpected layout of positions: %+v", positions)
+	}
+	if maxSize := ComputeMaxSize(structure); maxSize != 1+SLICE_LENGTH_PREFIX_SIZE+8*12 {
+		t.Errorf("Expected max size %d, got %d", 1+SLICE_LENGTH_PREFIX_SIZE+8*12, maxSize)
+	}
+
+	_, unbounded := ComputeStructure("IDs", ReferenceStructure{
+		{FieldName: "ids", Kind: reflect.Slice, ElemKind: reflect.Uint32},
+	})
+	if unbounded[0].MaxLength != DEFAULT_MAX_VARIABLE_ELEMENT_SIZE/4 {
+		t.Errorf("Expected max length %d, got %d", DEFAULT_MAX_VARIABLE_ELEMENT_SIZE/4, unbounded[0].MaxLength)
+	}
+}
+
+func TestComputeStructureRejectsUnsupportedSlices(t *testing.T) {
+	cases := map[string]ReferenceStructure{
+		"slice of strings": {
+			{FieldName: "names", Kind: reflect.Slice, ElemKind: reflect.String},
+		},
+		"struct elements with a string": {
+			{FieldName: "players", Kind: reflect.Slice, ElemKind: reflect.Struct, ElemStructure: ReferenceStructure{
+				{FieldName: "ign", Kind: reflect.String},
+			}},
+		},
+		"slice before other fields": {
+			{FieldName: "ids", Kind: reflect.Slice, ElemKind: reflect.Uint32},
+			{FieldName: "round", Kind: reflect.Uint8},
+		},
+	}
+	for name, structure := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected ComputeStructure to panic")
+				}
+			}()
+			ComputeStructure(name, structure)
+		})
+	}
+}
+
+func TestComputeStructureV2(t *testing.T) {
+	minSize, structure := ComputeStructureForWireFormat("Shoot", ReferenceStructure{
+		{FieldName: "id", Kind: reflect.Uint32},
+		{FieldName: "code", Kind: reflect.String, MaxByteSize: 32},
+		{FieldName: "label", Kind: reflect.String},
+		{FieldName: "round", Kind: reflect.Uint8},
+	}, WIRE_FORMAT_V2)
+	if minSize != 4+2*STRING_LENGTH_PREFIX_SIZE+1 {
+		t.Errorf("Expected min size %d, got %d", 4+2*STRING_LENGTH_PREFIX_SIZE+1, minSize)
+	}
+	if structure[3].Offset != MESSAGE_HEADER_SIZE+4+2*STRING_LENGTH_PREFIX_SIZE {
+		t.Errorf("Expected least possible offset %d of round, got %d", MESSAGE_HEADER_SIZE+4+2*STRING_LENGTH_PREFIX_SIZE, structure[3].Offset)
+	}
+	expectedMax := 4 + (STRING_LENGTH_PREFIX_SIZE + 32) + (STRING_LENGTH_PREFIX_SIZE + DEFAULT_MAX_VARIABLE_ELEMENT_SIZE) + 1
+	if maxSize := ComputeMaxSize(structure); maxSize != expectedMax {
+		t.Errorf("Expected max size %d, got %d", expectedMax, maxSize)
+	}
+
+	// Strings whose max size doesn't fit a uint16 get a uint32 length prefix
+	_, structure = ComputeStructureForWireFormat("Texts", ReferenceStructure{
+		{FieldName: "short", Kind: reflect.String, MaxByteSize: math.MaxUint16},
+		{FieldName: "long", Kind: reflect.String, MaxByteSize: math.MaxUint16 + 1},
+	}, WIRE_FORMAT_V2)
+	if structure[0].LengthPrefixSize != STRING_LENGTH_PREFIX_SIZE || structure[1].LengthPrefixSize != LONG_STRING_LENGTH_PREFIX_SIZE {
+		t.Errorf("Expected length prefixes of %d and %d bytes, got %d and %d", STRING_LENGTH_PREFIX_SIZE, LONG_STRING_LENGTH_PREFIX_SIZE,
+			structure[0].LengthPrefixSize, structure[1].LengthPrefixSize)
+	}
+	if structure[1].MaxByteSize != math.MaxUint16+1+LONG_STRING_LENGTH_PREFIX_SIZE {
+		t.Errorf("Expected max byte size %d, got %d", math.MaxUint16+1+LONG_STRING_LENGTH_PREFIX_SIZE, structure[1].MaxByteSize)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a max size over the length prefix to panic")
+		}
+	}()
+	ComputeStructureForWireFormat("TooLarge", ReferenceStructure{
+		{FieldName: "text", Kind: reflect.String, MaxByteSize: math.MaxUint32},
+	}, WIRE_FORMAT_V2)
+}
+
+func TestComputeStructureNestedStructs(t *testing.T) {
+	point := ReferenceStructure{
+		{FieldName: "x", Kind: reflect.Float32},
+		{FieldName: "y", Kind: reflect.Float32},
+	}
+	minSize, structure := ComputeStructure("Nested", ReferenceStructure{
+		{FieldName: "id", Kind: reflect.Uint32},
+		{FieldName: "line", Kind: reflect.Struct, Fields: ReferenceStructure{
+			{FieldName: "from", Kind: reflect.Struct, Fields: point},
+			{FieldName: "to", Kind: reflect.Struct, Fields: point},
+		}},
+		{FieldName: "players", Kind: reflect.Slice, ElemKind: reflect.Struct, ElemStructure: ReferenceStructure{
+			{FieldName: "id", Kind: reflect.Uint32},
+			{FieldName: "position", Kind: reflect.Struct, Fields: point},
+		}},
+	})
+	if minSize != 4+16+SLICE_LENGTH_PREFIX_SIZE {
+		t.Errorf("Expected min size %d, got %d", 4+16+SLICE_LENGTH_PREFIX_SIZE, minSize)
+	}
+
+	var names []string
+	for _, element := range structure {
+		names = append(names, element.FieldName)
+	}
+	expected := []string{"id", "line.from.x", "line.from.y", "line.to.x", "line.to.y", "players"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, names)
+	}
+	if toY := structure[4]; toY.Offset != MESSAGE_HEADER_SIZE+16 {
+		t.Errorf("Expected line.to.y at offset %d, got %d", MESSAGE_HEADER_SIZE+16, toY.Offset)
+	}
+	players := structure[5]
+	if players.ElemByteSize != 12 || players.ElemStructure[2].FieldName != "position.y" || players.ElemStructure[2].Offset != 8 {
+		t.Errorf("Unexpected layout of players: %+v", players)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a string in a nested struct to panic")
+		}
+	}()
+	ComputeStructure("NestedString", ReferenceStructure{
+		{FieldName: "player", Kind: reflect.Struct, Fields: ReferenceStructure{
+			{FieldName: "ign", Kind: reflect.String},
+		}},
+	})
+}
+
+func TestSchemaHash(t *testing.T) {
+	structureOf := func(charCodeSize uint32, format WireFormat) ComputedStructure {
+		_, structure := ComputeStructureForWireFormat("Hashed", ReferenceStructure{
+			{FieldName: "id", Kind: reflect.Uint32, Description: "ID"},
+			{FieldName: "charCode", Kind: reflect.String, MaxByteSize: charCodeSize},
+		}, format)
+		return structure
+	}
+	original := structureOf(32, WIRE_FORMAT_V1)
+	if original.Layout() != "id:uint32;charCode:string<=32" {
+		t.Errorf("Unexpected layout %q", original.Layout())
+	}
+
+	described := structureOf(32, WIRE_FORMAT_V1)
+	described[0].Description = "Changed"
+	if described.SchemaHash() != original.SchemaHash() {
+		t.Error("Expected descriptions not to affect the schema hash")
+	}
+	if structureOf(64, WIRE_FORMAT_V1).SchemaHash() == original.SchemaHash() {
+		t.Error("Expected a different max size to change the schema hash")
+	}
+	if structureOf(32, WIRE_FORMAT_V2).SchemaHash() == original.SchemaHash() {
+		t.Error("Expected a different wire format to change the schema hash")
+	}
+}
@@ -2,8 +2,11 @@ package internal
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"unicode/utf8"
 
 	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
 	"github.com/lilybw/bsc-multiplayer-backend/src/util"
@@ -23,18 +26,30 @@ func SendDebugInfoToClient(client *Client, code uint32, message string) error {
 	var messageBody = DEBUG_EVENT.CopyIDBytes()
 	var withCode = append(messageBody, util.BytesOfUint32(code)...)
 	var withMessage = append(withCode, []byte(message)...)
-	var isBinary = true
 	log.Println("Sending debug info, client encoding is: ", client.String())
+	messageType, encoded := encodeForClient(client, withMessage)
+	return client.Send(messageType, encoded)
+}
+
+// Sends a message to a single client only
+//
+// # Expects the message to be binary and pre-pended with the required messageID
+//
+// Prepends senderID
+func SendMessageToClient(client *Client, senderID ClientID, message []byte) error {
+	messageType, encoded := encodeForClient(client, util.CopyAndAppend(util.BytesOfUint32(senderID), message))
+	return client.Send(messageType, encoded)
+}
+
+// Returns the websocket message type and the message encoded as per the clients encoding
+func encodeForClient(client *Client, message []byte) (int, []byte) {
 	switch client.Encoding {
 	case meta.MESSAGE_ENCODING_BASE16:
-		isBinary = false
-		withMessage = util.EncodeBase16(withMessage)
+		return websocket.TextMessage, util.EncodeBase16(message)
 	case meta.MESSAGE_ENCODING_BASE64:
-		isBinary = false
-		withMessage = util.EncodeBase64(withMessage)
+		return websocket.TextMessage, util.EncodeBase64(message)
 	}
-
-	return client.Conn.WriteMessage(util.Ternary(isBinary, websocket.BinaryMessage, websocket.TextMessage), withMessage)
+	return websocket.BinaryMessage, message
 }
 
 var EMPTY_BYTE_ARR = []byte{}
@@ -56,9 +71,33 @@ func ExtractMessageHeader(msg []byte) (ClientID, *EventSpecification[any], []byt
 		return 0, nil, EMPTY_BYTE_ARR, fmt.Errorf("message ID %d not found", messageID)
 	} else if uint32(len(msg)) < spec.ExpectedMinSize+MESSAGE_HEADER_SIZE {
 		return 0, nil, EMPTY_BYTE_ARR, fmt.Errorf("message size too small. Expected at least %d bytes for message type %s, got %d", spec.ExpectedMinSize, spec.Name, len(msg))
+	} else if uint32(len(msg)) > spec.ExpectedMaxSize+MESSAGE_HEADER_SIZE {
+		return 0, nil, EMPTY_BYTE_ARR, fmt.Errorf("%w. Expected at most %d bytes for message type %s, got %d", ErrMessageTooLarge, spec.ExpectedMaxSize, spec.Name, len(msg))
 	}
 
-	return ClientID(userID), spec, msg[MESSAGE_HEADER_SIZE:], nil
+	remainder := msg[MESSAGE_HEADER_SIZE:]
+	spans, err := locateElements(spec.Structure, remainder, 0)
+	if err != nil {
+		return 0, nil, EMPTY_BYTE_ARR, fmt.Errorf("message type %s: %s", spec.Name, err.Error())
+	}
+	for i, element := range spec.Structure {
+		if element.Kind == reflect.String && !utf8.Valid(remainder[spans[i].start:spans[i].end]) {
+			return 0, nil, EMPTY_BYTE_ARR, fmt.Errorf("field %s of message type %s is not valid UTF-8", element.FieldName, spec.Name)
+		}
+	}
+
+	return ClientID(userID), spec, remainder, nil
+}
+
+var ErrMessageTooLarge = errors.New("message size too large")
+
+// In bytes, including the message header. Of all events currently loaded
+func LargestMessageSize() uint32 {
+	var largest uint32 = 0
+	for _, spec := range ALL_EVENTS {
+		largest = max(largest, spec.ExpectedMaxSize+MESSAGE_HEADER_SIZE)
+	}
+	return largest
 }
 
 // BroadcastMessage sends a message to all users in the lobby except the sender
@@ -98,6 +137,8 @@ func BroadcastMessageBase64(lobby *Lobby, senderID ClientID, message []byte) []*
 
 // Returns the clients that could not be reached (if any)
 //
+// Never blocks on slow clients, as messages are only queued for each clients writer routine
+//
 // Prepends senderID
 func broadcast(lobby *Lobby, senderID ClientID, message []byte, messageType int) []*Client {
 	var unreachableClients []*Client
@@ -107,7 +148,8 @@ func broadcast(lobby *Lobby, senderID ClientID, message []byte, messageType int)
 	message = append(wSenderID, message...)
 	lobby.Clients.Range(func(userID ClientID, user *Client) bool {
 		if userID != senderID {
-			err := user.Conn.WriteMessage(messageType, message)
+			// Disconnected clients have the message retained until they resume
+			err := user.deliverBroadcast(messageType, message)
 			replicationCount++
 			if err != nil {
 				log.Println("[messaging] Error sending message to user:", userID, err)
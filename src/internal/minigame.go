@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"log"
 	"sync/atomic"
 )
 
@@ -56,17 +57,79 @@ func LoadMinigameControls(diffDTO *DifficultyConfirmedForMinigameMessageDTO, lob
 	}
 }
 
+// Minigame controls loaded in the background from lock in, so fetching settings from the main backend
+// happens while players opt in and ready up, rather than when they expect the minigame to begin
+type minigamePrefetch struct {
+	diff *DifficultyConfirmedForMinigameMessageDTO
+	// Closed once controls or err is set
+	done     chan struct{}
+	controls *GenericMinigameControls
+	err      error
+}
+
+// Starts loading the controls of the locked in minigame. A failure is passed on to the post process routine,
+// which is the only one to change the state of the minigame
+func (lobby *Lobby) prefetchMinigameControls(diff *DifficultyConfirmedForMinigameMessageDTO) {
+	prefetch := &minigamePrefetch{diff: diff, done: make(chan struct{})}
+	lobby.activityTracker.prefetch.Store(prefetch)
+
+	go func() {
+		prefetch.controls, prefetch.err = LoadMinigameControls(diff, lobby, lobby.dismountCurrentActivity)
+		close(prefetch.done)
+		if prefetch.err == nil {
+			return
+		}
+		log.Printf("[lobby] Error prefetching minigame %d for lobby %d: %v", diff.MinigameID, lobby.ID, prefetch.err)
+		// If the queue is full, the error is handled once the controls are awaited instead
+		select {
+		case lobby.PostProcessQueue <- &MessageEntry{failedPrefetch: prefetch}:
+		default:
+		}
+	}()
+}
+
+// Called by the post process routine. If the failed prefetch is still that of the locked in minigame,
+// the lobby is sent an untimely abort and the lock is released
+func (lobby *Lobby) onPrefetchFailed(prefetch *minigamePrefetch) {
+	// Don't abort whatever has been locked in since, nor a minigame that has already awaited the controls and handled the error
+	if !lobby.activityTracker.prefetch.CompareAndSwap(prefetch, nil) {
+		return
+	}
+	if err := OnUntimelyMinigameAbort(prefetch.err.Error(), SERVER_ID, lobby, nil); err != nil {
+		log.Printf("[lobby] Error sending untimely abort message: %v", err)
+	}
+	lobby.activityTracker.ReleaseLock()
+}
+
+// Blocks until the prefetched controls are loaded. Loads them now if nothing was prefetched
+func (lobby *Lobby) awaitMinigameControls(diff *DifficultyConfirmedForMinigameMessageDTO) (*GenericMinigameControls, error) {
+	prefetch := lobby.activityTracker.prefetch.Swap(nil)
+	if prefetch == nil || prefetch.diff != diff {
+		return LoadMinigameControls(diff, lobby, lobby.dismountCurrentActivity)
+	}
+	<-prefetch.done
+	return prefetch.controls, prefetch.err
+}
+
+// Sent in place of a reason that could not be serialized, so that the players still learn of the abort
+const GENERIC_UNTIMELY_ABORT_REASON = "The minigame was aborted due to an internal error"
+
+// The reason is cut short to MAX_REASON_SIZE, as it is often an error of some dependency, fx. the main backend
 func OnUntimelyMinigameAbort(reason string, sourceID uint32, lobby *Lobby, state *atomic.Uint32) error {
 	if state != nil {
 		state.Store(uint32(MINIGAME_STATE_ABORT))
 	}
 	data := GenericUntimelyAbortMessageDTO{
-		Reason:   reason,
+		Reason:   truncateUTF8(reason, MAX_REASON_SIZE),
 		SourceID: sourceID,
 	}
 	serialized, err := Serialize(GENERIC_MINIGAME_UNTIMELY_ABORT, data)
 	if err != nil {
-		return err
+		log.Printf("[lobby] Error serializing untimely abort, sending a generic reason instead: %v", err)
+		data.Reason = GENERIC_UNTIMELY_ABORT_REASON
+		if serialized, err = Serialize(GENERIC_MINIGAME_UNTIMELY_ABORT, data); err != nil {
+			return err
+		}
 	}
 	lobby.BroadcastMessage(SERVER_ID, serialized)
 	return nil
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
+	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
+)
+
+func newPrefetchTestLobby(t *testing.T) *Lobby {
+	fake, err := integrations.NewFakeMainBackend("../../fakeMainBackendSettings.json")
+	if err != nil {
+		t.Fatalf("Error creating fake main backend: %v", err)
+	}
+	configuration := meta.NewRuntimeConfiguration(meta.RUNTIME_MODE_DEV, meta.MESSAGE_ENCODING_BINARY)
+	return NewLobby(1, 1, 1, meta.MESSAGE_ENCODING_BINARY, make(chan *Lobby, 1), NewLifecycleBus(), fake, nil, configuration, LobbySettings{})
+}
+
+func lockIn(t *testing.T, lobby *Lobby, diff *DifficultyConfirmedForMinigameMessageDTO) {
+	if !lobby.activityTracker.SetDiffConfirmed(diff) || !lobby.activityTracker.LockIn(0) {
+		t.Fatal("Expected lock in to succeed")
+	}
+	lobby.prefetchMinigameControls(diff)
+}
+
+func TestPrefetchedMinigameControls(t *testing.T) {
+	lobby := newPrefetchTestLobby(t)
+	diff := &DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 1}
+	lockIn(t, lobby, diff)
+
+	controls, err := lobby.awaitMinigameControls(diff)
+	if err != nil || controls == nil {
+		t.Fatalf("Expected prefetched controls, got %v, %v", controls, err)
+	}
+	if lobby.activityTracker.prefetch.Load() != nil {
+		t.Error("Expected the prefetch to be consumed")
+	}
+}
+
+func TestFailedPrefetchReleasesLock(t *testing.T) {
+	lobby := newPrefetchTestLobby(t)
+	lockIn(t, lobby, &DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 999})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for lobby.activityTracker.lockedIn.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if lobby.activityTracker.lockedIn.Load() {
+		t.Fatal("Expected the lock to be released after the prefetch failed")
+	}
+	if LobbyPhase(lobby.GetPhase()) != LOBBY_PHASE_ROAMING_COLONY {
+		t.Errorf("Expected roaming colony phase, got %s", LobbyPhase(lobby.GetPhase()))
+	}
+}
+
+func TestStaleFailedPrefetchKeepsLock(t *testing.T) {
+	lobby := newPrefetchTestLobby(t)
+	diff := &DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 1}
+	lockIn(t, lobby, diff)
+
+	// Of a minigame locked in before the current one
+	stale := &minigamePrefetch{diff: &DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 999}, done: make(chan struct{}), err: errors.New("stale")}
+	close(stale.done)
+	lobby.onPrefetchFailed(stale)
+
+	if !lobby.activityTracker.lockedIn.Load() {
+		t.Error("Expected the lock to be kept after a stale prefetch failed")
+	}
+	if _, err := lobby.awaitMinigameControls(diff); err != nil {
+		t.Errorf("Expected the current prefetch to be unaffected, got %v", err)
+	}
+}
+
+func TestFailedPrefetchWithRealisticErrorIsBroadcast(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	owner := joinTestLobby(t, newTestLobbyServer(t, lm), lobby, 1, ORIGIN_TYPE_OWNER)
+
+	diff := &DifficultyConfirmedForMinigameMessageDTO{MinigameID: 1, DifficultyID: 1}
+	if !lobby.activityTracker.SetDiffConfirmed(diff) || !lobby.activityTracker.LockIn(0) {
+		t.Fatal("Expected lock in to succeed")
+	}
+	// What a failed request to an unreachable main backend looks like
+	dialErr := &url.Error{
+		Op:  "Get",
+		URL: "http://main-backend.internal.example:5386/api/v1/minigame/minimized?minigameID=1&difficultyID=1&" + strings.Repeat("padding=x&", 20),
+		Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")},
+	}
+	failed := &minigamePrefetch{diff: diff, done: make(chan struct{}), err: fmt.Errorf("error fetching minigame settings: %w", dialErr)}
+	close(failed.done)
+	if len(failed.err.Error()) <= MAX_REASON_SIZE {
+		t.Fatalf("Expected an error longer than %d bytes, got %d", MAX_REASON_SIZE, len(failed.err.Error()))
+	}
+	lobby.activityTracker.prefetch.Store(failed)
+	lobby.onPrefetchFailed(failed)
+
+	abort, err := Deserialize(GENERIC_MINIGAME_UNTIMELY_ABORT, owner.awaitEvent(GENERIC_MINIGAME_UNTIMELY_ABORT.ID), true)
+	if err != nil {
+		t.Fatalf("Error deserializing abort: %v", err)
+	}
+	if abort.SourceID != SERVER_ID || !strings.HasPrefix(failed.err.Error(), abort.Reason) {
+		t.Errorf("Expected the server to send the error cut short, got %d: %q", abort.SourceID, abort.Reason)
+	}
+	if lobby.activityTracker.lockedIn.Load() {
+		t.Error("Expected the lock to be released")
+	}
+}
+
+func TestUntimelyAbortWithLongReasonIsBroadcast(t *testing.T) {
+	lm := newTestLobbyManager(t, nil)
+	lobby := newTestLobby(t, lm, 1, LobbySettings{})
+	owner := joinTestLobby(t, newTestLobbyServer(t, lm), lobby, 1, ORIGIN_TYPE_OWNER)
+
+	// As long as a failed dial to the main backend, ending in a multi-byte character split by the cut
+	reason := strings.Repeat("x", MAX_REASON_SIZE-1) + "é" + strings.Repeat("x", 40)
+	if err := OnUntimelyMinigameAbort(reason, SERVER_ID, lobby, nil); err != nil {
+		t.Fatalf("Expected the abort to be broadcast, got %v", err)
+	}
+	abort, err := Deserialize(GENERIC_MINIGAME_UNTIMELY_ABORT, owner.awaitEvent(GENERIC_MINIGAME_UNTIMELY_ABORT.ID), true)
+	if err != nil {
+		t.Fatalf("Error deserializing abort: %v", err)
+	}
+	if abort.Reason != reason[:MAX_REASON_SIZE-1] {
+		t.Errorf("Expected the reason cut short before the split character, got %d bytes", len(abort.Reason))
+	}
+}
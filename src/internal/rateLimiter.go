@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lilybw/bsc-multiplayer-backend/src/meta"
+	"github.com/lilybw/bsc-multiplayer-backend/src/util"
+)
+
+// Threadsafe counters describing how often a client has hit its rate limits
+type ClientRateLimitMetrics struct {
+	// Messages dropped for exceeding either limit
+	ThrottledMessages atomic.Uint64
+}
+
+// Per client token buckets. One across all events and one for each event with a RateLimit of its own.
+//
+// Threadsafe
+type ClientRateLimiter struct {
+	global        *util.TokenBucket // nil if disabled
+	perEvent      util.ConcurrentTypedMap[MessageID, *util.TokenBucket]
+	offenceWindow time.Duration
+	// Protects windowStart and offences
+	windowLock       sync.Mutex
+	windowStart      time.Time
+	offences         uint32
+	offenceThreshold uint32
+	Metrics          *ClientRateLimitMetrics
+}
+
+func NewClientRateLimiter(configuration *meta.RuntimeConfiguration) *ClientRateLimiter {
+	limiter := &ClientRateLimiter{
+		perEvent:         util.ConcurrentTypedMap[MessageID, *util.TokenBucket]{},
+		offenceWindow:    configuration.RateLimitOffenceWindow,
+		windowStart:      time.Now(),
+		offenceThreshold: uint32(max(configuration.RateLimitOffenceThreshold, 0)),
+		Metrics:          &ClientRateLimitMetrics{},
+	}
+	if configuration.ClientRateLimit > 0 {
+		limiter.global = util.NewTokenBucket(configuration.ClientRateLimit, configuration.ClientRateBurst)
+	}
+	return limiter
+}
+
+// Takes a token from the global bucket and the bucket of the event, if it has any.
+// The global bucket is only drawn from if the event limit allows the message
+func (rl *ClientRateLimiter) allow(spec *EventSpecification[any]) bool {
+	if spec.RateLimit != nil {
+		bucket, exists := rl.perEvent.Load(spec.ID)
+		if !exists {
+			bucket = util.NewTokenBucket(spec.RateLimit.PerSecond, spec.RateLimit.Burst)
+			rl.perEvent.Store(spec.ID, bucket)
+		}
+		if !bucket.Allow() {
+			return false
+		}
+	}
+	return rl.global == nil || rl.global.Allow()
+}
+
+// Counts a throttled message towards the current offence window.
+//
+// Returns true if the offence threshold has been exceeded
+func (rl *ClientRateLimiter) recordOffence() bool {
+	rl.Metrics.ThrottledMessages.Add(1)
+
+	rl.windowLock.Lock()
+	defer rl.windowLock.Unlock()
+	rl.rollWindowLocked(time.Now())
+	rl.offences++
+	return rl.offenceThreshold > 0 && rl.offences > rl.offenceThreshold
+}
+
+// Throttled messages within the current offence window
+func (rl *ClientRateLimiter) RecentOffences() uint32 {
+	rl.windowLock.Lock()
+	defer rl.windowLock.Unlock()
+	rl.rollWindowLocked(time.Now())
+	return rl.offences
+}
+
+// Starts a new offence window if the current one has passed
+func (rl *ClientRateLimiter) rollWindowLocked(now time.Time) {
+	if now.Sub(rl.windowStart) > rl.offenceWindow {
+		rl.windowStart = now
+		rl.offences = 0
+	}
+}
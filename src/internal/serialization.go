@@ -12,6 +12,15 @@ import (
 // Serializes the provided data according to the specification
 // Includes the event id part of the header prefixed (i.e. only needs the sender id to be appended before sending)
 func Serialize[T any](spec *EventSpecification[T], data T) ([]byte, error) {
+	if codec, ok := any(data).(binaryAppender); ok && spec.hasGeneratedCodec {
+		message := make([]byte, 0, uint32(len(spec.IDBytes))+spec.ExpectedMinSize)
+		return codec.AppendBinary(append(message, spec.IDBytes...))
+	}
+	return serializeWithReflection(spec, data)
+}
+
+// Serialize, but without any generated codec
+func serializeWithReflection[T any](spec *EventSpecification[T], data T) ([]byte, error) {
 	// Calculate the exact size needed
 	messageSize, err := ComputeMessageSize(spec, data)
 	if err != nil {
@@ -41,10 +50,17 @@ func Serialize[T any](spec *EventSpecification[T], data T) ([]byte, error) {
 
 	// Serialize fields according to spec
 	for _, element := range spec.Structure {
-		field, _ := util.FindFieldByJSONTagValue(v, element.FieldName)
+		field, _ := util.FindFieldByJSONTagPath(v, element.FieldName)
 		// We don't need to check found because we already validated in ComputeMessageSize
 
-		message, err = appendValue(message, buffer, field)
+		switch {
+		case element.Kind == reflect.Slice:
+			message, err = appendSlice(message, buffer, field, element)
+		case element.Kind == reflect.String && element.LengthPrefixSize > 0:
+			message, err = appendValue(appendStringLength(message, element, len(field.String())), buffer, field)
+		default:
+			message, err = appendValue(message, buffer, field)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("field '%s': %s", element.FieldName, err.Error())
 		}
@@ -68,7 +84,7 @@ func ComputeMessageSize[T any](spec *EventSpecification[T], data T) (uint32, err
 	// Go through spec structure to calculate size
 	for i, element := range spec.Structure {
 		// Find field by JSON tag name
-		field, found := util.FindFieldByJSONTagValue(v, element.FieldName)
+		field, found := util.FindFieldByJSONTagPath(v, element.FieldName)
 		if !found {
 			return 0, fmt.Errorf("field with JSON tag '%s' not found in struct", element.FieldName)
 		}
@@ -79,17 +95,29 @@ func ComputeMessageSize[T any](spec *EventSpecification[T], data T) (uint32, err
 		}
 
 		if element.ByteSize == 0 {
-			// Variable size field (currently only strings)
-			if element.Kind != reflect.String {
-				return 0, fmt.Errorf("unsupported variable size field type: %s", element.Kind)
-			}
-			// Variable size fields must be at the end
-			if i != len(spec.Structure)-1 {
+			// Variable size fields without a length prefix must be at the end
+			if element.LengthPrefixSize == 0 && i != len(spec.Structure)-1 {
 				return 0, fmt.Errorf("variable size field '%s' must be the last field",
 					element.FieldName)
 			}
-			// Add string length
-			size += uint32(len(field.String()))
+			switch element.Kind {
+			case reflect.String:
+				// Also for unprefixed strings, as whatever is sent to a client should be within what it's told to expect
+				if uint32(len(field.String())) > element.MaxByteSize-element.LengthPrefixSize {
+					return 0, fmt.Errorf("field '%s': %d bytes exceeds max size %d",
+						element.FieldName, len(field.String()), element.MaxByteSize-element.LengthPrefixSize)
+				}
+				// Add string length
+				size += element.LengthPrefixSize + uint32(len(field.String()))
+			case reflect.Slice:
+				if uint32(field.Len()) > element.MaxLength {
+					return 0, fmt.Errorf("field '%s': %d elements exceeds max length %d",
+						element.FieldName, field.Len(), element.MaxLength)
+				}
+				size += SLICE_LENGTH_PREFIX_SIZE + uint32(field.Len())*element.ElemByteSize
+			default:
+				return 0, fmt.Errorf("unsupported variable size field type: %s", element.Kind)
+			}
 		} else {
 			// Fixed size field
 			size += element.ByteSize
@@ -99,8 +127,43 @@ func ComputeMessageSize[T any](spec *EventSpecification[T], data T) (uint32, err
 	return size, nil
 }
 
+// Appends the byte count of a string in WIRE_FORMAT_V2, as a uint16 or a uint32 depending on its length prefix size
+func appendStringLength(message []byte, element MessageElementDescriptor, length int) []byte {
+	if element.LengthPrefixSize == LONG_STRING_LENGTH_PREFIX_SIZE {
+		return binary.BigEndian.AppendUint32(message, uint32(length))
+	}
+	return binary.BigEndian.AppendUint16(message, uint16(length))
+}
+
+// Appends the length prefix followed by each element. Fields of struct elements are appended in the order of the elements structure
+func appendSlice(message []byte, buffer []byte, value reflect.Value, element MessageElementDescriptor) ([]byte, error) {
+	binary.BigEndian.PutUint32(buffer, uint32(value.Len()))
+	message = append(message, buffer[:4]...)
+
+	var err error
+	for i := 0; i < value.Len(); i++ {
+		elem := value.Index(i)
+		if element.ElemKind != reflect.Struct {
+			if message, err = appendValue(message, buffer, elem); err != nil {
+				return nil, fmt.Errorf("element %d: %s", i, err.Error())
+			}
+			continue
+		}
+		for _, field := range element.ElemStructure {
+			fieldValue, _ := util.FindFieldByJSONTagPath(elem, field.FieldName)
+			if message, err = appendValue(message, buffer, fieldValue); err != nil {
+				return nil, fmt.Errorf("element %d, field '%s': %s", i, field.FieldName, err.Error())
+			}
+		}
+	}
+	return message, nil
+}
+
 func appendValue(message []byte, buffer []byte, value reflect.Value) ([]byte, error) {
 	switch value.Kind() {
+	case reflect.Bool:
+		return append(message, util.Ternary[uint8](value.Bool(), 1, 0)), nil
+
 	case reflect.Uint8:
 		return append(message, uint8(value.Uint())), nil
 
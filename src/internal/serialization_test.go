@@ -47,6 +47,7 @@ func createTestSpec[T any](id uint32, structure []ShortElementDescriptor) *Event
 		ID:              id,
 		IDBytes:         util.BytesOfUint32(id),
 		ExpectedMinSize: minSize,
+		ExpectedMaxSize: ComputeMaxSize(computed),
 		Structure:       computed,
 	}
 }
@@ -629,3 +630,229 @@ func TestSerializePlayerLeft(t *testing.T) {
 		t.Errorf("expected name 'test', got %q", string(nameBytes))
 	}
 }
+
+type testPosition struct {
+	ID uint32  `json:"id" comment:"Player ID"`
+	X  float32 `json:"x" comment:"X Position"`
+	Y  float32 `json:"y" comment:"Y Position"`
+}
+
+type testPositionsMessage struct {
+	Round     uint8          `json:"round" comment:"Round"`
+	Positions []testPosition `json:"positions" comment:"Player positions" maxLength:"4"`
+}
+
+type testFlagsMessage struct {
+	Flags []bool `json:"flags" comment:"Flags"`
+}
+
+func TestSerializeSliceOfStructs(t *testing.T) {
+	spec := NewSpecification[testPositionsMessage](1_000_000_001, "TestPositions", "Test", SERVER_ONLY, nil)
+	data := testPositionsMessage{
+		Round:     2,
+		Positions: []testPosition{{ID: 1, X: 0.25, Y: 0.5}, {ID: 2, X: 0.75, Y: 1}},
+	}
+
+	msg, err := Serialize(spec, data)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if len(msg) != 4+1+4+2*12 {
+		t.Fatalf("expected %d bytes, got %d", 4+1+4+2*12, len(msg))
+	}
+	if length := binary.BigEndian.Uint32(msg[5:9]); length != 2 {
+		t.Errorf("expected length prefix 2, got %d", length)
+	}
+	if id := binary.BigEndian.Uint32(msg[21:25]); id != 2 {
+		t.Errorf("expected id of second position to be 2, got %d", id)
+	}
+
+	deserialized, err := Deserialize(spec, msg[4:], true)
+	if err != nil {
+		t.Fatalf("failed to deserialize: %v", err)
+	}
+	if !reflect.DeepEqual(*deserialized, data) {
+		t.Errorf("data changed during round trip:\noriginal:     %+v\ndeserialized: %+v", data, *deserialized)
+	}
+
+	data.Positions = make([]testPosition, 5)
+	if _, err := Serialize(spec, data); err == nil {
+		t.Error("expected an error for a slice over its max length")
+	}
+}
+
+func TestSerializeSliceOfScalars(t *testing.T) {
+	spec := NewSpecification[testFlagsMessage](1_000_000_002, "TestFlags", "Test", SERVER_ONLY, nil)
+	data := testFlagsMessage{Flags: []bool{true, false, true}}
+
+	msg, err := Serialize(spec, data)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if !reflect.DeepEqual(msg[4:], []byte{0, 0, 0, 3, 1, 0, 1}) {
+		t.Errorf("unexpected serialized flags: %v", msg[4:])
+	}
+
+	deserialized, err := Deserialize(spec, msg[4:], true)
+	if err != nil {
+		t.Fatalf("failed to deserialize: %v", err)
+	}
+	if !reflect.DeepEqual(*deserialized, data) {
+		t.Errorf("data changed during round trip:\noriginal:     %+v\ndeserialized: %+v", data, *deserialized)
+	}
+}
+
+type testLabelledShotMessage struct {
+	PlayerID uint32   `json:"id" comment:"Player ID"`
+	CharCode string   `json:"code" comment:"Code shot at" maxSize:"8"`
+	Label    string   `json:"label" comment:"Label"`
+	Hits     []uint32 `json:"hits" comment:"Asteroids hit" maxLength:"4"`
+	Round    uint8    `json:"round" comment:"Round"`
+}
+
+func TestSerializeV2(t *testing.T) {
+	spec := NewV2Specification[testLabelledShotMessage](1_000_000_004, "TestLabelledShot", "Test", SERVER_ONLY, nil)
+	data := testLabelledShotMessage{PlayerID: 1, CharCode: "ab", Label: "héllo", Hits: []uint32{7}, Round: 3}
+
+	msg, err := Serialize(spec, data)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	expected := []byte{
+		0, 0, 0, 1, // PlayerID
+		0, 2, 'a', 'b', // CharCode
+		0, 6, 'h', 0xc3, 0xa9, 'l', 'l', 'o', // Label
+		0, 0, 0, 1, 0, 0, 0, 7, // Hits
+		3, // Round
+	}
+	if !reflect.DeepEqual(msg[4:], expected) {
+		t.Fatalf("unexpected serialized message:\ngot:  %v\nwant: %v", msg[4:], expected)
+	}
+
+	deserialized, err := Deserialize(spec, msg[4:], true)
+	if err != nil {
+		t.Fatalf("failed to deserialize: %v", err)
+	}
+	if !reflect.DeepEqual(*deserialized, data) {
+		t.Errorf("data changed during round trip:\noriginal:     %+v\ndeserialized: %+v", data, *deserialized)
+	}
+
+	data.CharCode = "123456789"
+	if _, err := Serialize(spec, data); err == nil {
+		t.Error("expected an error for a string over its max size")
+	}
+}
+
+func TestSerializeRejectsTrailingStringOverMaxSize(t *testing.T) {
+	spec := NewSpecification[PlayerJoinedMessageDTO](1_000_000_007, "TestPlayerJoined", "Test", SERVER_ONLY, nil)
+	data := PlayerJoinedMessageDTO{PlayerID: 1, IGN: strings.Repeat("x", MAX_IGN_SIZE)}
+	if _, err := serializeWithReflection(spec, data); err != nil {
+		t.Fatalf("expected an IGN of the max size to be serialized, got %v", err)
+	}
+	data.IGN += "x"
+	if _, err := serializeWithReflection(spec, data); err == nil {
+		t.Error("expected an error for a trailing string over its max size")
+	}
+}
+
+type testLongTextMessage struct {
+	Title string `json:"title" comment:"Title" maxSize:"16"`
+	Text  string `json:"text" comment:"Text" maxSize:"100000"`
+	Round uint8  `json:"round" comment:"Round"`
+}
+
+func TestSerializeV2LongString(t *testing.T) {
+	spec := NewV2Specification[testLongTextMessage](1_000_000_008, "TestLongText", "Test", SERVER_ONLY, nil)
+	data := testLongTextMessage{Title: "hi", Text: "abc", Round: 7}
+
+	msg, err := Serialize(spec, data)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	expected := []byte{
+		0, 2, 'h', 'i', // Title, uint16 byte count
+		0, 0, 0, 3, 'a', 'b', 'c', // Text, uint32 byte count
+		7, // Round
+	}
+	if !reflect.DeepEqual(msg[4:], expected) {
+		t.Fatalf("unexpected serialized message:\ngot:  %v\nwant: %v", msg[4:], expected)
+	}
+	deserialized, err := Deserialize(spec, msg[4:], true)
+	if err != nil {
+		t.Fatalf("failed to deserialize: %v", err)
+	}
+	if !reflect.DeepEqual(*deserialized, data) {
+		t.Errorf("data changed during round trip:\noriginal:     %+v\ndeserialized: %+v", data, *deserialized)
+	}
+
+	// Past what a uint16 could count
+	data.Text = strings.Repeat("x", 70_000)
+	msg, err = Serialize(spec, data)
+	if err != nil {
+		t.Fatalf("failed to serialize a string of %d bytes: %v", len(data.Text), err)
+	}
+	if deserialized, err = Deserialize(spec, msg[4:], true); err != nil || deserialized.Text != data.Text {
+		t.Errorf("expected a string of %d bytes to survive the round trip, got error %v", len(data.Text), err)
+	}
+
+	tooLong := append([]byte{0, 0, 0, 1, 0x86, 0xa1}, make([]byte, 100_001+1)...)
+	if _, err := Deserialize(spec, tooLong, true); err == nil {
+		t.Error("expected an error for a uint32 byte count over the max size")
+	}
+}
+
+func TestSerializeWithGeneratedCodecRejectsTrailingStringOverMaxSize(t *testing.T) {
+	if !PLAYER_JOINED_EVENT.hasGeneratedCodec {
+		t.Fatal("expected PlayerJoined to have a generated codec")
+	}
+	data := PlayerJoinedMessageDTO{PlayerID: 1, IGN: strings.Repeat("x", MAX_IGN_SIZE)}
+	if _, err := Serialize(PLAYER_JOINED_EVENT, data); err != nil {
+		t.Fatalf("expected an IGN of the max size to be serialized, got %v", err)
+	}
+	data.IGN += "x"
+	if _, err := Serialize(PLAYER_JOINED_EVENT, data); err == nil {
+		t.Error("expected an error for a trailing string over its max size")
+	}
+}
+
+type testPoint2D struct {
+	X float32 `json:"x" comment:"X"`
+	Y float32 `json:"y" comment:"Y"`
+}
+
+type testPlayerPlacement struct {
+	ID       uint32      `json:"id" comment:"Player ID"`
+	Position testPoint2D `json:"position" comment:"Position"`
+}
+
+type testPlacementsMessage struct {
+	Origin  testPoint2D           `json:"origin" comment:"Origin"`
+	Players []testPlayerPlacement `json:"players" comment:"Players" maxLength:"4"`
+}
+
+func TestSerializeNestedStructs(t *testing.T) {
+	spec := NewSpecification[testPlacementsMessage](1_000_000_006, "TestPlacements", "Test", SERVER_ONLY, nil)
+	data := testPlacementsMessage{
+		Origin:  testPoint2D{X: 0.5, Y: 0.25},
+		Players: []testPlayerPlacement{{ID: 1, Position: testPoint2D{X: 0.1, Y: 0.2}}, {ID: 2, Position: testPoint2D{X: 0.3, Y: 0.4}}},
+	}
+
+	msg, err := Serialize(spec, data)
+	if err != nil {
+		t.Fatalf("failed to serialize: %v", err)
+	}
+	if len(msg) != 4+8+4+2*12 {
+		t.Fatalf("expected %d bytes, got %d", 4+8+4+2*12, len(msg))
+	}
+	if y := math.Float32frombits(binary.BigEndian.Uint32(msg[8:12])); y != 0.25 {
+		t.Errorf("expected origin.y 0.25, got %f", y)
+	}
+
+	deserialized, err := Deserialize(spec, msg[4:], true)
+	if err != nil {
+		t.Fatalf("failed to deserialize: %v", err)
+	}
+	if !reflect.DeepEqual(*deserialized, data) {
+		t.Errorf("data changed during round trip:\noriginal:     %+v\ndeserialized: %+v", data, *deserialized)
+	}
+}
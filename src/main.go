@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"strconv"
 	"syscall"
 
+	"github.com/lilybw/bsc-multiplayer-backend/src/auth"
 	"github.com/lilybw/bsc-multiplayer-backend/src/config"
 	"github.com/lilybw/bsc-multiplayer-backend/src/integrations"
 	"github.com/lilybw/bsc-multiplayer-backend/src/internal"
@@ -30,27 +32,32 @@ func main() {
 		panic(envErr)
 	}
 	log.Println("[main] Configuration loaded: ", runtimeConfiguration.ToString())
-	port, portErr := config.GetInt("MAIN_BACKEND_PORT")
-	if portErr != nil {
-		panic("Error getting MAIN_BACKEND_PORT" + portErr.Error())
-	}
-	host, hostErr := config.LoudGet("MAIN_BACKEND_HOST")
-	if hostErr != nil {
-		panic("Error getting MAIN_BACKEND_HOST" + hostErr.Error())
-	}
-	// Initializing the singleton
-	_, mbErr := integrations.InitializeMainBackendIntegration(host, port)
+	mainBackend, mbErr := createMainBackend(runtimeConfiguration)
 	if mbErr != nil {
 		panic(mbErr)
 	}
+	outbox, outboxErr := integrations.NewOutbox(mainBackend, integrations.OutboxOptions{
+		Path:         runtimeConfiguration.OutboxFile,
+		RetryBackoff: runtimeConfiguration.OutboxRetryBackoff,
+		MaxBackoff:   runtimeConfiguration.OutboxMaxBackoff,
+		MaxFailed:    runtimeConfiguration.OutboxMaxFailed,
+	})
+	if outboxErr != nil {
+		panic("Error opening outbox, check OUTBOX_FILE: " + outboxErr.Error())
+	}
 	internal.SetServerID(SERVER_ID, SERVER_ID_BYTES)
 
-	lobbyManager := internal.CreateLobbyManager(runtimeConfiguration)
+	ticketIssuer, ticketErr := auth.NewTicketIssuer(runtimeConfiguration.JoinTicketSecret, runtimeConfiguration.TicketIssuerAPIKey, runtimeConfiguration.JoinTicketTTL)
+	if ticketErr != nil {
+		panic("Error configuring join tickets, check JOIN_TICKET_SECRET, TICKET_ISSUER_API_KEY and JOIN_TICKET_TTL_S: " + ticketErr.Error())
+	}
+
+	lobbyManager := internal.CreateLobbyManager(runtimeConfiguration, mainBackend, outbox)
 
 	// Create a new ServeMux
 	mux := http.NewServeMux()
 
-	applyPublicApi(mux, lobbyManager)
+	applyPublicApi(mux, lobbyManager, ticketIssuer)
 	if runtimeConfiguration.Mode == meta.RUNTIME_MODE_DEV {
 		applyDevAPI(mux, lobbyManager)
 	}
@@ -98,3 +105,42 @@ func awaitSysShutdown() {
 	sig := <-sigs
 	log.Printf("[server] Received shutdown signal: %v", sig)
 }
+
+func createMainBackend(runtimeConfiguration *meta.RuntimeConfiguration) (integrations.MainBackend, error) {
+	mainBackend, err := createUncachedMainBackend(runtimeConfiguration)
+	if err != nil || runtimeConfiguration.MinigameSettingsCacheTTL <= 0 {
+		return mainBackend, err
+	}
+	return integrations.NewCachingMainBackend(mainBackend, integrations.MinigameSettingsCacheOptions{
+		TTL:          runtimeConfiguration.MinigameSettingsCacheTTL,
+		StaleIfError: runtimeConfiguration.MinigameSettingsStaleIfError,
+	}), nil
+}
+
+func createUncachedMainBackend(runtimeConfiguration *meta.RuntimeConfiguration) (integrations.MainBackend, error) {
+	if runtimeConfiguration.MainBackendMode == meta.MAIN_BACKEND_MODE_FAKE {
+		return integrations.NewFakeMainBackend(runtimeConfiguration.MainBackendFakeSettingsFile)
+	}
+
+	port, portErr := config.GetInt("MAIN_BACKEND_PORT")
+	if portErr != nil {
+		return nil, fmt.Errorf("error getting MAIN_BACKEND_PORT: %s", portErr.Error())
+	}
+	host, hostErr := config.LoudGet("MAIN_BACKEND_HOST")
+	if hostErr != nil {
+		return nil, fmt.Errorf("error getting MAIN_BACKEND_HOST: %s", hostErr.Error())
+	}
+	return integrations.NewMainBackendIntegration(host, port, integrations.MainBackendOptions{
+		Timeout:            runtimeConfiguration.MainBackendTimeout,
+		DialTimeout:        runtimeConfiguration.MainBackendDialTimeout,
+		MaxAttempts:        runtimeConfiguration.MainBackendMaxAttempts,
+		RetryBackoff:       runtimeConfiguration.MainBackendRetryBackoff,
+		BreakerThreshold:   runtimeConfiguration.MainBackendBreakerThreshold,
+		BreakerCooldown:    runtimeConfiguration.MainBackendBreakerCooldown,
+		Scheme:             runtimeConfiguration.MainBackendScheme,
+		CAFile:             runtimeConfiguration.MainBackendCAFile,
+		ClientCertFile:     runtimeConfiguration.MainBackendClientCertFile,
+		ClientKeyFile:      runtimeConfiguration.MainBackendClientKeyFile,
+		InsecureSkipVerify: runtimeConfiguration.MainBackendInsecureSkipVerify,
+	})
+}
@@ -1,5 +1,10 @@
 package meta
 
+import (
+	"fmt"
+	"time"
+)
+
 type RuntimeMode string
 
 const (
@@ -16,18 +21,188 @@ const (
 	MESSAGE_ENCODING_BINARY MessageEncoding = "binary"
 )
 
+// What to do when the owner of a lobby loses their connection
+type OwnerDisconnectPolicy string
+
+const (
+	// Close the lobby as soon as the owner is disconnected
+	OWNER_DISCONNECT_POLICY_CLOSE OwnerDisconnectPolicy = "close"
+	// Keep the lobby open for a while, in case the owner resumes their session
+	OWNER_DISCONNECT_POLICY_WAIT OwnerDisconnectPolicy = "wait"
+	// Make the longest connected player the new owner
+	OWNER_DISCONNECT_POLICY_PROMOTE OwnerDisconnectPolicy = "promote"
+)
+
+type MainBackendMode string
+
+const (
+	// Calls are made to the main backend over HTTP
+	MAIN_BACKEND_MODE_REMOTE MainBackendMode = "remote"
+	// Calls are served by an in memory fake, for tests and offline development
+	MAIN_BACKEND_MODE_FAKE MainBackendMode = "fake"
+)
+
+// What to do when a clients send queue is full
+type SlowConsumerPolicy string
+
+const (
+	// Drop the message that didn't fit in the queue
+	SLOW_CONSUMER_POLICY_DROP SlowConsumerPolicy = "drop"
+	// Close the connection. The client may resume its session as after any other dropped connection
+	SLOW_CONSUMER_POLICY_DISCONNECT SlowConsumerPolicy = "disconnect"
+)
+
+// What to do when a connecting client sends a protocol version other than the one of the event specifications
+type ProtocolVersionPolicy string
+
+const (
+	// Accept the client without checking
+	PROTOCOL_VERSION_POLICY_IGNORE ProtocolVersionPolicy = "ignore"
+	// Accept the client, but log a warning
+	PROTOCOL_VERSION_POLICY_WARN ProtocolVersionPolicy = "warn"
+	// Refuse the connection, also if the client sent no protocol version at all
+	PROTOCOL_VERSION_POLICY_REJECT ProtocolVersionPolicy = "reject"
+)
+
 type RuntimeConfiguration struct {
 	Mode     RuntimeMode
 	Encoding MessageEncoding
+	// How long a client that lost its connection is kept in the lobby, awaiting a resume
+	ReconnectGracePeriod time.Duration
+	// Max amount of broadcasts retained for a disconnected client, oldest are dropped first
+	ResumeBufferSize int
+	// Max amount of messages waiting to be written to any one client
+	SendQueueSize int
+	// Deadline for writing any single message to a client
+	WriteTimeout       time.Duration
+	SlowConsumerPolicy SlowConsumerPolicy
+	// Default interval at which the server pings each client. 0 disables heartbeats. Can be overwritten per lobby
+	PingInterval time.Duration
+	// Default time allowed for a pong after a ping, before the client is considered dead. Can be overwritten per lobby
+	PongTimeout time.Duration
+	// Shared secret used to sign join tickets. Never printed
+	JoinTicketSecret string
+	// Required by the endpoints minting join tickets. Held by the main backend, never printed
+	TicketIssuerAPIKey string
+	// How long a minted join ticket remains valid
+	JoinTicketTTL time.Duration
+	// Amount of messages with a spoofed sender id tolerated before the client is disconnected. 0 disables disconnecting
+	SpoofDisconnectThreshold int
+	// Messages per second any one client may send across all events. 0 disables the limit
+	ClientRateLimit float64
+	ClientRateBurst int
+	// Amount of throttled messages within the offence window tolerated before the client is disconnected. 0 disables disconnecting
+	RateLimitOffenceThreshold int
+	RateLimitOffenceWindow    time.Duration
+	// Max size in bytes of any single websocket message read from a client. 0 derives it from the largest event specification
+	WSReadLimit int64
+	// What to do when the owner is disconnected, after any reconnect grace period
+	OwnerDisconnectPolicy OwnerDisconnectPolicy
+	// How long the lobby is kept open for the owner to resume their session, under the wait policy
+	OwnerReconnectWait time.Duration
+	// Endpoints recieving lifecycle webhooks. None if empty
+	WebhookURLs []string
+	// Shared secret used to sign webhook payloads. Never printed
+	WebhookSecret string
+	// Including the first attempt
+	WebhookMaxAttempts int
+	// Wait before the first retry of a failed delivery, doubled for each retry after, up to the max
+	WebhookInitialBackoff time.Duration
+	WebhookMaxBackoff     time.Duration
+	// Of each attempt at a call to the main backend
+	MainBackendTimeout     time.Duration
+	MainBackendDialTimeout time.Duration
+	// Including the first attempt. Only idempotent calls are retried
+	MainBackendMaxAttempts int
+	// Wait before the first retry of a failed call, doubled for each retry after
+	MainBackendRetryBackoff time.Duration
+	// Consecutive failed calls to the main backend before any more fail fast. 0 disables the circuit breaker
+	MainBackendBreakerThreshold int
+	MainBackendBreakerCooldown  time.Duration
+	// "https" or "http"
+	MainBackendScheme string
+	// PEM bundle of CAs trusted besides the system roots when verifying the main backend. Optional
+	MainBackendCAFile string
+	// PEM certificate and key presented to the main backend for mutual TLS. Optional, but both or neither
+	MainBackendClientCertFile string
+	MainBackendClientKeyFile  string
+	// Dev only. Skips verifying the certificate of the main backend
+	MainBackendInsecureSkipVerify bool
+	// Dev only if fake
+	MainBackendMode MainBackendMode
+	// JSON file the fake main backend serves minigame settings from. Optional
+	MainBackendFakeSettingsFile string
+	// Time minigame settings are cached before they are revalidated with the main backend. 0 disables the cache
+	MinigameSettingsCacheTTL time.Duration
+	// Time past the TTL cached settings are still used if the main backend is unavailable
+	MinigameSettingsStaleIfError time.Duration
+	// File calls to the main backend that must not be lost are kept in until they succeed
+	OutboxFile string
+	// Wait before retrying an outbox item, doubled for each retry after, up to the max
+	OutboxRetryBackoff time.Duration
+	OutboxMaxBackoff   time.Duration
+	// Failed outbox items kept for inspection, past which the oldest are dropped
+	OutboxMaxFailed int
+	// Whether clients generated against other event specifications may connect
+	ProtocolVersionPolicy ProtocolVersionPolicy
 }
 
 func (rc *RuntimeConfiguration) ToString() string {
-	return "mode: " + string(rc.Mode) + " encoding: " + string(rc.Encoding)
+	return "mode: " + string(rc.Mode) + " encoding: " + string(rc.Encoding) +
+		fmt.Sprintf(" reconnect grace period: %s resume buffer size: %d", rc.ReconnectGracePeriod, rc.ResumeBufferSize) +
+		fmt.Sprintf(" send queue size: %d write timeout: %s slow consumer policy: %s", rc.SendQueueSize, rc.WriteTimeout, rc.SlowConsumerPolicy) +
+		fmt.Sprintf(" ping interval: %s pong timeout: %s join ticket ttl: %s", rc.PingInterval, rc.PongTimeout, rc.JoinTicketTTL) +
+		fmt.Sprintf(" spoof disconnect threshold: %d", rc.SpoofDisconnectThreshold) +
+		fmt.Sprintf(" client rate limit: %.1f/s burst %d offence threshold: %d per %s", rc.ClientRateLimit, rc.ClientRateBurst, rc.RateLimitOffenceThreshold, rc.RateLimitOffenceWindow) +
+		fmt.Sprintf(" ws read limit: %d", rc.WSReadLimit) +
+		fmt.Sprintf(" owner disconnect policy: %s owner reconnect wait: %s", rc.OwnerDisconnectPolicy, rc.OwnerReconnectWait) +
+		fmt.Sprintf(" webhook urls: %d max attempts: %d backoff: %s to %s", len(rc.WebhookURLs), rc.WebhookMaxAttempts, rc.WebhookInitialBackoff, rc.WebhookMaxBackoff) +
+		fmt.Sprintf(" main backend timeout: %s dial timeout: %s max attempts: %d retry backoff: %s", rc.MainBackendTimeout, rc.MainBackendDialTimeout, rc.MainBackendMaxAttempts, rc.MainBackendRetryBackoff) +
+		fmt.Sprintf(" main backend breaker threshold: %d cooldown: %s", rc.MainBackendBreakerThreshold, rc.MainBackendBreakerCooldown) +
+		fmt.Sprintf(" main backend scheme: %s ca file: \"%s\" client cert: \"%s\" insecure skip verify: %t", rc.MainBackendScheme, rc.MainBackendCAFile, rc.MainBackendClientCertFile, rc.MainBackendInsecureSkipVerify) +
+		fmt.Sprintf(" main backend mode: %s fake settings file: \"%s\"", rc.MainBackendMode, rc.MainBackendFakeSettingsFile) +
+		fmt.Sprintf(" minigame settings cache ttl: %s stale if error: %s", rc.MinigameSettingsCacheTTL, rc.MinigameSettingsStaleIfError) +
+		fmt.Sprintf(" outbox file: \"%s\" backoff: %s to %s max failed: %d", rc.OutboxFile, rc.OutboxRetryBackoff, rc.OutboxMaxBackoff, rc.OutboxMaxFailed) +
+		fmt.Sprintf(" protocol version policy: %s", rc.ProtocolVersionPolicy)
 }
 
 func NewRuntimeConfiguration(mode RuntimeMode, encoding MessageEncoding) *RuntimeConfiguration {
 	return &RuntimeConfiguration{
-		Mode:     mode,
-		Encoding: encoding,
+		Mode:                         mode,
+		Encoding:                     encoding,
+		ReconnectGracePeriod:         30 * time.Second,
+		ResumeBufferSize:             512,
+		SendQueueSize:                256,
+		WriteTimeout:                 5 * time.Second,
+		SlowConsumerPolicy:           SLOW_CONSUMER_POLICY_DROP,
+		PingInterval:                 15 * time.Second,
+		PongTimeout:                  10 * time.Second,
+		JoinTicketTTL:                60 * time.Second,
+		SpoofDisconnectThreshold:     3,
+		ClientRateLimit:              60,
+		ClientRateBurst:              120,
+		RateLimitOffenceThreshold:    50,
+		RateLimitOffenceWindow:       10 * time.Second,
+		OwnerDisconnectPolicy:        OWNER_DISCONNECT_POLICY_CLOSE,
+		OwnerReconnectWait:           60 * time.Second,
+		WebhookURLs:                  []string{},
+		WebhookMaxAttempts:           5,
+		WebhookInitialBackoff:        time.Second,
+		WebhookMaxBackoff:            60 * time.Second,
+		MainBackendTimeout:           10 * time.Second,
+		MainBackendDialTimeout:       5 * time.Second,
+		MainBackendMaxAttempts:       3,
+		MainBackendRetryBackoff:      200 * time.Millisecond,
+		MainBackendBreakerThreshold:  5,
+		MainBackendBreakerCooldown:   30 * time.Second,
+		MainBackendScheme:            "https",
+		MainBackendMode:              MAIN_BACKEND_MODE_REMOTE,
+		MinigameSettingsCacheTTL:     5 * time.Minute,
+		MinigameSettingsStaleIfError: time.Hour,
+		OutboxFile:                   "outbox.json",
+		OutboxRetryBackoff:           time.Second,
+		OutboxMaxBackoff:             5 * time.Minute,
+		OutboxMaxFailed:              100,
+		ProtocolVersionPolicy:        PROTOCOL_VERSION_POLICY_WARN,
 	}
 }
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -22,6 +23,21 @@ func FindFieldByJSONTagValue(v reflect.Value, jsonName string) (reflect.Value, b
 	return v, false
 }
 
+// As FindFieldByJSONTagValue, but follows a dotted path of JSON tag values into nested structs, fx. "position.x"
+func FindFieldByJSONTagPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, jsonName := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return v, false
+		}
+		field, found := FindFieldByJSONTagValue(v, jsonName)
+		if !found {
+			return v, false
+		}
+		v = field
+	}
+	return v, true
+}
+
 // Include comments during runtime by adding them as tags in the struct
 // Example:
 //
@@ -37,6 +53,46 @@ func GetCommentValue(field reflect.StructField) (string, error) {
 	return tag, nil
 }
 
+// Optional upper bound in bytes on the serialized size of a variable size field
+// Example:
+//
+//	type MyStruct struct {
+//		Field1 string `json:"field1" comment:"This is a comment" maxSize:"64"`
+//	}
+//
+// Returns false if no maxSize tag is present
+func GetMaxSizeValue(field reflect.StructField) (uint32, bool, error) {
+	tag, present := field.Tag.Lookup("maxSize")
+	if !present {
+		return 0, false, nil
+	}
+	maxSize, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || maxSize == 0 {
+		return 0, true, fmt.Errorf("field %s has an invalid maxSize tag \"%s\", expected a positive integer", field.Name, tag)
+	}
+	return uint32(maxSize), true, nil
+}
+
+// Optional upper bound on the amount of elements of a slice field
+// Example:
+//
+//	type MyStruct struct {
+//		Field1 []uint32 `json:"field1" comment:"This is a comment" maxLength:"16"`
+//	}
+//
+// Returns false if no maxLength tag is present
+func GetMaxLengthValue(field reflect.StructField) (uint32, bool, error) {
+	tag, present := field.Tag.Lookup("maxLength")
+	if !present {
+		return 0, false, nil
+	}
+	maxLength, err := strconv.ParseUint(tag, 10, 32)
+	if err != nil || maxLength == 0 {
+		return 0, true, fmt.Errorf("field %s has an invalid maxLength tag \"%s\", expected a positive integer", field.Name, tag)
+	}
+	return uint32(maxLength), true, nil
+}
+
 // GetFieldNameFromTag returns the general field name from the json tag of a struct field
 func GetFieldNameFromTag(field reflect.StructField) (string, error) {
 	tag := field.Tag.Get("json")
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Classic token bucket. Refills at a constant rate up to its burst size, each allowed event takes a token.
+//
+// Threadsafe
+type TokenBucket struct {
+	ratePerSecond float64
+	burst         float64
+	tokens        float64
+	lastRefill    time.Time
+	mu            sync.Mutex
+}
+
+// Starts out full
+func NewTokenBucket(ratePerSecond float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		ratePerSecond: ratePerSecond,
+		burst:         float64(burst),
+		tokens:        float64(burst),
+		lastRefill:    time.Now(),
+	}
+}
+
+// Takes a token if any is available
+func (tb *TokenBucket) Allow() bool {
+	return tb.AllowAt(time.Now())
+}
+
+// Takes a token if any is available at the given point in time.
+// Points in time before the last refill are treated as no time having passed
+func (tb *TokenBucket) AllowAt(now time.Time) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if elapsed := now.Sub(tb.lastRefill); elapsed > 0 {
+		tb.tokens = min(tb.burst, tb.tokens+elapsed.Seconds()*tb.ratePerSecond)
+		tb.lastRefill = now
+	}
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	bucket := NewTokenBucket(1, 5)
+	now := bucket.lastRefill
+
+	for i := 0; i < 5; i++ {
+		if !bucket.AllowAt(now) {
+			t.Fatalf("Expected token %d of the burst to be allowed", i)
+		}
+	}
+	if bucket.AllowAt(now) {
+		t.Error("Expected bucket to be empty after the burst")
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	bucket := NewTokenBucket(10, 2)
+	now := bucket.lastRefill
+	bucket.AllowAt(now)
+	bucket.AllowAt(now)
+
+	if bucket.AllowAt(now.Add(50 * time.Millisecond)) {
+		t.Error("Expected no token after half a refill period")
+	}
+	if !bucket.AllowAt(now.Add(100 * time.Millisecond)) {
+		t.Error("Expected a token after a full refill period")
+	}
+
+	// Refilling never exceeds the burst size
+	later := now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !bucket.AllowAt(later) {
+			t.Fatalf("Expected token %d after a long pause", i)
+		}
+	}
+	if bucket.AllowAt(later) {
+		t.Error("Expected refill to be capped at the burst size")
+	}
+}
+
+func TestTokenBucketIgnoresPastTimes(t *testing.T) {
+	bucket := NewTokenBucket(10, 1)
+	now := bucket.lastRefill
+	bucket.AllowAt(now)
+
+	if bucket.AllowAt(now.Add(-time.Second)) {
+		t.Error("Expected a point in time before the last refill to not refill the bucket")
+	}
+}